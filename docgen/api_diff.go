@@ -0,0 +1,448 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// APIChangeKind is the kind of change of a declaration between two versions of a program.
+//
+type APIChangeKind string
+
+const (
+	APIChangeKindAdded   APIChangeKind = "added"
+	APIChangeKindRemoved APIChangeKind = "removed"
+	APIChangeKindChanged APIChangeKind = "changed"
+)
+
+// APIChange is a change of a single declaration between two versions of a program.
+//
+// Breaking is true if the change may break the users of the public API of the program.
+// UpdateErrors lists the reasons why the contract update validation rejects the change, if any.
+//
+type APIChange struct {
+	Kind            APIChangeKind `json:"kind"`
+	DeclarationKind string        `json:"declarationKind"`
+	Declaration     string        `json:"declaration"`
+	Details         []string      `json:"details,omitempty"`
+	Breaking        bool          `json:"breaking"`
+	UpdateErrors    []string      `json:"updateErrors,omitempty"`
+}
+
+// APIDiff is the list of changes between two versions of a program.
+//
+// All the changes to public declarations are reported. Changes to non-public declarations
+// are only reported if they are rejected by the contract update validation.
+//
+type APIDiff struct {
+	Changes []*APIChange `json:"changes"`
+}
+
+// HasBreakingChanges returns true if any of the changes breaks the public API.
+//
+func (d *APIDiff) HasBreakingChanges() bool {
+	for _, change := range d.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUpdateErrors returns true if any of the changes is rejected by the contract update validation.
+//
+func (d *APIDiff) HasUpdateErrors() bool {
+	for _, change := range d.Changes {
+		if len(change.UpdateErrors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIDiff parses the old and the new version of a program
+// and returns the changes of the declarations between the two versions.
+//
+func (gen *DocGenerator) GenerateAPIDiff(oldSource string, newSource string) (*APIDiff, error) {
+	oldDocumentation, err := gen.GenerateModel(oldSource)
+	if err != nil {
+		return nil, err
+	}
+
+	newDocumentation, err := gen.GenerateModel(newSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAPIDiff(oldDocumentation, newDocumentation), nil
+}
+
+// NewAPIDiff returns the changes of the declarations between two versions of a program.
+//
+func NewAPIDiff(oldDocumentation *Documentation, newDocumentation *Documentation) *APIDiff {
+	diff := &APIDiff{
+		Changes: []*APIChange{},
+	}
+
+	diff.diffDeclarations(
+		nil,
+		oldDocumentation.Declarations,
+		newDocumentation.Declarations,
+		true,
+	)
+
+	return diff
+}
+
+func (d *APIDiff) diffDeclarations(
+	parentPath []string,
+	oldDecls []*DeclarationDoc,
+	newDecls []*DeclarationDoc,
+	isParentPublic bool,
+) {
+	newDeclsByName := declarationsByName(newDecls)
+	oldDeclsByName := declarationsByName(oldDecls)
+
+	for _, oldDecl := range oldDecls {
+		declPath := append(parentPath[:len(parentPath):len(parentPath)], oldDecl.Name)
+
+		newDecl, ok := newDeclsByName[oldDecl.Name]
+		if !ok {
+			d.addRemoved(declPath, oldDecl, isParentPublic)
+			continue
+		}
+
+		d.addChanged(declPath, oldDecl, newDecl, isParentPublic)
+
+		d.diffDeclarations(
+			declPath,
+			oldDecl.Members,
+			newDecl.Members,
+			isPublicDeclaration(newDecl, isParentPublic),
+		)
+	}
+
+	for _, newDecl := range newDecls {
+		if _, ok := oldDeclsByName[newDecl.Name]; ok {
+			continue
+		}
+
+		declPath := append(parentPath[:len(parentPath):len(parentPath)], newDecl.Name)
+		d.addAdded(declPath, newDecl, len(parentPath) > 0, isParentPublic)
+	}
+}
+
+func (d *APIDiff) addRemoved(declPath []string, oldDecl *DeclarationDoc, isParentPublic bool) {
+	change := &APIChange{
+		Kind:            APIChangeKindRemoved,
+		DeclarationKind: oldDecl.Kind,
+		Declaration:     strings.Join(declPath, "."),
+		Breaking:        isPublicDeclaration(oldDecl, isParentPublic),
+	}
+
+	switch {
+	case isTypeDeclarationKind(oldDecl.Kind):
+		change.UpdateErrors = append(change.UpdateErrors, "type declarations cannot be removed")
+	case oldDecl.Kind == common.DeclarationKindEnumCase.Name():
+		change.UpdateErrors = append(change.UpdateErrors, "enum cases cannot be removed")
+	}
+
+	d.add(change)
+}
+
+func (d *APIDiff) addAdded(declPath []string, newDecl *DeclarationDoc, isNested bool, isParentPublic bool) {
+	change := &APIChange{
+		Kind:            APIChangeKindAdded,
+		DeclarationKind: newDecl.Kind,
+		Declaration:     strings.Join(declPath, "."),
+	}
+
+	if isNested && newDecl.Kind == common.DeclarationKindField.Name() {
+		change.UpdateErrors = append(change.UpdateErrors, "fields cannot be added")
+	}
+
+	if isPublicDeclaration(newDecl, isParentPublic) || len(change.UpdateErrors) > 0 {
+		d.Changes = append(d.Changes, change)
+	}
+}
+
+func (d *APIDiff) addChanged(
+	declPath []string,
+	oldDecl *DeclarationDoc,
+	newDecl *DeclarationDoc,
+	isParentPublic bool,
+) {
+	change := &APIChange{
+		Kind:            APIChangeKindChanged,
+		DeclarationKind: newDecl.Kind,
+		Declaration:     strings.Join(declPath, "."),
+	}
+
+	wasPublic := isPublicDeclaration(oldDecl, isParentPublic)
+	isPublic := isPublicDeclaration(newDecl, isParentPublic)
+
+	addDetail := func(breaking bool, format string, args ...any) {
+		change.Details = append(change.Details, fmt.Sprintf(format, args...))
+		if breaking && wasPublic {
+			change.Breaking = true
+		}
+	}
+
+	if oldDecl.Kind != newDecl.Kind {
+		addDetail(true, "kind changed from %s to %s", oldDecl.Kind, newDecl.Kind)
+		if isTypeDeclarationKind(oldDecl.Kind) {
+			change.UpdateErrors = append(change.UpdateErrors, "the kind of type declarations cannot be changed")
+		}
+	}
+
+	if oldDecl.Access != newDecl.Access {
+		addDetail(
+			wasPublic && !isPublic,
+			"access changed from %s to %s",
+			accessDescription(oldDecl.Access),
+			accessDescription(newDecl.Access),
+		)
+	}
+
+	for _, conformance := range oldDecl.Conformances {
+		if !containsString(newDecl.Conformances, conformance) {
+			addDetail(true, "conformance `%s` removed", conformance)
+			change.UpdateErrors = append(change.UpdateErrors, "conformances cannot be removed")
+		}
+	}
+
+	for _, conformance := range newDecl.Conformances {
+		if !containsString(oldDecl.Conformances, conformance) {
+			addDetail(false, "conformance `%s` added", conformance)
+		}
+	}
+
+	if oldDecl.VariableKind != newDecl.VariableKind {
+		addDetail(false, "changed from %s to %s", oldDecl.VariableKind, newDecl.VariableKind)
+	}
+
+	if oldDecl.Type != newDecl.Type {
+		addDetail(true, "type changed from `%s` to `%s`", oldDecl.Type, newDecl.Type)
+		if oldDecl.Kind == common.DeclarationKindField.Name() {
+			change.UpdateErrors = append(change.UpdateErrors, "the type of fields cannot be changed")
+		}
+	}
+
+	oldParameters := parametersString(oldDecl.Parameters)
+	newParameters := parametersString(newDecl.Parameters)
+	if oldParameters != newParameters {
+		addDetail(true, "parameters changed from `(%s)` to `(%s)`", oldParameters, newParameters)
+	}
+
+	if oldDecl.ReturnType != newDecl.ReturnType {
+		addDetail(true, "return type changed from `%s` to `%s`", oldDecl.ReturnType, newDecl.ReturnType)
+	}
+
+	if !hasSameEnumCasePrefix(oldDecl, newDecl) {
+		addDetail(true, "enum cases reordered")
+		change.UpdateErrors = append(change.UpdateErrors, "enum cases cannot be reordered")
+	}
+
+	if len(change.Details) == 0 {
+		return
+	}
+
+	if wasPublic || isPublic || len(change.UpdateErrors) > 0 {
+		d.Changes = append(d.Changes, change)
+	}
+}
+
+func (d *APIDiff) add(change *APIChange) {
+	if change.Breaking || len(change.UpdateErrors) > 0 {
+		d.Changes = append(d.Changes, change)
+	}
+}
+
+// Changelog returns a human-readable Markdown changelog of the changes.
+//
+func (d *APIDiff) Changelog() string {
+	var builder strings.Builder
+
+	sections := []struct {
+		title string
+		kind  APIChangeKind
+	}{
+		{"Added", APIChangeKindAdded},
+		{"Removed", APIChangeKindRemoved},
+		{"Changed", APIChangeKindChanged},
+	}
+
+	for _, section := range sections {
+		var changes []*APIChange
+		for _, change := range d.Changes {
+			if change.Kind == section.kind {
+				changes = append(changes, change)
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		if builder.Len() > 0 {
+			builder.WriteString(newline)
+		}
+
+		builder.WriteString(fmt.Sprintf("## %s", section.title))
+		builder.WriteString(newline)
+
+		for _, change := range changes {
+			builder.WriteString(fmt.Sprintf("- %s `%s`", change.DeclarationKind, change.Declaration))
+
+			if change.Breaking {
+				builder.WriteString(" **(breaking)**")
+			}
+
+			builder.WriteString(newline)
+
+			for _, detail := range change.Details {
+				builder.WriteString(fmt.Sprintf("  - %s", detail))
+				builder.WriteString(newline)
+			}
+
+			for _, updateError := range change.UpdateErrors {
+				builder.WriteString(fmt.Sprintf("  - **Invalid contract update**: %s", updateError))
+				builder.WriteString(newline)
+			}
+		}
+	}
+
+	if builder.Len() == 0 {
+		return "No changes" + newline
+	}
+
+	return builder.String()
+}
+
+func declarationsByName(decls []*DeclarationDoc) map[string]*DeclarationDoc {
+	declsByName := make(map[string]*DeclarationDoc, len(decls))
+	for _, decl := range decls {
+		declsByName[decl.Name] = decl
+	}
+	return declsByName
+}
+
+// isPublicDeclaration returns true if the given declaration is part of the public API,
+// i.e. if it and all its enclosing declarations are public.
+// Enum cases have no access modifier, and are public if the enum is public.
+//
+func isPublicDeclaration(decl *DeclarationDoc, isParentPublic bool) bool {
+	if !isParentPublic {
+		return false
+	}
+
+	if decl.Kind == common.DeclarationKindEnumCase.Name() {
+		return true
+	}
+
+	return decl.Access == ast.AccessPublic.Keyword() ||
+		decl.Access == ast.AccessPublicSettable.Keyword()
+}
+
+func isTypeDeclarationKind(kind string) bool {
+	switch kind {
+	case common.DeclarationKindStructure.Name(),
+		common.DeclarationKindResource.Name(),
+		common.DeclarationKindContract.Name(),
+		common.DeclarationKindEvent.Name(),
+		common.DeclarationKindEnum.Name(),
+		common.DeclarationKindStructureInterface.Name(),
+		common.DeclarationKindResourceInterface.Name(),
+		common.DeclarationKindContractInterface.Name():
+		return true
+	default:
+		return false
+	}
+}
+
+// hasSameEnumCasePrefix returns true if the enum cases of the old declaration
+// are a prefix of the enum cases of the new declaration, ignoring removed cases.
+// Removed cases are reported separately.
+//
+func hasSameEnumCasePrefix(oldDecl *DeclarationDoc, newDecl *DeclarationDoc) bool {
+	newCases := enumCaseNames(newDecl)
+	oldCases := enumCaseNames(oldDecl)
+
+	newCaseSet := make(map[string]struct{}, len(newCases))
+	for _, name := range newCases {
+		newCaseSet[name] = struct{}{}
+	}
+
+	index := 0
+	for _, name := range oldCases {
+		if _, ok := newCaseSet[name]; !ok {
+			continue
+		}
+
+		if index >= len(newCases) || newCases[index] != name {
+			return false
+		}
+		index++
+	}
+
+	return true
+}
+
+func enumCaseNames(decl *DeclarationDoc) []string {
+	var names []string
+	for _, member := range decl.Members {
+		if member.Kind == common.DeclarationKindEnumCase.Name() {
+			names = append(names, member.Name)
+		}
+	}
+	return names
+}
+
+func parametersString(parameters []*ParameterDoc) string {
+	parts := make([]string, 0, len(parameters))
+	for _, parameter := range parameters {
+		if len(parameter.Label) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s: %s", parameter.Label, parameter.Name, parameter.Type))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s", parameter.Name, parameter.Type))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func accessDescription(access string) string {
+	if len(access) == 0 {
+		return "not specified"
+	}
+	return access
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+// CheckPrograms parses and type-checks the given programs, keyed by file name,
+// and returns the errors of all the programs.
+//
+// Imports of files (e.g. `import Foo from "Foo.cdc"`) are resolved to the given programs.
+// Imports of addresses (e.g. `import Foo from 0x1`) cannot be resolved, as the deployed
+// contracts are not available. Programs importing addresses, directly or through
+// an imported file, are therefore not type-checked, and they are not reported as invalid.
+//
+func CheckPrograms(sources map[string]string) []*ProgramError {
+	checker := &programsChecker{
+		sources:       sources,
+		elaborations:  map[string]*sema.Elaboration{},
+		programErrors: map[string]error{},
+		unchecked:     map[string]bool{},
+		checking:      map[string]bool{},
+		errors:        []*ProgramError{},
+	}
+
+	files := make([]string, 0, len(sources))
+	for file := range sources {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if _, ok := checker.elaborations[file]; ok {
+			continue
+		}
+		checker.check(file)
+	}
+
+	return checker.errors
+}
+
+type programsChecker struct {
+	sources map[string]string
+	// elaborations of the checked programs. The elaboration is nil if the program has errors.
+	elaborations map[string]*sema.Elaboration
+	// programErrors are the errors of the checked programs which have errors.
+	programErrors map[string]error
+	// unchecked are the programs which cannot be checked, because they import addresses.
+	unchecked map[string]bool
+	// checking are the programs currently being checked, used to detect cyclic imports.
+	checking map[string]bool
+	errors   []*ProgramError
+}
+
+func (c *programsChecker) check(file string) *sema.Elaboration {
+	c.checking[file] = true
+	defer delete(c.checking, file)
+
+	elaboration, err := c.checkProgram(file)
+	if err == errUncheckedImport {
+		c.unchecked[file] = true
+		elaboration = nil
+	} else if err != nil {
+		c.errors = append(c.errors, NewProgramErrors(file, err)...)
+		c.programErrors[file] = err
+		elaboration = nil
+	}
+
+	c.elaborations[file] = elaboration
+
+	return elaboration
+}
+
+func (c *programsChecker) checkProgram(file string) (*sema.Elaboration, error) {
+	program, err := parser.ParseProgram([]byte(c.sources[file]), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.hasUncheckedImport(program) {
+		return nil, errUncheckedImport
+	}
+
+	baseValueActivation := sema.NewVariableActivation(sema.BaseValueActivation)
+	for _, value := range stdlib.DefaultScriptStandardLibraryValues(nil) {
+		baseValueActivation.DeclareValue(value)
+	}
+
+	checker, err := sema.NewChecker(
+		program,
+		common.StringLocation(file),
+		nil,
+		&sema.Config{
+			BaseValueActivation: baseValueActivation,
+			AccessCheckMode:     sema.AccessCheckModeStrict,
+			ImportHandler:       c.importProgram,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checker.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	return checker.Elaboration, nil
+}
+
+// errUncheckedImport is returned when checking a program
+// which imports an address, or a program which cannot be checked.
+//
+var errUncheckedImport = errors.New("cannot check imports of addresses")
+
+// hasUncheckedImport returns true if the given program imports an address,
+// or a file which cannot be checked.
+//
+func (c *programsChecker) hasUncheckedImport(program *ast.Program) bool {
+	for _, declaration := range program.ImportDeclarations() {
+		switch location := declaration.Location.(type) {
+		case common.AddressLocation:
+			return true
+
+		case common.StringLocation:
+			file, ok := c.resolveFile(string(location))
+			if !ok || c.checking[file] {
+				// Reported when checking the import
+				continue
+			}
+
+			if _, ok := c.elaborations[file]; !ok {
+				c.check(file)
+			}
+
+			if c.unchecked[file] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *programsChecker) importProgram(
+	_ *sema.Checker,
+	importedLocation common.Location,
+	importRange ast.Range,
+) (sema.Import, error) {
+
+	if importedLocation == stdlib.CryptoChecker.Location {
+		return sema.ElaborationImport{
+			Elaboration: stdlib.CryptoChecker.Elaboration,
+		}, nil
+	}
+
+	stringLocation, ok := importedLocation.(common.StringLocation)
+	if !ok {
+		return nil, fmt.Errorf("cannot import %s: only imports of files are supported", importedLocation)
+	}
+
+	file, ok := c.resolveFile(string(stringLocation))
+	if !ok {
+		return nil, fmt.Errorf("cannot import %s: no such file", stringLocation)
+	}
+
+	if c.checking[file] {
+		return nil, &sema.CyclicImportsError{
+			Location: importedLocation,
+			Range:    importRange,
+		}
+	}
+
+	elaboration, ok := c.elaborations[file]
+	if !ok {
+		elaboration = c.check(file)
+	}
+
+	if elaboration == nil {
+		return nil, c.programErrors[file]
+	}
+
+	return sema.ElaborationImport{
+		Elaboration: elaboration,
+	}, nil
+}
+
+// resolveFile returns the file name for the given import location,
+// e.g. the location `./Foo.cdc` resolves to the file `Foo.cdc`.
+//
+func (c *programsChecker) resolveFile(location string) (string, bool) {
+	if _, ok := c.sources[location]; ok {
+		return location, true
+	}
+
+	file := path.Clean(location)
+	_, ok := c.sources[file]
+	return file, ok
+}
@@ -19,6 +19,8 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -26,10 +28,34 @@ import (
 	"io/ioutil"
 
 	"github.com/onflow/cadence-tools/docgen"
+	"github.com/onflow/cadence-tools/docgen/templates"
 )
 
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
+var formatFlag = flag.String("format", formatMarkdown, "output format: markdown or json")
+var templatesFlag = flag.String("templates", "", "directory of templates overriding the default templates")
+var coverageFlag = flag.Bool("coverage", false, "report the documentation coverage instead of generating the docs")
+var minCoverageFlag = flag.Float64("min-coverage", 0, "minimum documentation coverage percentage, when reporting the coverage")
+var apiDiffFlag = flag.Bool("api-diff", false, "report the changes of the public API between two versions of a program instead of generating the docs")
+
 func main() {
-	programArgsCount := len(os.Args) - 1
+	flag.Parse()
+
+	if *coverageFlag {
+		reportCoverage()
+		return
+	}
+
+	if *apiDiffFlag {
+		reportAPIDiff()
+		return
+	}
+
+	programArgsCount := flag.NArg()
 	if programArgsCount < 2 {
 		log.Fatalf("Not enough arguments: expected 2, found %d", programArgsCount)
 	}
@@ -38,8 +64,8 @@ func main() {
 		log.Fatalf("Too many arguments: expected 2, found %d", programArgsCount)
 	}
 
-	input := os.Args[1]
-	outputDir := os.Args[2]
+	input := flag.Arg(0)
+	outputDir := flag.Arg(1)
 
 	content, err := ioutil.ReadFile(input)
 	if err != nil {
@@ -58,8 +84,32 @@ func main() {
 
 	code := string(content)
 
-	docGen := docgen.NewDocGenerator()
-	err = docGen.Generate(code, outputDir)
+	var options []docgen.Option
+
+	if len(*templatesFlag) > 0 {
+		templateProvider := templates.NewDirectoryTemplateProvider(
+			*templatesFlag,
+			templates.NewMarkdownTemplateProvider(),
+		)
+
+		err = docgen.ValidateTemplates(templateProvider)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		options = append(options, docgen.WithTemplateProvider(templateProvider))
+	}
+
+	docGen := docgen.NewDocGenerator(options...)
+
+	switch *formatFlag {
+	case formatMarkdown:
+		err = docGen.Generate(code, outputDir)
+	case formatJSON:
+		err = docGen.GenerateJSON(code, outputDir)
+	default:
+		log.Fatalf("Unsupported format: %s", *formatFlag)
+	}
 
 	if err != nil {
 		log.Fatal(err)
@@ -67,3 +117,88 @@ func main() {
 
 	fmt.Println(fmt.Sprintf("Docs generated at: %s", outputDir))
 }
+
+func reportCoverage() {
+	programArgsCount := flag.NArg()
+	if programArgsCount < 1 {
+		log.Fatalf("Not enough arguments: expected 1, found %d", programArgsCount)
+	}
+
+	if programArgsCount > 1 {
+		log.Fatalf("Too many arguments: expected 1, found %d", programArgsCount)
+	}
+
+	input := flag.Arg(0)
+
+	content, err := ioutil.ReadFile(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	docGen := docgen.NewDocGenerator()
+	report, err := docGen.GenerateCoverageReport(string(content))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, issue := range report.Issues {
+		fmt.Printf(
+			"%s:%d:%d: %s\n",
+			input,
+			issue.Position.Line,
+			issue.Position.Column,
+			issue.Message(),
+		)
+	}
+
+	fmt.Printf("Documentation coverage: %s\n", report)
+
+	if report.Coverage() < *minCoverageFlag {
+		fmt.Printf("Documentation coverage is below the minimum of %.2f%%\n", *minCoverageFlag)
+		os.Exit(1)
+	}
+}
+
+func reportAPIDiff() {
+	programArgsCount := flag.NArg()
+	if programArgsCount < 2 {
+		log.Fatalf("Not enough arguments: expected 2, found %d", programArgsCount)
+	}
+
+	if programArgsCount > 2 {
+		log.Fatalf("Too many arguments: expected 2, found %d", programArgsCount)
+	}
+
+	oldContent, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	newContent, err := ioutil.ReadFile(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	docGen := docgen.NewDocGenerator()
+	diff, err := docGen.GenerateAPIDiff(string(oldContent), string(newContent))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *formatFlag {
+	case formatMarkdown:
+		fmt.Print(diff.Changelog())
+	case formatJSON:
+		content, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(content))
+	default:
+		log.Fatalf("Unsupported format: %s", *formatFlag)
+	}
+
+	if diff.HasUpdateErrors() {
+		os.Exit(1)
+	}
+}
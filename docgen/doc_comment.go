@@ -0,0 +1,323 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"strings"
+	"unicode"
+)
+
+const tagPrefix = "@"
+const deprecatedTag = "@deprecated"
+const sinceTag = "@since"
+const exampleTag = "@example"
+const seeTag = "@see"
+const emitsTag = "@emits"
+const throwsTag = "@throws"
+const panicsTag = "@panics"
+
+// DocComment is the structured form of a documentation comment.
+// The summary is the free text of the comment, with all recognised tags removed.
+//
+type DocComment struct {
+	Summary         string       `json:"summary,omitempty"`
+	Params          []DocParam   `json:"params,omitempty"`
+	Returns         string       `json:"returns,omitempty"`
+	Deprecated      bool         `json:"deprecated,omitempty"`
+	DeprecationNote string       `json:"deprecationNote,omitempty"`
+	Since           string       `json:"since,omitempty"`
+	Examples        []DocExample `json:"examples,omitempty"`
+	See             []string     `json:"see,omitempty"`
+	Emits           []DocEvent   `json:"emits,omitempty"`
+	Panics          []string     `json:"panics,omitempty"`
+}
+
+// DocParam is the documentation of a single parameter, given by a `@param name: description` tag.
+//
+type DocParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// DocExample is a code example, given by an `@example` tag.
+// The text following the tag on the same line is the title of the example,
+// and all following lines, up to the next tag, are the code of the example.
+//
+type DocExample struct {
+	Title string `json:"title,omitempty"`
+	Code  string `json:"code"`
+}
+
+// DocEvent is an event emitted by a function, given by an `@emits EventName: description` tag.
+//
+type DocEvent struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// IsEmpty returns true if the comment has neither a summary nor any tags.
+//
+func (c *DocComment) IsEmpty() bool {
+	return c == nil ||
+		len(c.Summary) == 0 &&
+			len(c.Params) == 0 &&
+			len(c.Returns) == 0 &&
+			!c.HasTags()
+}
+
+// HasTags returns true if the comment has any of the tags
+// which are not specific to functions, i.e. other than `@param` and `@return`.
+//
+func (c *DocComment) HasTags() bool {
+	return c.Deprecated ||
+		len(c.Since) > 0 ||
+		len(c.Examples) > 0 ||
+		len(c.See) > 0 ||
+		len(c.Emits) > 0 ||
+		len(c.Panics) > 0
+}
+
+// ParamDoc returns the documentation of the parameter with the given name, if any.
+//
+func (c *DocComment) ParamDoc(name string) (DocParam, bool) {
+	for _, param := range c.Params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return DocParam{}, false
+}
+
+// parseDocComment splits a doc-string into the summary and the tags.
+// The `@param` and `@return` tags are only recognised if parseParams and parseReturn are true,
+// respectively. Otherwise, they are treated as normal doc lines.
+//
+func parseDocComment(docString string, parseParams bool, parseReturn bool) *DocComment {
+	comment := &DocComment{}
+
+	var summaryLines []string
+	var isPrevLineEmpty bool
+
+	// Lines of the example currently being parsed, if any
+	var example *DocExample
+	var exampleLines []string
+
+	endExample := func() {
+		if example == nil {
+			return
+		}
+		example.Code = formatCode(exampleLines)
+		comment.Examples = append(comment.Examples, *example)
+		example = nil
+		exampleLines = nil
+	}
+
+	// Trim leading and trailing empty lines
+	docString = strings.TrimSpace(docString)
+
+	lines := strings.Split(docString, newline)
+
+	for _, line := range lines {
+		formattedLine := strings.TrimSpace(line)
+
+		tag, tagInfo := splitTag(formattedLine)
+
+		// All lines following an example tag are part of the example,
+		// until the next recognised tag.
+		if example != nil {
+			if !isKnownTag(tag) {
+				exampleLines = append(exampleLines, line)
+				continue
+			}
+			endExample()
+		}
+
+		if parseParams && strings.HasPrefix(formattedLine, paramPrefix) {
+			param, ok := parseParamTag(formattedLine)
+			if ok {
+				comment.Params = append(comment.Params, param)
+				continue
+			}
+		} else if parseReturn && strings.HasPrefix(formattedLine, returnPrefix) {
+			comment.Returns = strings.TrimSpace(strings.TrimPrefix(formattedLine, returnPrefix))
+			continue
+		}
+
+		switch tag {
+		case deprecatedTag:
+			comment.Deprecated = true
+			comment.DeprecationNote = tagInfo
+			continue
+
+		case sinceTag:
+			comment.Since = tagInfo
+			continue
+
+		case exampleTag:
+			example = &DocExample{
+				Title: tagInfo,
+			}
+			continue
+
+		case seeTag:
+			if len(tagInfo) > 0 {
+				comment.See = append(comment.See, tagInfo)
+				continue
+			}
+
+		case emitsTag:
+			if len(tagInfo) > 0 {
+				name, description := splitNameAndDescription(tagInfo)
+				comment.Emits = append(comment.Emits, DocEvent{
+					Name:        name,
+					Description: description,
+				})
+				continue
+			}
+
+		case throwsTag, panicsTag:
+			if len(tagInfo) > 0 {
+				comment.Panics = append(comment.Panics, tagInfo)
+				continue
+			}
+		}
+
+		// Ignore the line if its a consecutive blank line.
+		isLineEmpty := len(formattedLine) == 0
+		if isPrevLineEmpty && isLineEmpty {
+			continue
+		}
+
+		summaryLines = append(summaryLines, formattedLine)
+		isPrevLineEmpty = isLineEmpty
+	}
+
+	endExample()
+
+	comment.Summary = strings.TrimSpace(strings.Join(summaryLines, newline))
+
+	return comment
+}
+
+// parseParamTag parses a line of the form `@param name: description`.
+// If the colon is missing, or the name is empty, the parameter name cannot be determined,
+// and the line must be treated as a normal doc line.
+//
+func parseParamTag(line string) (DocParam, bool) {
+	paramInfo := strings.TrimPrefix(line, paramPrefix)
+	colonIndex := strings.IndexByte(paramInfo, ':')
+	if colonIndex < 0 {
+		return DocParam{}, false
+	}
+
+	paramName := strings.TrimSpace(paramInfo[0:colonIndex])
+	if len(paramName) == 0 {
+		return DocParam{}, false
+	}
+
+	return DocParam{
+		Name:        paramName,
+		Description: strings.TrimSpace(paramInfo[colonIndex+1:]),
+	}, true
+}
+
+// splitTag splits a line starting with a tag (e.g. `@since v1.2`)
+// into the tag and the remaining text of the line.
+//
+func splitTag(line string) (tag string, info string) {
+	if !strings.HasPrefix(line, tagPrefix) {
+		return "", ""
+	}
+
+	end := strings.IndexFunc(line, unicode.IsSpace)
+	if end < 0 {
+		return line, ""
+	}
+
+	return line[:end], strings.TrimSpace(line[end:])
+}
+
+func isKnownTag(tag string) bool {
+	switch tag {
+	case strings.TrimSpace(paramPrefix),
+		strings.TrimSpace(returnPrefix),
+		deprecatedTag,
+		sinceTag,
+		exampleTag,
+		seeTag,
+		emitsTag,
+		throwsTag,
+		panicsTag:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitNameAndDescription splits text of the form `name: description`.
+// The description is optional.
+//
+func splitNameAndDescription(info string) (name string, description string) {
+	colonIndex := strings.IndexByte(info, ':')
+	if colonIndex < 0 {
+		return info, ""
+	}
+
+	return strings.TrimSpace(info[:colonIndex]), strings.TrimSpace(info[colonIndex+1:])
+}
+
+// formatCode removes the leading and trailing empty lines of a code snippet,
+// and the indentation common to all lines, preserving the relative indentation.
+//
+func formatCode(lines []string) string {
+	for len(lines) > 0 && len(strings.TrimSpace(lines[0])) == 0 {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && len(strings.TrimSpace(lines[len(lines)-1])) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		lineIndent := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		if indent < 0 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+
+	if indent < 0 {
+		indent = 0
+	}
+
+	formattedLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if len(line) < indent {
+			formattedLines = append(formattedLines, strings.TrimSpace(line))
+			continue
+		}
+		formattedLines = append(formattedLines, strings.TrimRightFunc(line[indent:], unicode.IsSpace))
+	}
+
+	return strings.Join(formattedLines, newline)
+}
@@ -0,0 +1,390 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// DocIssueKind is the kind of documentation issue.
+//
+type DocIssueKind string
+
+const (
+	// DocIssueKindMissingDoc is reported for public declarations without a doc-comment.
+	DocIssueKindMissingDoc DocIssueKind = "missing-doc"
+
+	// DocIssueKindMissingParam is reported for parameters without a `@param` tag.
+	DocIssueKindMissingParam DocIssueKind = "missing-param"
+
+	// DocIssueKindStaleParam is reported for `@param` tags which do not match any parameter.
+	DocIssueKindStaleParam DocIssueKind = "stale-param"
+)
+
+// DocIssue is a documentation issue of a declaration.
+// Parameter is only set for parameter issues.
+//
+type DocIssue struct {
+	Kind            DocIssueKind   `json:"kind"`
+	DeclarationKind string         `json:"declarationKind"`
+	Declaration     string         `json:"declaration"`
+	Parameter       string         `json:"parameter,omitempty"`
+	Position        SourcePosition `json:"position"`
+}
+
+// Message returns a human-readable description of the issue.
+//
+func (i DocIssue) Message() string {
+	switch i.Kind {
+	case DocIssueKindMissingDoc:
+		return fmt.Sprintf("missing documentation for %s `%s`", i.DeclarationKind, i.Declaration)
+	case DocIssueKindMissingParam:
+		return fmt.Sprintf("missing documentation for parameter `%s` of %s `%s`", i.Parameter, i.DeclarationKind, i.Declaration)
+	case DocIssueKindStaleParam:
+		return fmt.Sprintf("documented parameter `%s` does not exist in %s `%s`", i.Parameter, i.DeclarationKind, i.Declaration)
+	default:
+		return string(i.Kind)
+	}
+}
+
+// CoverageReport is the documentation coverage of the public declarations of a program.
+// Public declarations and the parameters of public functions and events are counted.
+//
+type CoverageReport struct {
+	Total      int        `json:"total"`
+	Documented int        `json:"documented"`
+	Issues     []DocIssue `json:"issues"`
+}
+
+// Coverage returns the percentage of documented declarations and parameters.
+//
+func (r *CoverageReport) Coverage() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return float64(r.Documented) / float64(r.Total) * 100
+}
+
+func (r *CoverageReport) String() string {
+	return fmt.Sprintf("%.2f%% (%d/%d)", r.Coverage(), r.Documented, r.Total)
+}
+
+// GenerateCoverageReport parses the given source and reports
+// the documentation coverage of all the public declarations.
+//
+func (gen *DocGenerator) GenerateCoverageReport(source string) (*CoverageReport, error) {
+	program, err := parser.ParseProgram([]byte(source), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CoverageReport{
+		Issues: []DocIssue{},
+	}
+	report.addDeclarations(program.Declarations(), nil, true, source)
+
+	return report, nil
+}
+
+// addDeclarations counts the given declarations and their members.
+// Members are only counted if all their enclosing declarations are public,
+// like the documentation is only generated for them.
+//
+func (r *CoverageReport) addDeclarations(
+	decls []ast.Declaration,
+	parentPath []string,
+	isParentPublic bool,
+	source string,
+) {
+	for _, decl := range decls {
+		declPath := append(parentPath[:len(parentPath):len(parentPath)], decl.DeclarationIdentifier().Identifier)
+		public := isParentPublic && isPublic(decl.DeclarationAccess())
+
+		switch decl := decl.(type) {
+		case *ast.CompositeDeclaration:
+			if decl.CompositeKind == common.CompositeKindEvent {
+				r.addFunctionLike(decl, declPath, eventParameters(decl), decl.DocString, false, public, source)
+				continue
+			}
+
+			r.addDeclaration(decl, declPath, public)
+			r.addInitializer(decl, declPath, public, source)
+			r.addDeclarations(decl.Members.Declarations(), declPath, public, source)
+
+		case *ast.InterfaceDeclaration:
+			r.addDeclaration(decl, declPath, public)
+			r.addDeclarations(decl.Members.Declarations(), declPath, public, source)
+
+		case *ast.FunctionDeclaration:
+			r.addFunctionLike(decl, declPath, decl.ParameterList, decl.DocString, true, public, source)
+
+		case *ast.FieldDeclaration:
+			r.addDeclaration(decl, declPath, public)
+		}
+	}
+}
+
+// addInitializer counts the initializer of the given composite declaration and its parameters,
+// if the composite is public and the initializer is documented, i.e. for structures and resources.
+// Initializers have no access modifier, so they are public if the composite is public.
+// The parser does not record the doc-comments of initializers,
+// so the doc-comment is read from the source.
+//
+func (r *CoverageReport) addInitializer(
+	decl *ast.CompositeDeclaration,
+	declPath []string,
+	public bool,
+	source string,
+) {
+	switch decl.CompositeKind {
+	case common.CompositeKindStructure,
+		common.CompositeKindResource:
+	default:
+		return
+	}
+
+	initializers := decl.Members.Initializers()
+	if len(initializers) == 0 {
+		return
+	}
+
+	initializer := initializers[0]
+	initializerPath := append(declPath[:len(declPath):len(declPath)], initializer.DeclarationIdentifier().Identifier)
+
+	r.addFunctionLike(
+		initializer,
+		initializerPath,
+		initializer.FunctionDeclaration.ParameterList,
+		precedingDocString(source, initializer.StartPosition()),
+		false,
+		public,
+		source,
+	)
+}
+
+// addDeclaration counts the given declaration, if it is public.
+// Returns the parsed doc-comment and true if the declaration was counted.
+//
+func (r *CoverageReport) addDeclaration(decl ast.Declaration, declPath []string, public bool) (*DocComment, bool) {
+	return r.addDeclarationWithComment(
+		decl,
+		declPath,
+		parseDocComment(decl.DeclarationDocString(), false, false),
+		public,
+	)
+}
+
+func (r *CoverageReport) addDeclarationWithComment(
+	decl ast.Declaration,
+	declPath []string,
+	comment *DocComment,
+	public bool,
+) (*DocComment, bool) {
+	if !public {
+		return nil, false
+	}
+
+	r.Total++
+
+	if len(comment.Summary) > 0 {
+		r.Documented++
+	} else {
+		r.Issues = append(r.Issues, DocIssue{
+			Kind:            DocIssueKindMissingDoc,
+			DeclarationKind: decl.DeclarationKind().Name(),
+			Declaration:     strings.Join(declPath, "."),
+			Position:        newSourcePosition(decl.StartPosition()),
+		})
+	}
+
+	return comment, true
+}
+
+// addFunctionLike counts the given function, initializer or event declaration, if it is public,
+// and all of its parameters. `@param` tags not matching any of the parameters are reported.
+//
+func (r *CoverageReport) addFunctionLike(
+	decl ast.Declaration,
+	declPath []string,
+	parameterList *ast.ParameterList,
+	docString string,
+	parseReturn bool,
+	public bool,
+	source string,
+) {
+	comment, ok := r.addDeclarationWithComment(
+		decl,
+		declPath,
+		parseDocComment(docString, true, parseReturn),
+		public,
+	)
+	if !ok {
+		return
+	}
+
+	declName := strings.Join(declPath, ".")
+	declKind := decl.DeclarationKind().Name()
+
+	var parameters map[string]*ast.Parameter
+
+	if parameterList != nil {
+		for _, parameter := range parameterList.Parameters {
+			r.Total++
+
+			name := parameter.Identifier.Identifier
+			if _, ok := comment.ParamDoc(name); ok {
+				r.Documented++
+				continue
+			}
+
+			r.Issues = append(r.Issues, DocIssue{
+				Kind:            DocIssueKindMissingParam,
+				DeclarationKind: declKind,
+				Declaration:     declName,
+				Parameter:       name,
+				Position:        newSourcePosition(parameter.StartPos),
+			})
+		}
+
+		parameters = parameterList.ParametersByIdentifier()
+	}
+
+	for _, param := range comment.Params {
+		if _, ok := parameters[param.Name]; ok {
+			continue
+		}
+
+		r.Issues = append(r.Issues, DocIssue{
+			Kind:            DocIssueKindStaleParam,
+			DeclarationKind: declKind,
+			Declaration:     declName,
+			Parameter:       param.Name,
+			Position:        paramTagPosition(source, decl.StartPosition(), param.Name),
+		})
+	}
+}
+
+// paramTagPosition returns the position of the `@param` tag of the parameter with the given name,
+// in the doc-comment preceding the declaration at the given position.
+// Returns the position of the declaration if the tag cannot be found.
+//
+func paramTagPosition(source string, declPosition ast.Position, name string) SourcePosition {
+	if declPosition.Offset > len(source) {
+		return newSourcePosition(declPosition)
+	}
+
+	// The doc-comment directly precedes the declaration,
+	// so the last matching tag before the declaration belongs to it.
+	offset := -1
+	preceding := source[:declPosition.Offset]
+	for {
+		index := strings.LastIndex(preceding, strings.TrimSpace(paramPrefix))
+		if index < 0 {
+			break
+		}
+
+		tag, ok := parseParamTag(firstLine(source[index:]))
+		if ok && tag.Name == name {
+			offset = index
+			break
+		}
+
+		preceding = preceding[:index]
+	}
+
+	if offset < 0 {
+		return newSourcePosition(declPosition)
+	}
+
+	line := 1 + strings.Count(source[:offset], newline)
+	column := offset - (strings.LastIndex(source[:offset], newline) + 1)
+
+	return SourcePosition{
+		Offset: offset,
+		Line:   line,
+		Column: column,
+	}
+}
+
+// precedingDocString returns the doc-string of the doc-comment directly preceding
+// the given position, i.e. the content of a `/** */` block comment,
+// or of consecutive `///` line comments.
+//
+func precedingDocString(source string, position ast.Position) string {
+	if position.Offset > len(source) {
+		return ""
+	}
+
+	preceding := strings.TrimRight(source[:position.Offset], " \t\r\n")
+
+	if strings.HasSuffix(preceding, "*/") {
+		start := strings.LastIndex(preceding, "/**")
+		if start < 0 {
+			return ""
+		}
+		return preceding[start+len("/**") : len(preceding)-len("*/")]
+	}
+
+	var lines []string
+	for {
+		lineStart := strings.LastIndex(preceding, newline) + 1
+		line := strings.TrimSpace(preceding[lineStart:])
+		if !strings.HasPrefix(line, "///") {
+			break
+		}
+
+		lines = append([]string{strings.TrimPrefix(line, "///")}, lines...)
+
+		if lineStart == 0 {
+			break
+		}
+		preceding = preceding[:lineStart-1]
+	}
+
+	return strings.Join(lines, newline)
+}
+
+func firstLine(text string) string {
+	index := strings.Index(text, newline)
+	if index < 0 {
+		return text
+	}
+	return text[:index]
+}
+
+// eventParameters returns the parameters of an event.
+// Events are declared with an initializer holding the parameters.
+//
+func eventParameters(decl *ast.CompositeDeclaration) *ast.ParameterList {
+	initializers := decl.Members.Initializers()
+	if len(initializers) == 0 {
+		return nil
+	}
+	return initializers[0].FunctionDeclaration.ParameterList
+}
+
+func isPublic(access ast.Access) bool {
+	return access == ast.AccessPublic ||
+		access == ast.AccessPublicSettable
+}
@@ -20,8 +20,11 @@ package docgen
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"strings"
@@ -35,9 +38,8 @@ import (
 	"github.com/onflow/cadence-tools/docgen/templates"
 )
 
-const nameSeparator = "_"
 const newline = "\n"
-const mdFileExt = ".md"
+const jsonFileName = "docs.json"
 const paramPrefix = "@param "
 const returnPrefix = "@return "
 
@@ -55,12 +57,17 @@ var templateFiles = []string{
 	"enum-case-template",
 	"initializer-template",
 	"event-template",
+	"field-details-template",
+	"doc-tags-template",
 }
 
 type DocGenerator struct {
 	entryPageGen     *template.Template
 	compositePageGen *template.Template
+	templateProvider templates.TemplateProvider
 	typeNames        []string
+	layout           pageLayout
+	pages            map[string]string
 	outputDir        string
 	files            InMemoryFiles
 }
@@ -91,51 +98,129 @@ func (w *InMemoryFileWriter) Close() error {
 	return nil
 }
 
-func NewDocGenerator() *DocGenerator {
-	gen := &DocGenerator{}
+// Option is an option for the document generator.
+//
+type Option func(*DocGenerator)
 
-	functions := newTemplateFunctions[ast.Declaration](ASTDeclarationTemplateFunctions{})
+// WithTemplateProvider sets the provider of the templates used to generate the documentation.
+// By default, the embedded Markdown templates are used.
+//
+func WithTemplateProvider(templateProvider templates.TemplateProvider) Option {
+	return func(gen *DocGenerator) {
+		gen.templateProvider = templateProvider
+	}
+}
 
-	functions["fileName"] = func(decl ast.Declaration) string {
-		fileNamePrefix := gen.currentFileName()
-		if len(fileNamePrefix) == 0 {
-			return fmt.Sprint(decl.DeclarationIdentifier().String(), mdFileExt)
-		}
+// NewDocGenerator creates a new document generator.
+// It panics if the templates are invalid, use ValidateTemplates
+// to check user-supplied templates beforehand.
+//
+func NewDocGenerator(options ...Option) *DocGenerator {
+	gen := &DocGenerator{
+		templateProvider: templates.NewMarkdownTemplateProvider(),
+	}
 
-		return fmt.Sprint(fileNamePrefix, nameSeparator, decl.DeclarationIdentifier().String(), mdFileExt)
+	for _, option := range options {
+		option(gen)
 	}
 
-	templateProvider := templates.NewMarkdownTemplateProvider()
+	functions := gen.templateFunctions()
 
-	gen.entryPageGen = newTemplate(baseTemplate, templateProvider, functions)
-	gen.compositePageGen = newTemplate(compositeFullTemplate, templateProvider, functions)
+	var err error
+
+	gen.entryPageGen, err = newTemplate(baseTemplate, gen.templateProvider, functions)
+	if err != nil {
+		panic(err)
+	}
+
+	gen.compositePageGen, err = newTemplate(compositeFullTemplate, gen.templateProvider, functions)
+	if err != nil {
+		panic(err)
+	}
 
 	return gen
 }
 
-func newTemplate(name string, templateProvider templates.TemplateProvider, functions template.FuncMap) *template.Template {
+func (gen *DocGenerator) templateFunctions() template.FuncMap {
+	functions := newTemplateFunctions[ast.Declaration](ASTDeclarationTemplateFunctions{})
+
+	functions["fileName"] = func(decl ast.Declaration) string {
+		var typePath []string
+		typePath = append(typePath, gen.typeNames...)
+		typePath = append(typePath, decl.DeclarationIdentifier().Identifier)
+
+		return relativeLink(gen.currentPage(), gen.layout.pagePath(typePath))
+	}
+
+	functions["seeLink"] = gen.seeLink
+	functions["breadcrumbs"] = gen.breadcrumbs
+	functions["eventParameters"] = eventParameterDocs
+
+	return functions
+}
+
+func newTemplate(
+	name string,
+	templateProvider templates.TemplateProvider,
+	functions template.FuncMap,
+) (*template.Template, error) {
 	rootTemplate := template.New(name).Funcs(functions)
 
 	for _, templateFile := range templateFiles {
 		content, err := templateProvider.Get(templateFile)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 
 		var tmpl *template.Template
 		if templateFile == name {
 			tmpl = rootTemplate
 		} else {
-			tmpl = rootTemplate.New(name)
+			tmpl = rootTemplate.New(templateFile)
 		}
 
 		_, err = tmpl.Parse(content)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+	}
+
+	return rootTemplate, nil
+}
+
+// ValidateTemplates checks that the given provider provides all the templates
+// required by the document generator, and that all of them can be parsed.
+//
+func ValidateTemplates(templateProvider templates.TemplateProvider) error {
+	gen := &DocGenerator{}
+	functions := gen.templateFunctions()
+
+	validationErr := &TemplateValidationError{}
+
+	for _, templateFile := range templateFiles {
+		content, err := templateProvider.Get(templateFile)
+		if err != nil {
+			validationErr.Missing = append(validationErr.Missing, templateFile)
+			continue
+		}
+
+		_, err = template.New(templateFile).Funcs(functions).Parse(content)
+		if err != nil {
+			validationErr.Invalid = append(
+				validationErr.Invalid,
+				TemplateParseError{
+					Template: templateFile,
+					Err:      err,
+				},
+			)
 		}
 	}
 
-	return rootTemplate
+	if len(validationErr.Missing) > 0 || len(validationErr.Invalid) > 0 {
+		return validationErr
+	}
+
+	return nil
 }
 
 func (gen *DocGenerator) Generate(source string, outputDir string) error {
@@ -167,18 +252,58 @@ func (gen *DocGenerator) GenerateInMemory(source string) (InMemoryFiles, error)
 	return gen.files, nil
 }
 
+// GenerateModel parses the given source and returns the structured documentation model
+// of all the declarations in the program.
+//
+func (gen *DocGenerator) GenerateModel(source string) (*Documentation, error) {
+	program, err := parser.ParseProgram([]byte(source), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDocumentation(program), nil
+}
+
+// GenerateJSON writes the documentation model of the given source
+// as a JSON file to the output directory.
+//
+func (gen *DocGenerator) GenerateJSON(source string, outputDir string) error {
+	gen.outputDir = outputDir
+	gen.files = nil
+
+	documentation, err := gen.GenerateModel(source)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(documentation, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := gen.fileWriter(jsonFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(content)
+	return err
+}
+
 func (gen *DocGenerator) genProgram(program *ast.Program) error {
 
+	gen.layout = newPageLayout(program)
+
+	gen.pages = map[string]string{}
+	gen.collectPages(program.Declarations(), nil)
+
 	// If the program does not have a sole declaration,
 	// i.e. it has multiple top level declarations,
 	// then generate an entry page.
 
-	if program.SoleContractDeclaration() == nil &&
-		program.SoleContractInterfaceDeclaration() == nil {
-
-		// Generate entry page
-		// TODO: file name 'index' can conflict with struct names, resulting an overwrite.
-		f, err := gen.fileWriter("index.md")
+	if gen.layout.hasEntryPage() {
+		f, err := gen.fileWriter(gen.currentPage())
 		if err != nil {
 			return err
 		}
@@ -235,8 +360,7 @@ func (gen *DocGenerator) genCompositeDecl(name string, members *ast.Members, dec
 		gen.typeNames = gen.typeNames[:lastIndex]
 	}()
 
-	fileName := fmt.Sprint(gen.currentFileName(), mdFileExt)
-	f, err := gen.fileWriter(fileName)
+	f, err := gen.fileWriter(gen.currentPage())
 	if err != nil {
 		return err
 	}
@@ -250,16 +374,125 @@ func (gen *DocGenerator) genCompositeDecl(name string, members *ast.Members, dec
 	return gen.genDeclarations(members.Declarations())
 }
 
+// collectPages records the page of each composite and interface declaration, and of their members,
+// so that references to them in doc-comments can be resolved to their pages.
+//
+func (gen *DocGenerator) collectPages(decls []ast.Declaration, parentPath []string) {
+	for _, decl := range decls {
+		declPath := append(parentPath[:len(parentPath):len(parentPath)], decl.DeclarationIdentifier().Identifier)
+		qualifiedName := strings.Join(declPath, ".")
+
+		switch decl.(type) {
+		case *ast.CompositeDeclaration, *ast.InterfaceDeclaration:
+			if decl.DeclarationKind() != common.DeclarationKindEvent {
+				gen.pages[qualifiedName] = gen.layout.pagePath(declPath)
+				gen.collectPages(decl.DeclarationMembers().Declarations(), declPath)
+				continue
+			}
+		}
+
+		gen.pages[qualifiedName] = gen.layout.pagePath(parentPath)
+	}
+}
+
+// seeLink resolves a reference given in a `@see` tag to the page of the referenced declaration.
+// The reference is resolved relative to the current declaration and all its enclosing declarations.
+// Returns an empty string if the reference cannot be resolved.
+//
+func (gen *DocGenerator) seeLink(reference string) string {
+	reference = strings.TrimSuffix(reference, "()")
+
+	for i := len(gen.typeNames); i >= 0; i-- {
+		var qualifiedName []string
+		qualifiedName = append(qualifiedName, gen.typeNames[:i]...)
+		qualifiedName = append(qualifiedName, reference)
+
+		page, ok := gen.pages[strings.Join(qualifiedName, ".")]
+		if ok {
+			return relativeLink(gen.currentPage(), page)
+		}
+	}
+
+	return ""
+}
+
+// eventParameterDocs returns the parameters of an event,
+// together with their documentation given in the doc-comment of the event.
+//
+func eventParameterDocs(declaration ast.Declaration) []*ParameterDoc {
+	compositeDeclaration, ok := declaration.(*ast.CompositeDeclaration)
+	if !ok {
+		return nil
+	}
+
+	return newParameterDocs(
+		eventParameters(compositeDeclaration),
+		parseDocComment(compositeDeclaration.DocString, true, false),
+	)
+}
+
+// breadcrumbs returns the links to the pages of all the enclosing declarations of the current page,
+// starting with the entry page.
+//
+func (gen *DocGenerator) breadcrumbs() []PageLink {
+	var links []PageLink
+
+	currentPage := gen.currentPage()
+
+	if gen.layout.hasEntryPage() && len(gen.typeNames) > 0 {
+		links = append(links, PageLink{
+			Link: relativeLink(currentPage, gen.layout.pagePath(nil)),
+		})
+	}
+
+	for i := 1; i < len(gen.typeNames); i++ {
+		links = append(links, PageLink{
+			Name: gen.typeNames[i-1],
+			Link: relativeLink(currentPage, gen.layout.pagePath(gen.typeNames[:i])),
+		})
+	}
+
+	return links
+}
+
 func (gen *DocGenerator) fileWriter(fileName string) (io.WriteCloser, error) {
 	if gen.files == nil {
+		err := gen.createDirs(path.Dir(fileName))
+		if err != nil {
+			return nil, err
+		}
+
 		return os.Create(path.Join(gen.outputDir, fileName))
 	}
 
 	return NewInMemoryFileWriter(gen.files, fileName), nil
 }
 
-func (gen *DocGenerator) currentFileName() string {
-	return strings.Join(gen.typeNames, nameSeparator)
+// createDirs creates the given directory and all its parents, inside the output directory.
+// Unlike os.MkdirAll, the output directory itself is never created.
+//
+func (gen *DocGenerator) createDirs(dir string) error {
+	if dir == "." {
+		return nil
+	}
+
+	err := gen.createDirs(path.Dir(dir))
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(path.Join(gen.outputDir, dir), os.ModePerm)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	return nil
+}
+
+// currentPage returns the path of the page of the declaration currently being generated.
+//
+func (gen *DocGenerator) currentPage() string {
+	return gen.layout.pagePath(gen.typeNames)
 }
 
 type ElementTemplateFunctions[T any] interface {
@@ -379,117 +612,57 @@ func newTemplateFunctions[T any](
 		"events":              elementFunctions.Events,
 		"formatDoc":           formatDoc,
 		"formatFuncDoc":       formatFuncDoc,
+		"parseDoc":            parseDoc,
+		"parseFuncDoc":        parseFuncDoc,
 	}
 }
 
 func formatDoc(docString string) string {
-	var builder strings.Builder
-
-	// Trim leading and trailing empty lines
-	docString = strings.TrimSpace(docString)
-
-	lines := strings.Split(docString, newline)
-
-	for i, line := range lines {
-		formattedLine := strings.TrimSpace(line)
-		if i > 0 {
-			builder.WriteString(newline)
-		}
-		builder.WriteString(formattedLine)
-	}
-
-	return builder.String()
+	return parseDocComment(docString, false, false).Summary
 }
 
 func formatFuncDoc(docString string, genReturnType bool) string {
 	var builder strings.Builder
 
-	var params []string
-	var isPrevLineEmpty bool
-	var docLines int
-	var returnDoc string
-
-	// Trim leading and trailing empty lines
-	docString = strings.TrimSpace(docString)
-
-	lines := strings.Split(docString, newline)
-
-	for _, line := range lines {
-		formattedLine := strings.TrimSpace(line)
-
-		if strings.HasPrefix(formattedLine, paramPrefix) {
-			paramInfo := strings.TrimPrefix(formattedLine, paramPrefix)
-			colonIndex := strings.IndexByte(paramInfo, ':')
-
-			// If colon isn't there, cannot determine the param name.
-			// Hence treat as a normal doc line.
-			if colonIndex >= 0 {
-				paramName := strings.TrimSpace(paramInfo[0:colonIndex])
-
-				// If param name is empty, treat as a normal doc line.
-				if len(paramName) > 0 {
-					paramDoc := strings.TrimSpace(paramInfo[colonIndex+1:])
-
-					var formattedParam string
-					if len(paramDoc) > 0 {
-						formattedParam = fmt.Sprintf("  - %s : _%s_", paramName, paramDoc)
-					} else {
-						formattedParam = fmt.Sprintf("  - %s", paramName)
-					}
-
-					params = append(params, formattedParam)
-					continue
-				}
-			}
-		} else if genReturnType && strings.HasPrefix(formattedLine, returnPrefix) {
-			returnDoc = formattedLine
-			continue
-		}
-
-		// Ignore the line if its a consecutive blank line.
-		isLineEmpty := len(formattedLine) == 0
-		if isPrevLineEmpty && isLineEmpty {
-			continue
-		}
-
-		if docLines > 0 {
-			builder.WriteString(newline)
-		}
+	comment := parseDocComment(docString, true, genReturnType)
 
-		builder.WriteString(formattedLine)
-		isPrevLineEmpty = isLineEmpty
-		docLines++
-	}
+	builder.WriteString(comment.Summary)
 
 	// Print the parameters
-	if len(params) > 0 {
-		if !isPrevLineEmpty {
-			builder.WriteString(newline)
-		}
-
+	if len(comment.Params) > 0 {
+		builder.WriteString(newline)
 		builder.WriteString(newline)
 		builder.WriteString("Parameters:")
 
-		for _, param := range params {
+		for _, param := range comment.Params {
 			builder.WriteString(newline)
-			builder.WriteString(param)
-		}
 
-		isPrevLineEmpty = false
+			if len(param.Description) > 0 {
+				builder.WriteString(fmt.Sprintf("  - %s : _%s_", param.Name, param.Description))
+			} else {
+				builder.WriteString(fmt.Sprintf("  - %s", param.Name))
+			}
+		}
 	}
 
 	// Print the return type info
-	if len(returnDoc) > 0 {
-		if !isPrevLineEmpty {
-			builder.WriteString(newline)
-		}
-
+	if len(comment.Returns) > 0 {
 		builder.WriteString(newline)
-
-		returnInfo := strings.TrimPrefix(returnDoc, returnPrefix)
-		returnInfo = strings.TrimSpace(returnInfo)
-		builder.WriteString(fmt.Sprintf("Returns: %s", returnInfo))
+		builder.WriteString(newline)
+		builder.WriteString(fmt.Sprintf("Returns: %s", comment.Returns))
 	}
 
 	return builder.String()
 }
+
+// parseDoc returns the structured doc-comment of a declaration, for use in templates.
+//
+func parseDoc(docString string) *DocComment {
+	return parseDocComment(docString, false, false)
+}
+
+// parseFuncDoc returns the structured doc-comment of a function or an event, for use in templates.
+//
+func parseFuncDoc(docString string, parseReturn bool) *DocComment {
+	return parseDocComment(docString, true, parseReturn)
+}
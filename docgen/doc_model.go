@@ -0,0 +1,234 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// ModelVersion is the version of the documentation model.
+// It must be incremented whenever a backward incompatible change is made to the model.
+//
+const ModelVersion = 1
+
+// Documentation is the structured documentation model of a Cadence program.
+// It is designed to be serialized as JSON and consumed by other tools.
+//
+type Documentation struct {
+	Version      int               `json:"version"`
+	Declarations []*DeclarationDoc `json:"declarations"`
+}
+
+// DeclarationDoc is the documentation of a single declaration.
+//
+// VariableKind and Type are only set for fields and variables.
+// Parameters and ReturnType are only set for functions, initializers and events.
+//
+type DeclarationDoc struct {
+	Kind         string            `json:"kind"`
+	Access       string            `json:"access,omitempty"`
+	Name         string            `json:"name"`
+	Conformances []string          `json:"conformances,omitempty"`
+	VariableKind string            `json:"variableKind,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	Parameters   []*ParameterDoc   `json:"parameters,omitempty"`
+	ReturnType   string            `json:"returnType,omitempty"`
+	Doc          *DocComment       `json:"doc,omitempty"`
+	Members      []*DeclarationDoc `json:"members,omitempty"`
+	Range        SourceRange       `json:"range"`
+}
+
+// ParameterDoc is the documentation of a function or event parameter.
+// The documentation is taken from the matching `@param` tag of the enclosing declaration.
+//
+type ParameterDoc struct {
+	Label string `json:"label,omitempty"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Doc   string `json:"doc,omitempty"`
+}
+
+// SourceRange is the range of a declaration in the source code.
+//
+type SourceRange struct {
+	Start SourcePosition `json:"start"`
+	End   SourcePosition `json:"end"`
+}
+
+// SourcePosition is a position in the source code.
+// Lines start at 1, columns and offsets start at 0.
+//
+type SourcePosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func newSourceRange(element ast.HasPosition) SourceRange {
+	return SourceRange{
+		Start: newSourcePosition(element.StartPosition()),
+		End:   newSourcePosition(element.EndPosition(nil)),
+	}
+}
+
+func newSourcePosition(position ast.Position) SourcePosition {
+	return SourcePosition{
+		Offset: position.Offset,
+		Line:   position.Line,
+		Column: position.Column,
+	}
+}
+
+// NewDocumentation builds the documentation model for all declarations of the given program.
+//
+func NewDocumentation(program *ast.Program) *Documentation {
+	return &Documentation{
+		Version:      ModelVersion,
+		Declarations: newDeclarationDocs(program.Declarations()),
+	}
+}
+
+func newDeclarationDocs(declarations []ast.Declaration) []*DeclarationDoc {
+	docs := make([]*DeclarationDoc, 0, len(declarations))
+
+	for _, declaration := range declarations {
+		doc := newDeclarationDoc(declaration)
+		if doc == nil {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs
+}
+
+func newDeclarationDoc(declaration ast.Declaration) *DeclarationDoc {
+	switch declaration.(type) {
+	case *ast.ImportDeclaration,
+		*ast.PragmaDeclaration,
+		*ast.TransactionDeclaration:
+		return nil
+	}
+
+	doc := &DeclarationDoc{
+		Kind:   declaration.DeclarationKind().Name(),
+		Access: declaration.DeclarationAccess().Keyword(),
+		Name:   declaration.DeclarationIdentifier().Identifier,
+		Range:  newSourceRange(declaration),
+	}
+
+	switch declaration := declaration.(type) {
+	case *ast.CompositeDeclaration:
+		doc.Conformances = conformanceNames(declaration.Conformances)
+		isEvent := declaration.CompositeKind == common.CompositeKindEvent
+		doc.Doc = parseDocComment(declaration.DocString, isEvent, false)
+
+		if isEvent {
+			doc.Parameters = newParameterDocs(eventParameters(declaration), doc.Doc)
+		} else {
+			doc.Members = newDeclarationDocs(declaration.Members.Declarations())
+		}
+
+	case *ast.InterfaceDeclaration:
+		doc.Doc = parseDocComment(declaration.DocString, false, false)
+		doc.Members = newDeclarationDocs(declaration.Members.Declarations())
+
+	case *ast.FunctionDeclaration:
+		setFunctionDoc(doc, declaration)
+
+	case *ast.SpecialFunctionDeclaration:
+		setFunctionDoc(doc, declaration.FunctionDeclaration)
+
+	case *ast.FieldDeclaration:
+		doc.VariableKind = declaration.VariableKind.Name()
+		doc.Type = typeAnnotationString(declaration.TypeAnnotation)
+		doc.Doc = parseDocComment(declaration.DocString, false, false)
+
+	case *ast.VariableDeclaration:
+		doc.VariableKind = declaration.DeclarationKind().Name()
+		doc.Type = typeAnnotationString(declaration.TypeAnnotation)
+		doc.Doc = parseDocComment(declaration.DocString, false, false)
+
+	default:
+		doc.Doc = parseDocComment(declaration.DeclarationDocString(), false, false)
+	}
+
+	if doc.Doc.IsEmpty() {
+		doc.Doc = nil
+	}
+
+	return doc
+}
+
+func setFunctionDoc(doc *DeclarationDoc, declaration *ast.FunctionDeclaration) {
+	doc.Doc = parseDocComment(declaration.DocString, true, true)
+	doc.Parameters = newParameterDocs(declaration.ParameterList, doc.Doc)
+	doc.ReturnType = typeAnnotationString(declaration.ReturnTypeAnnotation)
+}
+
+func newParameterDocs(parameterList *ast.ParameterList, comment *DocComment) []*ParameterDoc {
+	if parameterList == nil {
+		return nil
+	}
+
+	parameters := make([]*ParameterDoc, 0, len(parameterList.Parameters))
+
+	for _, parameter := range parameterList.Parameters {
+		name := parameter.Identifier.Identifier
+
+		parameterDoc := &ParameterDoc{
+			Label: parameter.Label,
+			Name:  name,
+			Type:  typeAnnotationString(parameter.TypeAnnotation),
+		}
+
+		if param, ok := comment.ParamDoc(name); ok {
+			parameterDoc.Doc = param.Description
+		}
+
+		parameters = append(parameters, parameterDoc)
+	}
+
+	return parameters
+}
+
+func conformanceNames(conformances []*ast.NominalType) []string {
+	if len(conformances) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(conformances))
+	for _, conformance := range conformances {
+		names = append(names, conformance.String())
+	}
+
+	return names
+}
+
+func typeAnnotationString(typeAnnotation *ast.TypeAnnotation) string {
+	if typeAnnotation == nil ||
+		typeAnnotation.Type == nil ||
+		ast.IsEmptyType(typeAnnotation.Type) {
+
+		return ""
+	}
+
+	return typeAnnotation.String()
+}
@@ -0,0 +1,139 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// TemplateValidationError is reported when the templates
+// provided to the document generator are incomplete or invalid.
+//
+type TemplateValidationError struct {
+	Missing []string
+	Invalid []TemplateParseError
+}
+
+var _ error = &TemplateValidationError{}
+
+func (e *TemplateValidationError) Error() string {
+	var builder strings.Builder
+	builder.WriteString("invalid templates")
+
+	if len(e.Missing) > 0 {
+		builder.WriteString(newline)
+		builder.WriteString(fmt.Sprintf("missing templates: %s", strings.Join(e.Missing, ", ")))
+	}
+
+	for _, invalid := range e.Invalid {
+		builder.WriteString(newline)
+		builder.WriteString(invalid.Error())
+	}
+
+	return builder.String()
+}
+
+// TemplateParseError is reported when a template cannot be parsed.
+//
+type TemplateParseError struct {
+	Template string
+	Err      error
+}
+
+var _ error = TemplateParseError{}
+
+func (e TemplateParseError) Error() string {
+	return fmt.Sprintf("failed to parse template '%s': %s", e.Template, e.Err)
+}
+
+func (e TemplateParseError) Unwrap() error {
+	return e.Err
+}
+
+// ProgramError is an error in a program, e.g. a syntax error or a type error.
+// The range is only set if the position of the error is known.
+//
+type ProgramError struct {
+	File    string       `json:"file,omitempty"`
+	Message string       `json:"message"`
+	Range   *SourceRange `json:"range,omitempty"`
+}
+
+var _ error = &ProgramError{}
+
+func (e *ProgramError) Error() string {
+	if e.Range == nil {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+
+	return fmt.Sprintf(
+		"%s:%d:%d: %s",
+		e.File,
+		e.Range.Start.Line,
+		e.Range.Start.Column,
+		e.Message,
+	)
+}
+
+// NewProgramErrors flattens the given error of the given file, e.g. a parser or checker error,
+// into the individual errors, together with their positions.
+//
+func NewProgramErrors(file string, err error) []*ProgramError {
+	if positioned, ok := err.(ast.HasPosition); ok {
+		message := err.Error()
+
+		// Imports which cannot be resolved are reported as errors of the imported program.
+		// The errors of imported programs are reported for the imported programs themselves,
+		// so only report the reason why an import could not be resolved.
+		if importErr, ok := err.(*sema.ImportedProgramError); ok {
+			if _, ok := importErr.Err.(errors.ParentError); !ok {
+				message = fmt.Sprintf("%s: %s", message, importErr.Err)
+			}
+		}
+
+		sourceRange := newSourceRange(positioned)
+		return []*ProgramError{
+			{
+				File:    file,
+				Message: message,
+				Range:   &sourceRange,
+			},
+		}
+	}
+
+	if parentError, ok := err.(errors.ParentError); ok {
+		var programErrors []*ProgramError
+		for _, childError := range parentError.ChildErrors() {
+			programErrors = append(programErrors, NewProgramErrors(file, childError)...)
+		}
+		return programErrors
+	}
+
+	return []*ProgramError{
+		{
+			File:    file,
+			Message: err.Error(),
+		},
+	}
+}
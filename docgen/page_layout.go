@@ -0,0 +1,127 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docgen
+
+import (
+	"path"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+const indexPage = "index.md"
+
+// pageLayout determines the paths of the documentation pages.
+//
+// Every composite and interface declaration gets a dedicated page, in a directory named
+// after the declaration, nested in the directory of the enclosing declaration,
+// e.g. `NFT/Collection/index.md`. The entry page of the program is `index.md`.
+//
+// If the program has a sole contract (or contract interface) declaration,
+// then the page of the contract is the entry page, and the pages of the nested
+// declarations are placed directly in the output directory, e.g. `Collection/index.md`.
+//
+// All pages are named `index.md`, and declaration names are unique within the enclosing declaration,
+// so the paths of different declarations never collide, regardless of the names of the declarations.
+//
+type pageLayout struct {
+	soleDeclarationName string
+}
+
+func newPageLayout(program *ast.Program) pageLayout {
+	var soleDeclaration ast.Declaration
+	if declaration := program.SoleContractDeclaration(); declaration != nil {
+		soleDeclaration = declaration
+	} else if declaration := program.SoleContractInterfaceDeclaration(); declaration != nil {
+		soleDeclaration = declaration
+	}
+
+	if soleDeclaration == nil {
+		return pageLayout{}
+	}
+
+	return pageLayout{
+		soleDeclarationName: soleDeclaration.DeclarationIdentifier().Identifier,
+	}
+}
+
+// hasEntryPage returns true if a separate entry page must be generated for the program.
+//
+func (l pageLayout) hasEntryPage() bool {
+	return len(l.soleDeclarationName) == 0
+}
+
+// pagePath returns the path of the page of the declaration with the given type path,
+// relative to the output directory. The empty type path denotes the entry page.
+//
+func (l pageLayout) pagePath(typePath []string) string {
+	if !l.hasEntryPage() &&
+		len(typePath) > 0 &&
+		typePath[0] == l.soleDeclarationName {
+
+		typePath = typePath[1:]
+	}
+
+	elements := make([]string, 0, len(typePath)+1)
+	elements = append(elements, typePath...)
+	elements = append(elements, indexPage)
+
+	return path.Join(elements...)
+}
+
+// relativeLink returns the link to the target page, relative to the given source page.
+// Both paths must be relative to the output directory.
+//
+func relativeLink(sourcePage string, targetPage string) string {
+	sourceDirs := splitDir(path.Dir(sourcePage))
+	targetDirs := splitDir(path.Dir(targetPage))
+
+	// Skip the common directories
+	shared := 0
+	for shared < len(sourceDirs) &&
+		shared < len(targetDirs) &&
+		sourceDirs[shared] == targetDirs[shared] {
+
+		shared++
+	}
+
+	var elements []string
+	for range sourceDirs[shared:] {
+		elements = append(elements, "..")
+	}
+	elements = append(elements, targetDirs[shared:]...)
+	elements = append(elements, path.Base(targetPage))
+
+	return strings.Join(elements, "/")
+}
+
+func splitDir(dir string) []string {
+	if dir == "." || len(dir) == 0 {
+		return nil
+	}
+	return strings.Split(dir, "/")
+}
+
+// PageLink is a link to a documentation page, relative to the current page.
+// The name is empty for the entry page of a program with multiple declarations.
+//
+type PageLink struct {
+	Name string
+	Link string
+}
@@ -0,0 +1,55 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package templates
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirectoryTemplateProvider is a provider for template files stored in a directory.
+// Templates missing in the directory are taken from the fallback provider, if any.
+//
+type DirectoryTemplateProvider struct {
+	dir      string
+	fallback TemplateProvider
+}
+
+var _ TemplateProvider = DirectoryTemplateProvider{}
+
+func NewDirectoryTemplateProvider(dir string, fallback TemplateProvider) DirectoryTemplateProvider {
+	return DirectoryTemplateProvider{
+		dir:      dir,
+		fallback: fallback,
+	}
+}
+
+func (t DirectoryTemplateProvider) Get(templateName string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(t.dir, templateName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && t.fallback != nil {
+			return t.fallback.Get(templateName)
+		}
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,53 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package templates
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// MapTemplateProvider is a provider for templates held in memory, keyed by template name.
+// Templates missing in the map are taken from the fallback provider, if any.
+//
+type MapTemplateProvider struct {
+	templates map[string]string
+	fallback  TemplateProvider
+}
+
+var _ TemplateProvider = MapTemplateProvider{}
+
+func NewMapTemplateProvider(templates map[string]string, fallback TemplateProvider) MapTemplateProvider {
+	return MapTemplateProvider{
+		templates: templates,
+		fallback:  fallback,
+	}
+}
+
+func (t MapTemplateProvider) Get(templateName string) (string, error) {
+	content, ok := t.templates[templateName]
+	if !ok {
+		if t.fallback != nil {
+			return t.fallback.Get(templateName)
+		}
+		return "", fmt.Errorf("template '%s': %w", templateName, fs.ErrNotExist)
+	}
+
+	return content, nil
+}
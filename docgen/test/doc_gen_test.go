@@ -29,6 +29,7 @@ import (
 	"github.com/onflow/cadence/runtime/parser"
 
 	"github.com/onflow/cadence-tools/docgen"
+	"github.com/onflow/cadence-tools/docgen/templates"
 )
 
 // This is a convenient method to generate the doc files given a cadence file.
@@ -41,12 +42,14 @@ func TestDocGen(t *testing.T) {
 	content, err := os.ReadFile(path.Join("samples", "sample2.cdc"))
 	require.NoError(t, err)
 
-	err = os.MkdirAll("outputs", os.ModePerm)
+	outputDir := path.Join("outputs", "sample2")
+
+	err = os.MkdirAll(outputDir, os.ModePerm)
 	require.NoError(t, err)
 
 	docGen := docgen.NewDocGenerator()
 
-	err = docGen.Generate(string(content), "outputs")
+	err = docGen.Generate(string(content), outputDir)
 	require.NoError(t, err)
 }
 
@@ -62,7 +65,7 @@ func TestDocGenForMultiDeclarationFile(t *testing.T) {
 	require.Len(t, docFiles, 6)
 
 	for fileName, fileContent := range docFiles {
-		expectedContent, err := os.ReadFile(path.Join("outputs", fileName))
+		expectedContent, err := os.ReadFile(path.Join("outputs", "sample1", fileName))
 		require.NoError(t, err)
 		assert.Equal(t, string(expectedContent), string(fileContent))
 	}
@@ -81,7 +84,7 @@ func TestDocGenForSingleContractFile(t *testing.T) {
 	require.Len(t, docFiles, 5)
 
 	for fileName, fileContent := range docFiles {
-		expectedContent, err := os.ReadFile(path.Join("outputs", fileName))
+		expectedContent, err := os.ReadFile(path.Join("outputs", "sample2", fileName))
 		require.NoError(t, err)
 		assert.Equal(t, string(expectedContent), string(fileContent))
 	}
@@ -132,3 +135,771 @@ func TestFunctionDocFormatting(t *testing.T) {
 
 	assert.Equal(t, string(expectedContent), string(docFiles["index.md"]))
 }
+
+func TestDocGenJSONModel(t *testing.T) {
+
+	t.Parallel()
+
+	content, err := os.ReadFile(path.Join("samples", "sample1.cdc"))
+	require.NoError(t, err)
+
+	outputDir := t.TempDir()
+
+	docGen := docgen.NewDocGenerator()
+
+	err = docGen.GenerateJSON(string(content), outputDir)
+	require.NoError(t, err)
+
+	actualContent, err := os.ReadFile(path.Join(outputDir, "docs.json"))
+	require.NoError(t, err)
+
+	expectedContent, err := os.ReadFile(path.Join("outputs", "sample1.json"))
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expectedContent), string(actualContent))
+}
+
+func TestDocGenModel(t *testing.T) {
+
+	t.Parallel()
+
+	code := `
+        /// A resource.
+        pub resource R: I {
+
+            /// The balance.
+            pub let balance: UFix64
+
+            /// Withdraws tokens.
+            ///
+            /// @param amount: The amount to withdraw
+            /// @return The withdrawn vault
+            pub fun withdraw(amount: UFix64): @R {
+                return <- create R()
+            }
+        }
+    `
+
+	docGen := docgen.NewDocGenerator()
+
+	documentation, err := docGen.GenerateModel(code)
+	require.NoError(t, err)
+
+	assert.Equal(t, docgen.ModelVersion, documentation.Version)
+	require.Len(t, documentation.Declarations, 1)
+
+	resource := documentation.Declarations[0]
+	assert.Equal(t, "resource", resource.Kind)
+	assert.Equal(t, "pub", resource.Access)
+	assert.Equal(t, "R", resource.Name)
+	assert.Equal(t, []string{"I"}, resource.Conformances)
+	assert.Equal(t, "A resource.", resource.Doc.Summary)
+	assert.Equal(t, 3, resource.Range.Start.Line)
+	require.Len(t, resource.Members, 2)
+
+	field := resource.Members[0]
+	assert.Equal(t, "field", field.Kind)
+	assert.Equal(t, "constant", field.VariableKind)
+	assert.Equal(t, "UFix64", field.Type)
+
+	function := resource.Members[1]
+	assert.Equal(t, "function", function.Kind)
+	assert.Equal(t, "@R", function.ReturnType)
+	assert.Equal(t, "Withdraws tokens.", function.Doc.Summary)
+	assert.Equal(t, "The withdrawn vault", function.Doc.Returns)
+	assert.Equal(
+		t,
+		[]*docgen.ParameterDoc{
+			{
+				Name: "amount",
+				Type: "UFix64",
+				Doc:  "The amount to withdraw",
+			},
+		},
+		function.Parameters,
+	)
+}
+
+func TestDocGenTags(t *testing.T) {
+
+	t.Parallel()
+
+	content, err := os.ReadFile(path.Join("samples", "sample4.cdc"))
+	require.NoError(t, err)
+
+	docGen := docgen.NewDocGenerator()
+
+	docFiles, err := docGen.GenerateInMemory(string(content))
+	require.NoError(t, err)
+
+	require.Len(t, docFiles, 2)
+
+	for fileName, fileContent := range docFiles {
+		expectedContent, err := os.ReadFile(path.Join("outputs", "sample4", fileName))
+		require.NoError(t, err)
+		assert.Equal(t, string(expectedContent), string(fileContent))
+	}
+}
+
+func TestDocGenModelTags(t *testing.T) {
+
+	t.Parallel()
+
+	content, err := os.ReadFile(path.Join("samples", "sample4.cdc"))
+	require.NoError(t, err)
+
+	docGen := docgen.NewDocGenerator()
+
+	documentation, err := docGen.GenerateModel(string(content))
+	require.NoError(t, err)
+
+	require.Len(t, documentation.Declarations, 2)
+
+	vault := documentation.Declarations[0]
+	assert.Equal(t, "v1.1", vault.Doc.Since)
+	assert.Equal(t, []string{"withdraw"}, vault.Doc.See)
+
+	withdraw := vault.Members[1]
+	assert.Equal(t, "withdraw", withdraw.Name)
+	assert.Equal(
+		t,
+		&docgen.DocComment{
+			Summary: "Withdraws tokens from the vault.",
+			Params: []docgen.DocParam{
+				{
+					Name:        "amount",
+					Description: "The amount to withdraw",
+				},
+			},
+			Returns: "The vault with the withdrawn tokens",
+			Examples: []docgen.DocExample{
+				{
+					Title: "Withdraw all tokens",
+					Code: "let vault <- vault.withdraw(amount: vault.balance)\n" +
+						"if vault.balance == 0.0 {\n" +
+						"    log(\"empty\")\n" +
+						"}",
+				},
+			},
+			See: []string{"Vault.take", "Unknown"},
+			Emits: []docgen.DocEvent{
+				{
+					Name:        "TokensWithdrawn",
+					Description: "When the tokens are withdrawn",
+				},
+			},
+			Panics: []string{"If the balance is insufficient"},
+		},
+		withdraw.Doc,
+	)
+
+	take := vault.Members[2]
+	assert.True(t, take.Doc.Deprecated)
+	assert.Equal(t, "Use `withdraw` instead.", take.Doc.DeprecationNote)
+}
+
+func TestDocGenTemplateOverrides(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("override", func(t *testing.T) {
+
+		t.Parallel()
+
+		templatesDir := t.TempDir()
+
+		err := os.WriteFile(
+			path.Join(templatesDir, "function-template"),
+			[]byte(`{{define "function"}}
+#### Function {{.DeclarationIdentifier}}
+{{end -}}`),
+			0644,
+		)
+		require.NoError(t, err)
+
+		templateProvider := templates.NewDirectoryTemplateProvider(
+			templatesDir,
+			templates.NewMarkdownTemplateProvider(),
+		)
+
+		err = docgen.ValidateTemplates(templateProvider)
+		require.NoError(t, err)
+
+		docGen := docgen.NewDocGenerator(docgen.WithTemplateProvider(templateProvider))
+
+		docFiles, err := docGen.GenerateInMemory(`
+            /// This is foo.
+            fun foo() {}
+        `)
+		require.NoError(t, err)
+
+		assert.Equal(
+			t,
+			"## Functions\n\n#### Function foo\n\n---\n",
+			string(docFiles["index.md"]),
+		)
+	})
+
+	t.Run("missing and invalid", func(t *testing.T) {
+
+		t.Parallel()
+
+		templatesDir := t.TempDir()
+
+		err := os.WriteFile(
+			path.Join(templatesDir, "function-template"),
+			[]byte(`{{define "function"}}{{unknownFunction .}}{{end}}`),
+			0644,
+		)
+		require.NoError(t, err)
+
+		// No fallback
+		templateProvider := templates.NewDirectoryTemplateProvider(templatesDir, nil)
+
+		err = docgen.ValidateTemplates(templateProvider)
+		require.Error(t, err)
+
+		var validationErr *docgen.TemplateValidationError
+		require.ErrorAs(t, err, &validationErr)
+
+		assert.Len(t, validationErr.Missing, 11)
+		assert.NotContains(t, validationErr.Missing, "function-template")
+
+		require.Len(t, validationErr.Invalid, 1)
+		assert.Equal(t, "function-template", validationErr.Invalid[0].Template)
+	})
+}
+
+func TestDocGenCoverageReport(t *testing.T) {
+
+	t.Parallel()
+
+	code := `
+        /// A contract.
+        pub contract C {
+
+            /// A documented field.
+            pub let a: Int
+
+            pub let b: Int
+
+            access(contract) let c: Int
+
+            /// Emitted on transfer.
+            /// @param from: The sender
+            pub event Transfer(from: Address, to: Address)
+
+            /// Transfers tokens.
+            /// @param amount: The amount
+            /// @param receiver: The receiver
+            pub fun transfer(amount: UFix64, recipient: Address) {}
+
+            priv fun undocumented(x: Int) {}
+
+            init() {
+                self.a = 1
+                self.b = 2
+                self.c = 3
+            }
+        }
+    `
+
+	docGen := docgen.NewDocGenerator()
+
+	report, err := docGen.GenerateCoverageReport(code)
+	require.NoError(t, err)
+
+	// Declarations: C, a, b, Transfer, transfer
+	// Parameters: from, to, amount, recipient
+	assert.Equal(t, 9, report.Total)
+	assert.Equal(t, 6, report.Documented)
+	assert.InDelta(t, 66.67, report.Coverage(), 0.01)
+
+	type issue struct {
+		kind        docgen.DocIssueKind
+		declaration string
+		parameter   string
+	}
+
+	var issues []issue
+	for _, docIssue := range report.Issues {
+		issues = append(issues, issue{
+			kind:        docIssue.Kind,
+			declaration: docIssue.Declaration,
+			parameter:   docIssue.Parameter,
+		})
+	}
+
+	assert.Equal(
+		t,
+		[]issue{
+			{
+				kind:        docgen.DocIssueKindMissingDoc,
+				declaration: "C.b",
+			},
+			{
+				kind:        docgen.DocIssueKindMissingParam,
+				declaration: "C.Transfer",
+				parameter:   "to",
+			},
+			{
+				kind:        docgen.DocIssueKindMissingParam,
+				declaration: "C.transfer",
+				parameter:   "recipient",
+			},
+			{
+				kind:        docgen.DocIssueKindStaleParam,
+				declaration: "C.transfer",
+				parameter:   "receiver",
+			},
+		},
+		issues,
+	)
+
+	assert.Equal(
+		t,
+		"documented parameter `receiver` does not exist in function `C.transfer`",
+		report.Issues[3].Message(),
+	)
+}
+
+func TestDocGenCoverageReportNested(t *testing.T) {
+
+	t.Parallel()
+
+	code := `
+        /// A contract.
+        pub contract C {
+
+            /// A resource.
+            pub resource R {
+
+                /// Creates a resource.
+                /// @param id: The ID
+                /// @param owner: The owner
+                init(id: UInt64, name: String) {}
+            }
+
+            priv struct S {
+
+                pub let a: Int
+
+                pub fun b(x: Int) {}
+
+                init(a: Int) {
+                    self.a = a
+                }
+            }
+        }
+    `
+
+	docGen := docgen.NewDocGenerator()
+
+	report, err := docGen.GenerateCoverageReport(code)
+	require.NoError(t, err)
+
+	// Declarations: C, R, R.init
+	// Parameters: id, name
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 4, report.Documented)
+
+	require.Len(t, report.Issues, 2)
+
+	assert.Equal(t, docgen.DocIssueKindMissingParam, report.Issues[0].Kind)
+	assert.Equal(t, "C.R.init", report.Issues[0].Declaration)
+	assert.Equal(t, "name", report.Issues[0].Parameter)
+
+	staleIssue := report.Issues[1]
+	assert.Equal(t, docgen.DocIssueKindStaleParam, staleIssue.Kind)
+	assert.Equal(t, "initializer", staleIssue.DeclarationKind)
+	assert.Equal(t, "C.R.init", staleIssue.Declaration)
+	assert.Equal(t, "owner", staleIssue.Parameter)
+
+	// The issue is reported at the position of the `@param owner` tag
+	assert.Equal(t, 10, staleIssue.Position.Line)
+	assert.Equal(t, 20, staleIssue.Position.Column)
+	assert.Equal(t, "@param owner", code[staleIssue.Position.Offset:staleIssue.Position.Offset+12])
+}
+
+func TestDocGenPageLayout(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("no collision with entry page", func(t *testing.T) {
+
+		t.Parallel()
+
+		code := `
+            pub struct index {}
+
+            pub fun foo() {}
+        `
+
+		docGen := docgen.NewDocGenerator()
+
+		docFiles, err := docGen.GenerateInMemory(code)
+		require.NoError(t, err)
+
+		require.Len(t, docFiles, 2)
+		require.Contains(t, docFiles, "index.md")
+		require.Contains(t, docFiles, "index/index.md")
+
+		assert.Contains(t, string(docFiles["index.md"]), "[More...](index/index.md)")
+		assert.Contains(t, string(docFiles["index/index.md"]), "[Index](../index.md) / `index`")
+	})
+
+	t.Run("nested directories per contract", func(t *testing.T) {
+
+		t.Parallel()
+
+		code := `
+            pub contract A {
+                pub resource R {}
+            }
+
+            pub contract B {
+                pub resource R {
+                    pub struct S {}
+                }
+            }
+        `
+
+		docGen := docgen.NewDocGenerator()
+
+		docFiles, err := docGen.GenerateInMemory(code)
+		require.NoError(t, err)
+
+		fileNames := make([]string, 0, len(docFiles))
+		for fileName := range docFiles {
+			fileNames = append(fileNames, fileName)
+		}
+
+		assert.ElementsMatch(
+			t,
+			[]string{
+				"index.md",
+				"A/index.md",
+				"A/R/index.md",
+				"B/index.md",
+				"B/R/index.md",
+				"B/R/S/index.md",
+			},
+			fileNames,
+		)
+
+		assert.Contains(t, string(docFiles["B/index.md"]), "[More...](R/index.md)")
+		assert.Contains(
+			t,
+			string(docFiles["B/R/S/index.md"]),
+			"[Index](../../../index.md) / [`B`](../../index.md) / [`R`](../index.md) / `S`",
+		)
+	})
+}
+
+func TestDocGenAPIDiff(t *testing.T) {
+
+	t.Parallel()
+
+	oldCode := `
+        pub contract C {
+
+            pub let a: Int
+            pub let b: String
+            access(contract) let c: Int
+
+            pub enum Color: UInt8 {
+                pub case red
+                pub case green
+                pub case blue
+            }
+
+            pub struct S {}
+
+            pub resource interface I {}
+
+            pub resource R: I {}
+
+            pub fun foo(x: Int): Int {
+                return x
+            }
+
+            pub fun bar() {}
+
+            init() {
+                self.a = 1
+                self.b = ""
+                self.c = 3
+            }
+        }
+    `
+
+	newCode := `
+        pub contract C {
+
+            pub let a: Int
+            pub let b: Int
+            access(contract) let d: Int
+
+            pub enum Color: UInt8 {
+                pub case green
+                pub case red
+            }
+
+            pub resource interface I {}
+
+            pub resource R {}
+
+            pub fun foo(x: Int, y: Int): Int {
+                return x + y
+            }
+
+            pub fun baz() {}
+
+            init() {
+                self.a = 1
+                self.b = 2
+                self.d = 3
+            }
+        }
+    `
+
+	docGen := docgen.NewDocGenerator()
+
+	diff, err := docGen.GenerateAPIDiff(oldCode, newCode)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		[]*docgen.APIChange{
+			{
+				Kind:            docgen.APIChangeKindChanged,
+				DeclarationKind: "field",
+				Declaration:     "C.b",
+				Details:         []string{"type changed from `String` to `Int`"},
+				Breaking:        true,
+				UpdateErrors:    []string{"the type of fields cannot be changed"},
+			},
+			{
+				Kind:            docgen.APIChangeKindChanged,
+				DeclarationKind: "enum",
+				Declaration:     "C.Color",
+				Details:         []string{"enum cases reordered"},
+				Breaking:        true,
+				UpdateErrors:    []string{"enum cases cannot be reordered"},
+			},
+			{
+				Kind:            docgen.APIChangeKindRemoved,
+				DeclarationKind: "enum case",
+				Declaration:     "C.Color.blue",
+				Breaking:        true,
+				UpdateErrors:    []string{"enum cases cannot be removed"},
+			},
+			{
+				Kind:            docgen.APIChangeKindRemoved,
+				DeclarationKind: "structure",
+				Declaration:     "C.S",
+				Breaking:        true,
+				UpdateErrors:    []string{"type declarations cannot be removed"},
+			},
+			{
+				Kind:            docgen.APIChangeKindChanged,
+				DeclarationKind: "resource",
+				Declaration:     "C.R",
+				Details:         []string{"conformance `I` removed"},
+				Breaking:        true,
+				UpdateErrors:    []string{"conformances cannot be removed"},
+			},
+			{
+				Kind:            docgen.APIChangeKindChanged,
+				DeclarationKind: "function",
+				Declaration:     "C.foo",
+				Details:         []string{"parameters changed from `(x: Int)` to `(x: Int, y: Int)`"},
+				Breaking:        true,
+			},
+			{
+				Kind:            docgen.APIChangeKindRemoved,
+				DeclarationKind: "function",
+				Declaration:     "C.bar",
+				Breaking:        true,
+			},
+			{
+				Kind:            docgen.APIChangeKindAdded,
+				DeclarationKind: "field",
+				Declaration:     "C.d",
+				UpdateErrors:    []string{"fields cannot be added"},
+			},
+			{
+				Kind:            docgen.APIChangeKindAdded,
+				DeclarationKind: "function",
+				Declaration:     "C.baz",
+			},
+		},
+		diff.Changes,
+	)
+
+	assert.True(t, diff.HasBreakingChanges())
+	assert.True(t, diff.HasUpdateErrors())
+
+	changelog := diff.Changelog()
+	assert.Contains(t, changelog, "## Added\n- field `C.d`\n  - **Invalid contract update**: fields cannot be added\n")
+	assert.Contains(t, changelog, "## Removed\n- enum case `C.Color.blue` **(breaking)**\n")
+	assert.Contains(t, changelog, "## Changed\n- field `C.b` **(breaking)**\n  - type changed from `String` to `Int`\n")
+
+	// Removing a private field is neither breaking, nor an invalid update
+
+	diff, err = docGen.GenerateAPIDiff(
+		`pub contract C { access(self) let x: Int; init() { self.x = 1 } }`,
+		`pub contract C { init() {} }`,
+	)
+	require.NoError(t, err)
+
+	assert.Empty(t, diff.Changes)
+	assert.Equal(t, "No changes\n", diff.Changelog())
+
+	// Changing the public members of a private declaration is not breaking
+
+	diff, err = docGen.GenerateAPIDiff(
+		`pub contract C { priv struct S { pub fun foo(x: Int) {}; pub fun bar() {} } }`,
+		`pub contract C { priv struct S { pub fun foo(x: String) {}; pub fun baz() {} } }`,
+	)
+	require.NoError(t, err)
+
+	assert.False(t, diff.HasBreakingChanges())
+	assert.Empty(t, diff.Changes)
+}
+
+func TestDocGenCheckPrograms(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+
+		errs := docgen.CheckPrograms(map[string]string{
+			"Foo.cdc": `
+                pub contract Foo {
+                    pub struct S {}
+                }
+            `,
+			"Bar.cdc": `
+                import Foo from "./Foo.cdc"
+
+                pub contract Bar {
+                    pub fun make(): Foo.S {
+                        return Foo.S()
+                    }
+                }
+            `,
+		})
+
+		assert.Empty(t, errs)
+	})
+
+	t.Run("address imports", func(t *testing.T) {
+		t.Parallel()
+
+		errs := docgen.CheckPrograms(map[string]string{
+			"Foo.cdc": `
+                import FungibleToken from 0xee82856bf20e2aa6
+
+                pub contract Foo {
+                    pub let x: Int
+                    init() {
+                        self.x = "not checked"
+                    }
+                }
+            `,
+			"Bar.cdc": `
+                import Foo from "./Foo.cdc"
+
+                pub contract Bar {}
+            `,
+			"Baz.cdc": `
+                pub contract Baz {
+                    pub let x: Int
+                    init() {
+                        self.x = "hello"
+                    }
+                }
+            `,
+		})
+
+		// Programs importing addresses, also indirectly, are not checked
+
+		require.Len(t, errs, 1)
+		assert.Equal(t, "Baz.cdc", errs[0].File)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		errs := docgen.CheckPrograms(map[string]string{
+			"Foo.cdc": `
+                pub contract Foo {
+                    pub let x: Int
+                    init() {
+                        self.x = "hello"
+                    }
+                }
+            `,
+			"Bar.cdc": `
+                import Foo from "Foo.cdc"
+                import Baz from "Baz.cdc"
+
+                pub contract Bar {}
+            `,
+			"Qux.cdc": `pub contract Qux {`,
+		})
+
+		require.Len(t, errs, 4)
+
+		// The imported program is checked first
+
+		assert.Equal(t, "Foo.cdc", errs[0].File)
+		assert.Contains(t, errs[0].Message, "mismatched types")
+		require.NotNil(t, errs[0].Range)
+		assert.Equal(t, 5, errs[0].Range.Start.Line)
+		assert.Equal(t, 33, errs[0].Range.Start.Column)
+
+		assert.Equal(t, "Bar.cdc", errs[1].File)
+		assert.Equal(t, "checking of imported program `Foo.cdc` failed", errs[1].Message)
+		require.NotNil(t, errs[1].Range)
+		assert.Equal(t, 2, errs[1].Range.Start.Line)
+
+		assert.Equal(t, "Bar.cdc", errs[2].File)
+		assert.Contains(t, errs[2].Message, "cannot import Baz.cdc: no such file")
+
+		assert.Equal(t, "Qux.cdc", errs[3].File)
+		require.NotNil(t, errs[3].Range)
+		assert.Equal(t, 1, errs[3].Range.Start.Line)
+	})
+}
+
+func TestDocGenMapTemplateProvider(t *testing.T) {
+
+	t.Parallel()
+
+	templateProvider := templates.NewMapTemplateProvider(
+		map[string]string{
+			"function-template": `{{define "function"}}#### Function {{.DeclarationIdentifier}}
+{{end -}}`,
+		},
+		templates.NewMarkdownTemplateProvider(),
+	)
+
+	require.NoError(t, docgen.ValidateTemplates(templateProvider))
+
+	docGen := docgen.NewDocGenerator(docgen.WithTemplateProvider(templateProvider))
+
+	docs, err := docGen.GenerateInMemory(`pub fun foo() {}`)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(docs["index.md"]), "#### Function foo\n")
+
+	// Without a fallback, missing templates are reported
+
+	err = docgen.ValidateTemplates(templates.NewMapTemplateProvider(nil, nil))
+
+	var validationErr *docgen.TemplateValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.NotEmpty(t, validationErr.Missing)
+}
@@ -24,10 +24,11 @@ import (
 	"log"
 
 	"encoding/json"
-	"runtime/debug"
+	"sort"
 	"syscall/js"
 
 	"github.com/onflow/cadence-tools/docgen"
+	"github.com/onflow/cadence-tools/docgen/templates"
 )
 
 const globalFunctionNamePrefix = "CADENCE_DOCGEN"
@@ -53,49 +54,127 @@ func main() {
 	<-done
 }
 
+// defaultFileName is the name of the file, if the code of a single program is given.
+const defaultFileName = "main.cdc"
+
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
 type documentations map[string]string
 
+// options are the options of the generation, given as the optional second argument.
+//
+// Format is either "markdown" (the default) or "json", i.e. the JSON documentation model.
+// Templates are the templates overriding the default Markdown templates, keyed by template name.
+// If Checked is true, the programs are type-checked, and the files with errors are not documented.
+// Programs importing addresses cannot be type-checked, and are documented without being checked.
+//
+type options struct {
+	Format    string
+	Templates map[string]string
+	Checked   bool
+}
+
+type fileResult struct {
+	Docs  documentations        `json:"docs,omitempty"`
+	Model *docgen.Documentation `json:"model,omitempty"`
+}
+
 type result struct {
-	Docs  documentations `json:"docs"`
-	Error error          `json:"error,omitempty"`
+	Files  map[string]fileResult  `json:"files"`
+	Errors []*docgen.ProgramError `json:"errors,omitempty"`
 }
 
+// generateDocs generates the documentation for the programs given as the first argument,
+// either a map of file names to code, or the code of a single program.
+// The options can be given as the optional second argument.
+//
 func generateDocs(args []js.Value) string {
 
-	var res result
+	res := result{
+		Files: map[string]fileResult{},
+	}
+
+	addError := func(file string, err error) {
+		res.Errors = append(res.Errors, docgen.NewProgramErrors(file, err)...)
+	}
 
 	func() {
 		programArgsCount := len(args)
 		if programArgsCount < 1 {
-			res.Error = fmt.Errorf("not enough arguments: expected 1, found %d", programArgsCount)
+			addError("", fmt.Errorf("not enough arguments: expected 1 or 2, found %d", programArgsCount))
 			return
 		}
 
-		if programArgsCount > 1 {
-			res.Error = fmt.Errorf("too many arguments: expected 1, found %d", programArgsCount)
+		if programArgsCount > 2 {
+			addError("", fmt.Errorf("too many arguments: expected 1 or 2, found %d", programArgsCount))
 			return
 		}
 
-		code := args[0].String()
-
 		defer func() {
 			if r := recover(); r != nil {
-				res.Error = fmt.Errorf("%s", debug.Stack())
+				addError("", fmt.Errorf("internal error: %v", r))
 			}
 		}()
 
-		docGen := docgen.NewDocGenerator()
-		docs, err := docGen.GenerateInMemory(code)
-
+		sources, err := parseSources(args[0])
 		if err != nil {
-			res.Error = err
+			addError("", err)
 			return
 		}
 
-		// Convert the byte content to string before sending as json.
-		res.Docs = documentations{}
-		for fileName, content := range docs {
-			res.Docs[fileName] = string(content)
+		opts := options{
+			Format: formatMarkdown,
+		}
+		if programArgsCount > 1 {
+			opts, err = parseOptions(args[1])
+			if err != nil {
+				addError("", err)
+				return
+			}
+		}
+
+		var genOptions []docgen.Option
+
+		if len(opts.Templates) > 0 {
+			templateProvider := templates.NewMapTemplateProvider(
+				opts.Templates,
+				templates.NewMarkdownTemplateProvider(),
+			)
+
+			err = docgen.ValidateTemplates(templateProvider)
+			if err != nil {
+				addError("", err)
+				return
+			}
+
+			genOptions = append(genOptions, docgen.WithTemplateProvider(templateProvider))
+		}
+
+		invalidFiles := map[string]struct{}{}
+		if opts.Checked {
+			for _, programErr := range docgen.CheckPrograms(sources) {
+				res.Errors = append(res.Errors, programErr)
+				invalidFiles[programErr.File] = struct{}{}
+			}
+		}
+
+		docGen := docgen.NewDocGenerator(genOptions...)
+
+		for _, file := range sortedFileNames(sources) {
+			if _, ok := invalidFiles[file]; ok {
+				continue
+			}
+
+			fileRes, err := generateFileDocs(docGen, sources[file], opts.Format)
+			if err != nil {
+				addError(file, err)
+				continue
+			}
+
+			res.Files[file] = fileRes
 		}
 	}()
 
@@ -106,3 +185,141 @@ func generateDocs(args []js.Value) string {
 
 	return string(serialized)
 }
+
+func generateFileDocs(docGen *docgen.DocGenerator, code string, format string) (fileResult, error) {
+	switch format {
+	case formatMarkdown:
+		docs, err := docGen.GenerateInMemory(code)
+		if err != nil {
+			return fileResult{}, err
+		}
+
+		// Convert the byte content to string before sending as json.
+		fileDocs := documentations{}
+		for fileName, content := range docs {
+			fileDocs[fileName] = string(content)
+		}
+
+		return fileResult{
+			Docs: fileDocs,
+		}, nil
+
+	case formatJSON:
+		model, err := docGen.GenerateModel(code)
+		if err != nil {
+			return fileResult{}, err
+		}
+
+		return fileResult{
+			Model: model,
+		}, nil
+
+	default:
+		return fileResult{}, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// parseSources parses the programs argument, which is either
+// an object mapping file names to code, or the code of a single program.
+//
+func parseSources(value js.Value) (map[string]string, error) {
+	switch value.Type() {
+	case js.TypeString:
+		return map[string]string{
+			defaultFileName: value.String(),
+		}, nil
+
+	case js.TypeObject:
+		return stringMap(value, "programs")
+
+	default:
+		return nil, fmt.Errorf("invalid programs: expected an object or a string, found %s", value.Type())
+	}
+}
+
+func parseOptions(value js.Value) (options, error) {
+	opts := options{
+		Format: formatMarkdown,
+	}
+
+	switch value.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		return opts, nil
+	case js.TypeObject:
+		break
+	default:
+		return opts, fmt.Errorf("invalid options: expected an object, found %s", value.Type())
+	}
+
+	format := value.Get("format")
+	switch format.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		break
+	case js.TypeString:
+		opts.Format = format.String()
+	default:
+		return opts, fmt.Errorf("invalid format option: expected a string, found %s", format.Type())
+	}
+
+	templatesValue := value.Get("templates")
+	switch templatesValue.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		break
+	default:
+		var err error
+		opts.Templates, err = stringMap(templatesValue, "templates option")
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	checked := value.Get("checked")
+	switch checked.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		break
+	case js.TypeBoolean:
+		opts.Checked = checked.Bool()
+	default:
+		return opts, fmt.Errorf("invalid checked option: expected a boolean, found %s", checked.Type())
+	}
+
+	return opts, nil
+}
+
+// stringMap converts a JavaScript object with string values to a map.
+//
+func stringMap(value js.Value, description string) (map[string]string, error) {
+	if value.Type() != js.TypeObject {
+		return nil, fmt.Errorf("invalid %s: expected an object, found %s", description, value.Type())
+	}
+
+	keys := js.Global().Get("Object").Call("keys", value)
+	length := keys.Length()
+
+	result := make(map[string]string, length)
+
+	for i := 0; i < length; i++ {
+		key := keys.Index(i).String()
+		element := value.Get(key)
+		if element.Type() != js.TypeString {
+			return nil, fmt.Errorf(
+				"invalid %s: expected a string for '%s', found %s",
+				description,
+				key,
+				element.Type(),
+			)
+		}
+		result[key] = element.String()
+	}
+
+	return result, nil
+}
+
+func sortedFileNames(sources map[string]string) []string {
+	fileNames := make([]string, 0, len(sources))
+	for fileName := range sources {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
@@ -0,0 +1,293 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/stdlib"
+	sdk "github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// AccountKeyOptions are the options of a key of an account,
+// created using EmulatorBackend.CreateAccountWithKeys.
+type AccountKeyOptions struct {
+	// Weight is the weight of the key, between 0 and sdk.AccountKeyWeightThreshold.
+	Weight             int
+	SignatureAlgorithm crypto.SignatureAlgorithm
+	HashAlgorithm      crypto.HashAlgorithm
+}
+
+// TransactionOptions are the options of a transaction,
+// set using EmulatorBackend.SetNextTransactionOptions.
+// The zero value are the default options.
+type TransactionOptions struct {
+	// Proposer is the account of the proposal key of the transaction.
+	// The service account, if nil.
+	Proposer         *common.Address
+	ProposerKeyIndex int
+
+	// Payer is the account paying for the transaction.
+	// The service account, if nil.
+	Payer *common.Address
+
+	// SigningKeys are the indices of the keys the given accounts sign the transaction with.
+	// Accounts without an entry sign with all of their keys which are not revoked.
+	SigningKeys map[common.Address][]int
+}
+
+// revokedKeysCache are the indices of the revoked keys of the accounts,
+// as of the block with the given ID, and the transactions of the pending block.
+// Must be reset when the pending block is discarded.
+type revokedKeysCache struct {
+	blockID flow.Identifier
+	indices map[common.Address]map[int]bool
+}
+
+// accountKeyID identifies the key of an account.
+type accountKeyID struct {
+	address common.Address
+	index   int
+}
+
+// newAccountKey generates a key with the given options,
+// and returns it together with its signer.
+func newAccountKey(index int, options AccountKeyOptions) (*sdk.AccountKey, crypto.Signer, error) {
+	if options.Weight < 0 || options.Weight > sdk.AccountKeyWeightThreshold {
+		return nil, nil, fmt.Errorf(
+			"invalid weight of key %d: expected weight between 0 and %d, got %d",
+			index,
+			sdk.AccountKeyWeightThreshold,
+			options.Weight,
+		)
+	}
+
+	seed := make([]byte, crypto.MinSeedLength)
+	_, err := rand.Read(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := crypto.GeneratePrivateKey(options.SignatureAlgorithm, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := crypto.NewInMemorySigner(privateKey, options.HashAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountKey := sdk.NewAccountKey().
+		FromPrivateKey(privateKey).
+		SetHashAlgo(options.HashAlgorithm).
+		SetWeight(options.Weight)
+	accountKey.Index = index
+
+	return accountKey, signer, nil
+}
+
+// accountKey returns the stored key of the given account with the given index.
+func (e *EmulatorBackend) accountKey(address common.Address, index int) (keyInfo, error) {
+	for _, key := range e.accountKeys[address] {
+		if key.accountKey.Index == index {
+			return key, nil
+		}
+	}
+
+	return keyInfo{}, fmt.Errorf(
+		"account with address: %s has no key with index %d",
+		address.HexWithPrefix(),
+		index,
+	)
+}
+
+// onChainAccountKey returns the key of the given account with the given index,
+// as it is stored on the blockchain, e.g. including its sequence number.
+func (e *EmulatorBackend) onChainAccountKey(address common.Address, index int) (flow.AccountPublicKey, error) {
+	account, err := e.blockchain.GetAccount(flow.Address(address))
+	if err != nil {
+		return flow.AccountPublicKey{}, err
+	}
+
+	for _, key := range account.Keys {
+		if key.Index == index {
+			return key, nil
+		}
+	}
+
+	return flow.AccountPublicKey{}, fmt.Errorf(
+		"account with address: %s has no key with index %d",
+		address.HexWithPrefix(),
+		index,
+	)
+}
+
+// signingKeys returns the keys the given account signs a transaction with,
+// ordered by key index.
+func (e *EmulatorBackend) signingKeys(
+	address common.Address,
+	options TransactionOptions,
+) ([]keyInfo, error) {
+
+	if indices, ok := options.SigningKeys[address]; ok {
+		keys := make([]keyInfo, 0, len(indices))
+		for _, index := range indices {
+			key, err := e.accountKey(address, index)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+
+	revoked, err := e.revokedKeyIndices(address)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]keyInfo, 0, len(e.accountKeys[address]))
+	for _, key := range e.accountKeys[address] {
+		if revoked[key.accountKey.Index] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf(
+			"account with address: %s has no keys to sign with",
+			address.HexWithPrefix(),
+		)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].accountKey.Index < keys[j].accountKey.Index
+	})
+
+	return keys, nil
+}
+
+// transactionSigningKeys returns the keys the given account signs the given transaction with,
+// ordered by key index. The keys of the proposer always include the proposal key,
+// even if the signing keys of the proposer are restricted to other keys.
+func (e *EmulatorBackend) transactionSigningKeys(
+	tx *sdk.Transaction,
+	address common.Address,
+	options TransactionOptions,
+) ([]keyInfo, error) {
+
+	keys, err := e.signingKeys(address, options)
+	if err != nil || address != common.Address(tx.ProposalKey.Address) {
+		return keys, err
+	}
+
+	for _, key := range keys {
+		if key.accountKey.Index == tx.ProposalKey.KeyIndex {
+			return keys, nil
+		}
+	}
+
+	proposalKey, err := e.accountKey(address, tx.ProposalKey.KeyIndex)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, proposalKey)
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].accountKey.Index < keys[j].accountKey.Index
+	})
+
+	return keys, nil
+}
+
+// revokedKeyIndices returns the indices of the revoked keys of the given account,
+// as of the latest block, and including the keys revoked by the transactions of the pending block.
+// The indices are cached until the next block, as the keys can only be revoked by a transaction,
+// and every signed transaction would otherwise read the accounts of all its signers.
+func (e *EmulatorBackend) revokedKeyIndices(address common.Address) (map[int]bool, error) {
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	blockID := latestBlock.ID()
+	if e.revokedKeys.indices == nil || e.revokedKeys.blockID != blockID {
+		e.revokedKeys = revokedKeysCache{
+			blockID: blockID,
+			indices: map[common.Address]map[int]bool{},
+		}
+	}
+
+	if revoked, ok := e.revokedKeys.indices[address]; ok {
+		return revoked, nil
+	}
+
+	account, err := e.blockchain.GetAccount(flow.Address(address))
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[int]bool, len(account.Keys))
+	for _, key := range account.Keys {
+		if key.Revoked {
+			revoked[key.Index] = true
+		}
+	}
+	e.revokedKeys.indices[address] = revoked
+
+	return revoked, nil
+}
+
+// revokeCachedKeys marks the keys revoked by the given events of an executed transaction
+// as revoked in the cache, as the accounts of the latest block do not include
+// the changes of the transactions of the pending block.
+func (e *EmulatorBackend) revokeCachedKeys(events []sdk.Event) error {
+	for _, event := range events {
+		if event.Type != sdk.EventAccountKeyRemoved {
+			continue
+		}
+
+		var address common.Address
+		var index int
+		for i, field := range event.Value.EventType.Fields {
+			switch field.Identifier {
+			case stdlib.AccountEventAddressParameter.Identifier:
+				address = common.Address(event.Value.Fields[i].(cadence.Address))
+			case stdlib.AccountEventPublicKeyIndexParameter.Identifier:
+				index = event.Value.Fields[i].(cadence.Int).Int()
+			}
+		}
+
+		revoked, err := e.revokedKeyIndices(address)
+		if err != nil {
+			return err
+		}
+		revoked[index] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// The cadence-test command runs the Cadence tests of a Flow project,
+// i.e. the test scripts ending in `_test.cdc` in the given files and directories
+// (by default the current directory). The contracts are resolved using the
+// project's configuration (`flow.json`): Test scripts can import contracts by name,
+// and contracts are deployed to the addresses of their aliases for the network
+// given by the -network flag, by default `testing`, which each contract must have.
+// Paths in test scripts, e.g. of files deployed or read by the tests,
+// are relative to the test script.
+//
+// The command exits with a non-zero status if a test fails,
+// or if the coverage is below the minimum coverage.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/onflow/cadence/runtime"
+
+	"github.com/onflow/cadence-tools/test"
+)
+
+var configFlag = flag.String("config", "flow.json", "path of the project configuration")
+var networkFlag = flag.String("network", "testing", "network of the contract aliases, which are the addresses of the contracts")
+var runFlag = flag.String("run", "", "only run the test functions matching the regular expression")
+var filterFlag = flag.String("filter", "", "only run the test functions matching the glob pattern, e.g. testTransfer*")
+var reporterFlag = flag.String("reporter", "text", "report format: text, junit, json or tap")
+var outputFlag = flag.String("output", "", "write the report to the given file instead of the standard output")
+var seedFlag = flag.Int64("seed", 0, "seed for the random order of the test functions, and for the fuzz inputs")
+var randomnessSeedFlag = flag.Int64("randomness-seed", 0, "seed for the randomness of the blockchain, e.g. of revertibleRandom")
+var parallelFlag = flag.Int("parallel", 1, "maximum number of test scripts run concurrently")
+var isolationFlag = flag.Bool("isolation", false, "run each test function against the state after setup()")
+var coverFlag = flag.Bool("cover", false, "collect the coverage of the contracts")
+var coverProfileFlag = flag.String("coverprofile", "", "write the coverage report to the given file")
+var coverFormatFlag = flag.String("coverformat", "lcov", "coverage report format: lcov, cobertura or html")
+var minCoverageFlag = flag.Float64("min-coverage", 0, "minimum coverage percentage of the statements")
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(
+			flag.CommandLine.Output(),
+			"Usage: %s [flags] [files or directories]\n\n",
+			filepath.Base(os.Args[0]),
+		)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	project, err := loadProject(*configFlag, *networkFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	scriptPaths, err := discoverTestScripts(paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(scriptPaths) == 0 {
+		fmt.Println("No test scripts found")
+		return
+	}
+
+	reporter, err := test.NewReporter(*reporterFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	runner := test.NewTestRunner().
+		WithContracts(project.contractAddresses).
+		WithRandomSeed(*seedFlag).
+		WithParallelism(*parallelFlag).
+		WithIsolation(*isolationFlag)
+
+	if isFlagSet("randomness-seed") {
+		runner = runner.WithRandomnessSeed(*randomnessSeedFlag)
+	}
+
+	testFilter, err := newTestFilter(*runFlag, *filterFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if testFilter != nil {
+		runner = runner.WithTestFilter(testFilter)
+	}
+
+	var coverageReport *runtime.CoverageReport
+	var coverageExporter test.CoverageExporter
+	if *coverFlag || *coverProfileFlag != "" || *minCoverageFlag > 0 {
+		coverageReport = runtime.NewCoverageReport()
+		coverageReport.WithLocationFilter(project.isContractLocation)
+		runner = runner.WithCoverageReport(coverageReport)
+
+		coverageExporter, err = test.NewCoverageExporter(
+			*coverFormatFlag,
+			runner.CoveragePathMapper(project.contractPaths),
+			readFile,
+		)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	scripts := make([]test.TestScript, 0, len(scriptPaths))
+	for _, scriptPath := range scriptPaths {
+		code, err := readFile(scriptPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		scriptDir := filepath.Dir(scriptPath)
+		scripts = append(scripts, test.TestScript{
+			Path:           scriptPath,
+			Code:           code,
+			ImportResolver: project.importResolver(scriptDir),
+			FileResolver:   fileResolver(scriptDir),
+		})
+	}
+
+	suites := runner.RunScripts(scripts)
+
+	err = writeReport(reporter, suites)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := hasFailures(suites)
+
+	if coverageReport != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Coverage: %.1f%% of statements\n", test.TotalCoverage(coverageReport))
+
+		if *coverProfileFlag != "" {
+			err = writeFile(*coverProfileFlag, func(writer io.Writer) error {
+				return coverageExporter.Export(writer, coverageReport)
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		err = test.CheckCoverageThreshold(coverageReport, *minCoverageFlag)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// isFlagSet returns true if the flag with the given name was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// newTestFilter returns the filter which selects the test functions matching
+// both the given regular expression and glob pattern, if given,
+// or nil if all test functions are selected.
+func newTestFilter(pattern string, globPattern string) (test.TestFilter, error) {
+	var filters []test.TestFilter
+
+	if pattern != "" {
+		filter, err := test.NewRegexpTestFilter(pattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if globPattern != "" {
+		filter, err := test.NewGlobTestFilter(globPattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	return func(testName string) bool {
+		for _, filter := range filters {
+			if !filter(testName) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// writeReport reports the results of the test scripts
+// to the output file, if any, or to the standard output.
+func writeReport(reporter test.Reporter, suites []test.SuiteResult) error {
+	report := func(writer io.Writer) error {
+		return reporter.Report(writer, suites)
+	}
+
+	if *outputFlag == "" {
+		return report(os.Stdout)
+	}
+
+	return writeFile(*outputFlag, report)
+}
+
+func writeFile(path string, write func(writer io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// hasFailures returns true if a test function or a test script failed.
+func hasFailures(suites []test.SuiteResult) bool {
+	for _, suite := range suites {
+		if suite.Error != nil {
+			return true
+		}
+
+		for _, result := range suite.Results {
+			if result.Error != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
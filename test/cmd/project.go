@@ -0,0 +1,245 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/common"
+
+	"github.com/onflow/cadence-tools/test"
+)
+
+const testScriptSuffix = "_test.cdc"
+
+// projectConfig is the part of the configuration of a Flow project (`flow.json`)
+// which is needed to run the tests of the project.
+type projectConfig struct {
+	Contracts map[string]contractConfig `json:"contracts"`
+}
+
+// contractConfig is the configuration of a contract, which is either
+// the path of its source file, or an object with the source and the aliases.
+type contractConfig struct {
+	Source  string            `json:"source"`
+	Aliases map[string]string `json:"aliases"`
+}
+
+func (c *contractConfig) UnmarshalJSON(data []byte) error {
+	var source string
+	if err := json.Unmarshal(data, &source); err == nil {
+		c.Source = source
+		return nil
+	}
+
+	// Avoid the recursion into this function
+	type contractObject contractConfig
+
+	var object contractObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	*c = contractConfig(object)
+	return nil
+}
+
+// project is a Flow project, i.e. its contracts.
+type project struct {
+	// contractPaths are the paths of the source files of the contracts, by name.
+	contractPaths map[string]string
+	// contractAddresses are the addresses of the contracts, by name,
+	// given by their aliases for the network of the tests.
+	contractAddresses map[string]common.Address
+}
+
+// loadProject loads the project configured by the given configuration file.
+// The contracts are mapped to the addresses of their aliases for the given network,
+// so each contract must have an alias for the network.
+// The paths of the contracts are relative to the directory of the configuration file.
+func loadProject(configPath string, network string) (*project, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config projectConfig
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+
+	dir := filepath.Dir(configPath)
+
+	p := &project{
+		contractPaths:     make(map[string]string, len(config.Contracts)),
+		contractAddresses: make(map[string]common.Address, len(config.Contracts)),
+	}
+
+	var unaliasedContracts []string
+
+	for name, contract := range config.Contracts {
+		p.contractPaths[name] = resolvePath(dir, contract.Source)
+
+		alias, ok := contract.Aliases[network]
+		if !ok {
+			unaliasedContracts = append(unaliasedContracts, name)
+			continue
+		}
+
+		address, err := common.HexToAddress(alias)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid %s alias of contract %s: %s",
+				network,
+				name,
+				alias,
+			)
+		}
+		p.contractAddresses[name] = address
+	}
+
+	// The contracts could neither be imported nor deployed without an address
+	if len(unaliasedContracts) > 0 {
+		sort.Strings(unaliasedContracts)
+		return nil, fmt.Errorf(
+			"missing %s alias of contracts: %s",
+			network,
+			strings.Join(unaliasedContracts, ", "),
+		)
+	}
+
+	return p, nil
+}
+
+// isContractLocation returns true if the given location
+// is the location of a contract of the project.
+func (p *project) isContractLocation(location common.Location) bool {
+	addressLocation, ok := location.(common.AddressLocation)
+	if !ok {
+		return false
+	}
+
+	_, ok = p.contractPaths[addressLocation.Name]
+	return ok
+}
+
+// importResolver returns the import resolver for the test script in the given directory.
+// Contracts are resolved by name, and all other imports are resolved
+// as paths relative to the test script.
+func (p *project) importResolver(scriptDir string) test.ImportResolver {
+	return func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			contractPath, ok := p.contractPaths[location.Name]
+			if !ok {
+				return "", fmt.Errorf("unknown contract: %s", location.Name)
+			}
+			return readFile(contractPath)
+
+		case common.StringLocation:
+			if contractPath, ok := p.contractPaths[string(location)]; ok {
+				return readFile(contractPath)
+			}
+			return readFile(resolvePath(scriptDir, string(location)))
+
+		default:
+			return "", fmt.Errorf("cannot import location: %s", location)
+		}
+	}
+}
+
+// fileResolver returns the file resolver for the test script in the given directory,
+// which resolves paths relative to the test script.
+func fileResolver(scriptDir string) test.FileResolver {
+	return func(path string) (string, error) {
+		return readFile(resolvePath(scriptDir, path))
+	}
+}
+
+// resolvePath returns the given path, relative to the given directory,
+// unless it is absolute.
+func resolvePath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func readFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// discoverTestScripts returns the paths of the test scripts in the given paths,
+// i.e. the given files, and the files ending in `_test.cdc` in the given directories
+// and their subdirectories, except hidden directories. The paths are sorted.
+func discoverTestScripts(paths []string) ([]string, error) {
+	var scriptPaths []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			scriptPaths = append(scriptPaths, path)
+			continue
+		}
+
+		root := path
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			name := entry.Name()
+
+			if entry.IsDir() {
+				if path != root && strings.HasPrefix(name, ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if strings.HasSuffix(name, testScriptSuffix) {
+				scriptPaths = append(scriptPaths, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(scriptPaths)
+
+	return scriptPaths, nil
+}
@@ -0,0 +1,406 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the given files, by path relative to the given directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		require.NoError(t, err)
+
+		err = os.WriteFile(path, []byte(content), 0o644)
+		require.NoError(t, err)
+	}
+}
+
+func TestContractConfigUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		json     string
+		expected contractConfig
+		err      bool
+	}{
+		{
+			name:     "source",
+			json:     `"./contracts/Foo.cdc"`,
+			expected: contractConfig{Source: "./contracts/Foo.cdc"},
+		},
+		{
+			name: "object",
+			json: `{"source": "./contracts/Foo.cdc", "aliases": {"testing": "0x0000000000000007"}}`,
+			expected: contractConfig{
+				Source: "./contracts/Foo.cdc",
+				Aliases: map[string]string{
+					"testing": "0x0000000000000007",
+				},
+			},
+		},
+		{
+			name:     "object without aliases",
+			json:     `{"source": "./contracts/Foo.cdc"}`,
+			expected: contractConfig{Source: "./contracts/Foo.cdc"},
+		},
+		{
+			name: "invalid",
+			json: `42`,
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var config contractConfig
+			err := json.Unmarshal([]byte(test.json), &config)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, config)
+		})
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		config            string
+		network           string
+		contractPaths     map[string]string
+		contractAddresses map[string]common.Address
+		err               string
+	}{
+		{
+			name: "aliases",
+			config: `{
+                "contracts": {
+                    "Foo": {
+                        "source": "./contracts/Foo.cdc",
+                        "aliases": {"testing": "0x0000000000000007", "emulator": "0xf8d6e0586b0a20c7"}
+                    },
+                    "Bar": {
+                        "source": "/contracts/Bar.cdc",
+                        "aliases": {"testing": "0000000000000008"}
+                    }
+                }
+            }`,
+			network: "testing",
+			contractPaths: map[string]string{
+				"Foo": "contracts/Foo.cdc",
+				"Bar": "/contracts/Bar.cdc",
+			},
+			contractAddresses: map[string]common.Address{
+				"Foo": {0, 0, 0, 0, 0, 0, 0, 7},
+				"Bar": {0, 0, 0, 0, 0, 0, 0, 8},
+			},
+		},
+		{
+			name: "other network",
+			config: `{
+                "contracts": {
+                    "Foo": {
+                        "source": "./contracts/Foo.cdc",
+                        "aliases": {"testing": "0x0000000000000007", "emulator": "0xf8d6e0586b0a20c7"}
+                    }
+                }
+            }`,
+			network: "emulator",
+			contractPaths: map[string]string{
+				"Foo": "contracts/Foo.cdc",
+			},
+			contractAddresses: map[string]common.Address{
+				"Foo": {0xf8, 0xd6, 0xe0, 0x58, 0x6b, 0x0a, 0x20, 0xc7},
+			},
+		},
+		{
+			name:              "no contracts",
+			config:            `{"networks": {}}`,
+			network:           "testing",
+			contractPaths:     map[string]string{},
+			contractAddresses: map[string]common.Address{},
+		},
+		{
+			name: "missing alias",
+			config: `{
+                "contracts": {
+                    "Foo": "./contracts/Foo.cdc",
+                    "Bar": {"source": "./contracts/Bar.cdc", "aliases": {"emulator": "0xf8d6e0586b0a20c7"}},
+                    "Baz": {"source": "./contracts/Baz.cdc", "aliases": {"testing": "0x0000000000000007"}}
+                }
+            }`,
+			network: "testing",
+			err:     "missing testing alias of contracts: Bar, Foo",
+		},
+		{
+			name: "invalid alias",
+			config: `{
+                "contracts": {
+                    "Foo": {"source": "./contracts/Foo.cdc", "aliases": {"testing": "0xzz"}}
+                }
+            }`,
+			network: "testing",
+			err:     "invalid testing alias of contract Foo: 0xzz",
+		},
+		{
+			name:    "invalid configuration",
+			config:  `{"contracts": []}`,
+			network: "testing",
+			err:     "failed to parse",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"flow.json": test.config,
+			})
+
+			project, err := loadProject(filepath.Join(dir, "flow.json"), test.network)
+			if test.err != "" {
+				require.ErrorContains(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			// The relative paths of the contracts are relative to the configuration file
+			contractPaths := make(map[string]string, len(test.contractPaths))
+			for name, path := range test.contractPaths {
+				contractPaths[name] = resolvePath(dir, path)
+			}
+			assert.Equal(t, contractPaths, project.contractPaths)
+			assert.Equal(t, test.contractAddresses, project.contractAddresses)
+		})
+	}
+
+	t.Run("missing configuration", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadProject(filepath.Join(t.TempDir(), "flow.json"), "testing")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestDiscoverTestScripts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"b_test.cdc":                    "",
+		"a_test.cdc":                    "",
+		"helpers.cdc":                   "",
+		"nested/c_test.cdc":             "",
+		"nested/deeper/a_test.cdc":      "",
+		".hidden/hidden_test.cdc":       "",
+		"nested/.hidden/other_test.cdc": "",
+		"other/explicit.cdc":            "",
+	})
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+		err      bool
+	}{
+		{
+			name:  "directory",
+			paths: []string{dir},
+			expected: []string{
+				"a_test.cdc",
+				"b_test.cdc",
+				"nested/c_test.cdc",
+				"nested/deeper/a_test.cdc",
+			},
+		},
+		{
+			name:  "subdirectory",
+			paths: []string{filepath.Join(dir, "nested")},
+			expected: []string{
+				"nested/c_test.cdc",
+				"nested/deeper/a_test.cdc",
+			},
+		},
+		{
+			name:     "hidden directory given explicitly",
+			paths:    []string{filepath.Join(dir, ".hidden")},
+			expected: []string{".hidden/hidden_test.cdc"},
+		},
+		{
+			name: "files and directories",
+			paths: []string{
+				filepath.Join(dir, "other/explicit.cdc"),
+				filepath.Join(dir, "nested/deeper"),
+			},
+			expected: []string{
+				"nested/deeper/a_test.cdc",
+				"other/explicit.cdc",
+			},
+		},
+		{
+			name:  "missing path",
+			paths: []string{filepath.Join(dir, "missing")},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			scriptPaths, err := discoverTestScripts(test.paths)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			expected := make([]string, 0, len(test.expected))
+			for _, path := range test.expected {
+				expected = append(expected, filepath.Join(dir, path))
+			}
+			assert.Equal(t, expected, scriptPaths)
+		})
+	}
+}
+
+func TestImportResolver(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"contracts/Foo.cdc":     "contract Foo",
+		"tests/helpers.cdc":     "helpers",
+		"tests/Foo.cdc":         "not the contract",
+		"tests/nested/util.cdc": "util",
+	})
+
+	project := &project{
+		contractPaths: map[string]string{
+			"Foo": filepath.Join(dir, "contracts/Foo.cdc"),
+		},
+	}
+
+	resolver := project.importResolver(filepath.Join(dir, "tests"))
+
+	tests := []struct {
+		name     string
+		location common.Location
+		expected string
+		err      bool
+	}{
+		{
+			name: "contract by address",
+			location: common.AddressLocation{
+				Address: common.Address{0, 0, 0, 0, 0, 0, 0, 7},
+				Name:    "Foo",
+			},
+			expected: "contract Foo",
+		},
+		{
+			name:     "contract by name",
+			location: common.StringLocation("Foo"),
+			expected: "contract Foo",
+		},
+		{
+			name:     "relative path",
+			location: common.StringLocation("helpers.cdc"),
+			expected: "helpers",
+		},
+		{
+			name:     "relative path of a contract file",
+			location: common.StringLocation("Foo.cdc"),
+			expected: "not the contract",
+		},
+		{
+			name:     "nested relative path",
+			location: common.StringLocation("./nested/util.cdc"),
+			expected: "util",
+		},
+		{
+			name:     "parent relative path",
+			location: common.StringLocation("../contracts/Foo.cdc"),
+			expected: "contract Foo",
+		},
+		{
+			name: "unknown contract",
+			location: common.AddressLocation{
+				Address: common.Address{0, 0, 0, 0, 0, 0, 0, 7},
+				Name:    "Bar",
+			},
+			err: true,
+		},
+		{
+			name:     "missing file",
+			location: common.StringLocation("missing.cdc"),
+			err:      true,
+		},
+		{
+			name:     "unsupported location",
+			location: common.IdentifierLocation("Foo"),
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := resolver(test.location)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, code)
+		})
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// uncachedProgramsInterface is a runtime interface which does not cache
+// the loaded programs, e.g. to check a contract which is substituted by a mock.
+type uncachedProgramsInterface struct {
+	runtime.Interface
+}
+
+func (uncachedProgramsInterface) GetOrLoadProgram(
+	_ runtime.Location,
+	load func() (*interpreter.Program, error),
+) (*interpreter.Program, error) {
+	return load()
+}
+
+// contractMockLocations returns the code of the contract mocks
+// of the test runner, by the location of the contract they substitute.
+func (r *TestRunner) contractMockLocations() (map[common.AddressLocation]string, error) {
+	mocks := make(
+		map[common.AddressLocation]string,
+		len(r.contractMocks)+len(r.contractLocationMocks),
+	)
+
+	for name, code := range r.contractMocks {
+		address, ok := r.contracts[name]
+		if !ok {
+			return nil, fmt.Errorf("could not find the address of contract: %s", name)
+		}
+
+		location := common.AddressLocation{
+			Address: address,
+			Name:    name,
+		}
+		mocks[location] = code
+	}
+
+	for location, code := range r.contractLocationMocks {
+		mocks[location] = code
+	}
+
+	return mocks, nil
+}
+
+// checkContractMock checks that the mock of the contract with the given location
+// conforms to the public interface of the contract, i.e. that the mock declares
+// all public members and nested types of the contract, with the same types,
+// and that the contract and its nested types conform to the same interfaces.
+func checkContractMock(
+	location common.AddressLocation,
+	contractElaboration *sema.Elaboration,
+	mockElaboration *sema.Elaboration,
+) error {
+	typeID := location.TypeID(nil, location.Name)
+
+	contractType := contractElaboration.CompositeType(typeID)
+	if contractType == nil {
+		return fmt.Errorf("could not find the contract to mock: %s", location)
+	}
+
+	mockType := mockElaboration.CompositeType(typeID)
+	if mockType == nil {
+		return &ContractMockError{
+			Location: location,
+			Mismatches: []string{
+				fmt.Sprintf("missing contract `%s`", location.Name),
+			},
+		}
+	}
+
+	var mismatches []string
+	checkCompositeTypeConformance(location.Name, contractType, mockType, &mismatches)
+	if len(mismatches) > 0 {
+		return &ContractMockError{
+			Location:   location,
+			Mismatches: mismatches,
+		}
+	}
+
+	return nil
+}
+
+func checkCompositeTypeConformance(
+	path string,
+	original *sema.CompositeType,
+	mock *sema.CompositeType,
+	mismatches *[]string,
+) {
+	if original.Kind != mock.Kind {
+		*mismatches = append(
+			*mismatches,
+			fmt.Sprintf(
+				"`%s` is a %s, expected a %s",
+				path,
+				mock.Kind.Name(),
+				original.Kind.Name(),
+			),
+		)
+		return
+	}
+
+	// Events are only compared by their parameters.
+	if original.Kind == common.CompositeKindEvent {
+		originalType := &sema.FunctionType{
+			Parameters:           original.ConstructorParameters,
+			ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+		}
+		mockType := &sema.FunctionType{
+			Parameters:           mock.ConstructorParameters,
+			ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+		}
+		if !originalType.Equal(mockType) {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf(
+					"event `%s` has parameters `%s`, expected `%s`",
+					path,
+					mockType.QualifiedString(),
+					originalType.QualifiedString(),
+				),
+			)
+		}
+		return
+	}
+
+	checkInterfaceConformances(
+		path,
+		original.ExplicitInterfaceConformances,
+		mock.ExplicitInterfaceConformances,
+		mismatches,
+	)
+	checkMembersConformance(path, original.Members, mock.Members, mismatches)
+	checkNestedTypesConformance(path, original.NestedTypes, mock.NestedTypes, mismatches)
+}
+
+func checkInterfaceTypeConformance(
+	path string,
+	original *sema.InterfaceType,
+	mock *sema.InterfaceType,
+	mismatches *[]string,
+) {
+	if original.CompositeKind != mock.CompositeKind {
+		*mismatches = append(
+			*mismatches,
+			fmt.Sprintf(
+				"`%s` is a %s interface, expected a %s interface",
+				path,
+				mock.CompositeKind.Name(),
+				original.CompositeKind.Name(),
+			),
+		)
+		return
+	}
+
+	checkInterfaceConformances(
+		path,
+		original.ExplicitInterfaceConformances,
+		mock.ExplicitInterfaceConformances,
+		mismatches,
+	)
+	checkMembersConformance(path, original.Members, mock.Members, mismatches)
+	checkNestedTypesConformance(path, original.NestedTypes, mock.NestedTypes, mismatches)
+}
+
+// checkInterfaceConformances checks that the mock conforms to all interfaces
+// the original conforms to, so that code relying on the interface types still works.
+// The mock may conform to additional interfaces.
+func checkInterfaceConformances(
+	path string,
+	original []*sema.InterfaceType,
+	mock []*sema.InterfaceType,
+	mismatches *[]string,
+) {
+	mockConformances := make(map[sema.TypeID]struct{}, len(mock))
+	for _, interfaceType := range mock {
+		mockConformances[interfaceType.ID()] = struct{}{}
+	}
+
+	for _, interfaceType := range original {
+		if _, ok := mockConformances[interfaceType.ID()]; ok {
+			continue
+		}
+
+		*mismatches = append(
+			*mismatches,
+			fmt.Sprintf(
+				"`%s` does not conform to `%s`",
+				path,
+				interfaceType.QualifiedString(),
+			),
+		)
+	}
+}
+
+func checkMembersConformance(
+	path string,
+	original *sema.StringMemberOrderedMap,
+	mock *sema.StringMemberOrderedMap,
+	mismatches *[]string,
+) {
+	original.Foreach(func(name string, member *sema.Member) {
+		// Nested types are compared separately.
+		if member.Predeclared ||
+			member.DeclarationKind.IsTypeDeclaration() ||
+			!isPublicAccess(member.Access) {
+
+			return
+		}
+
+		memberPath := path + "." + name
+
+		mockMember, ok := mock.Get(name)
+		if !ok || !isPublicAccess(mockMember.Access) {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf(
+					"missing %s `%s`",
+					member.DeclarationKind.Name(),
+					memberPath,
+				),
+			)
+			return
+		}
+
+		if mockMember.DeclarationKind != member.DeclarationKind {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf(
+					"`%s` is a %s, expected a %s",
+					memberPath,
+					mockMember.DeclarationKind.Name(),
+					member.DeclarationKind.Name(),
+				),
+			)
+			return
+		}
+
+		if !mockMember.Access.Equal(member.Access) {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf(
+					"`%s` has access `%s`, expected `%s`",
+					memberPath,
+					mockMember.Access.QualifiedString(),
+					member.Access.QualifiedString(),
+				),
+			)
+			return
+		}
+
+		originalType := member.TypeAnnotation.Type
+		mockType := mockMember.TypeAnnotation.Type
+		if !mockType.Equal(originalType) {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf(
+					"`%s` has type `%s`, expected `%s`",
+					memberPath,
+					mockType.QualifiedString(),
+					originalType.QualifiedString(),
+				),
+			)
+		}
+	})
+}
+
+func checkNestedTypesConformance(
+	path string,
+	original *sema.StringTypeOrderedMap,
+	mock *sema.StringTypeOrderedMap,
+	mismatches *[]string,
+) {
+	if original == nil {
+		return
+	}
+
+	original.Foreach(func(name string, nestedType sema.Type) {
+		nestedPath := path + "." + name
+
+		var mockNestedType sema.Type
+		if mock != nil {
+			mockNestedType, _ = mock.Get(name)
+		}
+
+		if mockNestedType == nil {
+			*mismatches = append(
+				*mismatches,
+				fmt.Sprintf("missing type `%s`", nestedPath),
+			)
+			return
+		}
+
+		switch nestedType := nestedType.(type) {
+		case *sema.CompositeType:
+			mockCompositeType, ok := mockNestedType.(*sema.CompositeType)
+			if !ok {
+				*mismatches = append(
+					*mismatches,
+					fmt.Sprintf("`%s` is not a %s", nestedPath, nestedType.Kind.Name()),
+				)
+				return
+			}
+			checkCompositeTypeConformance(nestedPath, nestedType, mockCompositeType, mismatches)
+
+		case *sema.InterfaceType:
+			mockInterfaceType, ok := mockNestedType.(*sema.InterfaceType)
+			if !ok {
+				*mismatches = append(
+					*mismatches,
+					fmt.Sprintf("`%s` is not a %s interface", nestedPath, nestedType.CompositeKind.Name()),
+				)
+				return
+			}
+			checkInterfaceTypeConformance(nestedPath, nestedType, mockInterfaceType, mismatches)
+
+		default:
+			if !mockNestedType.Equal(nestedType) {
+				*mismatches = append(
+					*mismatches,
+					fmt.Sprintf(
+						"`%s` is `%s`, expected `%s`",
+						nestedPath,
+						mockNestedType.QualifiedString(),
+						nestedType.QualifiedString(),
+					),
+				)
+			}
+		}
+	})
+}
+
+// isPublicAccess returns true if members with the given access
+// are part of the public interface of a contract,
+// i.e. if they are accessible outside of their account.
+func isPublicAccess(access sema.Access) bool {
+	return !access.IsPrimitiveAccess() ||
+		access.Equal(sema.PrimitiveAccess(ast.AccessAll))
+}
@@ -0,0 +1,515 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// CoveragePathMapper maps the location of a covered program
+// to the path of its file in the project.
+type CoveragePathMapper func(location common.Location) string
+
+// NewCoveragePathMapper returns a mapper for the locations of the given contracts,
+// i.e. the contracts mapping of the test runner, given the paths of their files.
+// Address locations are mapped by the name of the contract, if it is deployed at the address,
+// and string locations (e.g. imports of the test scripts) are used as paths.
+// All other locations are mapped to their ID.
+func NewCoveragePathMapper(
+	contracts map[string]common.Address,
+	paths map[string]string,
+) CoveragePathMapper {
+	return func(location common.Location) string {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			address, ok := contracts[location.Name]
+			if ok && address == location.Address {
+				if filePath, ok := paths[location.Name]; ok {
+					return filePath
+				}
+			}
+
+		case common.StringLocation:
+			return path.Clean(string(location))
+		}
+
+		return location.ID()
+	}
+}
+
+// CoveragePathMapper returns a mapper for the locations of the contracts of the runner,
+// given the paths of the files of the contracts, see NewCoveragePathMapper.
+func (r *TestRunner) CoveragePathMapper(paths map[string]string) CoveragePathMapper {
+	return NewCoveragePathMapper(r.contracts, paths)
+}
+
+// CoverageExporter exports a coverage report in a certain format.
+type CoverageExporter interface {
+	Export(writer io.Writer, report *runtime.CoverageReport) error
+}
+
+// NewCoverageExporter returns the exporter for the given format,
+// which is one of "lcov", "cobertura" and "html".
+// The file resolver is used to read the sources of the HTML report, and may be nil.
+func NewCoverageExporter(
+	format string,
+	paths CoveragePathMapper,
+	fileResolver FileResolver,
+) (CoverageExporter, error) {
+	switch format {
+	case "lcov":
+		return LCOVCoverageExporter{Paths: paths}, nil
+	case "cobertura":
+		return CoberturaCoverageExporter{Paths: paths}, nil
+	case "html":
+		return HTMLCoverageExporter{
+			Paths:        paths,
+			FileResolver: fileResolver,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported coverage format: %s", format)
+	}
+}
+
+// coverageFile is the coverage of a program, mapped to its file.
+type coverageFile struct {
+	Path     string
+	Location common.Location
+	// Lines are the lines with statements, in ascending order.
+	Lines      []int
+	LineHits   map[int]int
+	Statements int
+	Covered    int
+}
+
+// CoveredStatements returns the number of covered statements.
+// The covered lines may exceed the inspected statements,
+// see runtime.LocationCoverage.Percentage.
+func (f coverageFile) CoveredStatements() int {
+	if f.Covered > f.Statements {
+		return f.Statements
+	}
+	return f.Covered
+}
+
+// Rate returns the ratio of covered statements, between 0 and 1.
+func (f coverageFile) Rate() float64 {
+	return coverageRate(f.CoveredStatements(), f.Statements)
+}
+
+// HitLines returns the number of lines which were hit.
+// Unlike CoveredStatements, it matches the hits of the lines.
+func (f coverageFile) HitLines() int {
+	hitLines := 0
+	for _, line := range f.Lines {
+		if f.LineHits[line] > 0 {
+			hitLines++
+		}
+	}
+	return hitLines
+}
+
+func coverageRate(covered int, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+
+	return float64(covered) / float64(statements)
+}
+
+// coverageFiles returns the coverage of each location of the report, ordered by path.
+func coverageFiles(report *runtime.CoverageReport, paths CoveragePathMapper) []coverageFile {
+	files := make([]coverageFile, 0, len(report.Coverage))
+
+	for location, coverage := range report.Coverage {
+		filePath := location.ID()
+		if paths != nil {
+			filePath = paths(location)
+		}
+
+		lines := make([]int, 0, len(coverage.LineHits))
+		for line := range coverage.LineHits {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		files = append(files, coverageFile{
+			Path:       filePath,
+			Location:   location,
+			Lines:      lines,
+			LineHits:   coverage.LineHits,
+			Statements: coverage.Statements,
+			Covered:    coverage.CoveredLines(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files
+}
+
+// TotalCoverage returns the percentage of the covered statements of the report.
+func TotalCoverage(report *runtime.CoverageReport) float64 {
+	var covered, statements int
+	for _, file := range coverageFiles(report, nil) {
+		covered += file.CoveredStatements()
+		statements += file.Statements
+	}
+
+	return 100 * coverageRate(covered, statements)
+}
+
+// CheckCoverageThreshold returns an error if the percentage
+// of the covered statements of the report is below the given minimum.
+func CheckCoverageThreshold(report *runtime.CoverageReport, minimum float64) error {
+	coverage := TotalCoverage(report)
+	if coverage < minimum {
+		return &CoverageThresholdError{
+			Coverage: coverage,
+			Minimum:  minimum,
+		}
+	}
+
+	return nil
+}
+
+// LCOVCoverageExporter exports the coverage in the LCOV format,
+// which only supports line coverage.
+// See https://github.com/linux-test-project/lcov/blob/master/man/geninfo.1
+type LCOVCoverageExporter struct {
+	Paths CoveragePathMapper
+}
+
+var _ CoverageExporter = LCOVCoverageExporter{}
+
+func (e LCOVCoverageExporter) Export(writer io.Writer, report *runtime.CoverageReport) error {
+	for _, file := range coverageFiles(report, e.Paths) {
+		_, err := fmt.Fprintf(writer, "TN:\nSF:%s\n", file.Path)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range file.Lines {
+			_, err = fmt.Fprintf(writer, "DA:%d,%d\n", line, file.LineHits[line])
+			if err != nil {
+				return err
+			}
+		}
+
+		// The found and hit lines must match the DA entries,
+		// not the inspected statements, see coverageFile.CoveredStatements.
+		_, err = fmt.Fprintf(
+			writer,
+			"LF:%d\nLH:%d\nend_of_record\n",
+			len(file.Lines),
+			file.HitLines(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CoberturaCoverageExporter exports the coverage in the Cobertura XML format,
+// with a package for each directory, and a class for each file.
+// Only line coverage is reported.
+type CoberturaCoverageExporter struct {
+	Paths CoveragePathMapper
+}
+
+var _ CoverageExporter = CoberturaCoverageExporter{}
+
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">` + "\n"
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      string             `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity string           `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity string          `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+func (e CoberturaCoverageExporter) Export(writer io.Writer, report *runtime.CoverageReport) error {
+	coverage := coberturaCoverage{
+		BranchRate: coberturaRate(0),
+		Complexity: coberturaRate(0),
+		Timestamp:  time.Now().UnixMilli(),
+		Sources:    []string{"."},
+	}
+
+	packages := map[string]*coberturaPackage{}
+	packageCovered := map[string]int{}
+	packageLines := map[string]int{}
+	var packageNames []string
+
+	for _, file := range coverageFiles(report, e.Paths) {
+		packageName := path.Dir(file.Path)
+		pkg, ok := packages[packageName]
+		if !ok {
+			pkg = &coberturaPackage{
+				Name:       packageName,
+				BranchRate: coberturaRate(0),
+				Complexity: coberturaRate(0),
+			}
+			packages[packageName] = pkg
+			packageNames = append(packageNames, packageName)
+		}
+
+		class := coberturaClass{
+			Name:       coverageFileName(file),
+			Filename:   file.Path,
+			LineRate:   coberturaRate(coverageRate(file.HitLines(), len(file.Lines))),
+			BranchRate: coberturaRate(0),
+			Complexity: coberturaRate(0),
+		}
+		for _, line := range file.Lines {
+			class.Lines = append(class.Lines, coberturaLine{
+				Number: line,
+				Hits:   file.LineHits[line],
+			})
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		// The rates and totals must match the line entries,
+		// not the inspected statements, see coverageFile.CoveredStatements.
+		packageCovered[packageName] += file.HitLines()
+		packageLines[packageName] += len(file.Lines)
+		coverage.LinesCovered += file.HitLines()
+		coverage.LinesValid += len(file.Lines)
+	}
+
+	for _, packageName := range packageNames {
+		pkg := packages[packageName]
+		pkg.LineRate = coberturaRate(coverageRate(
+			packageCovered[packageName],
+			packageLines[packageName],
+		))
+		coverage.Packages = append(coverage.Packages, *pkg)
+	}
+
+	coverage.LineRate = coberturaRate(coverageRate(coverage.LinesCovered, coverage.LinesValid))
+
+	_, err := io.WriteString(writer, xml.Header+coberturaDocType)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(coverage)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, "\n")
+	return err
+}
+
+func coberturaRate(rate float64) string {
+	return fmt.Sprintf("%.4f", rate)
+}
+
+// coverageFileName returns the name of the covered program,
+// i.e. the name of the contract, or the name of the file.
+func coverageFileName(file coverageFile) string {
+	if location, ok := file.Location.(common.AddressLocation); ok {
+		return location.Name
+	}
+
+	return strings.TrimSuffix(path.Base(file.Path), path.Ext(file.Path))
+}
+
+// HTMLCoverageExporter exports the coverage as an HTML document,
+// with a summary of all files, and the annotated source code of each file.
+// The source code is read using the file resolver. If it is not available,
+// only the hits of each line are shown.
+type HTMLCoverageExporter struct {
+	Paths        CoveragePathMapper
+	FileResolver FileResolver
+}
+
+var _ CoverageExporter = HTMLCoverageExporter{}
+
+type htmlCoverageFile struct {
+	ID         string
+	Name       string
+	Path       string
+	Statements int
+	Covered    int
+	Percentage string
+	Lines      []htmlCoverageLine
+}
+
+type htmlCoverageLine struct {
+	Number int
+	Source string
+	// Class is one of "covered", "missed", or empty if the line has no statements.
+	Class string
+	Hits  string
+}
+
+var htmlCoverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: left; }
+.source td { font-family: monospace; white-space: pre; }
+.source .number, .source .hits { color: #888; text-align: right; }
+.covered { background-color: #dfd; }
+.missed { background-color: #fdd; }
+</style>
+</head>
+<body>
+<h1>Coverage report: {{.Percentage}}</h1>
+<table class="summary">
+<tr><th>File</th><th>Statements</th><th>Covered</th><th>Coverage</th></tr>
+{{- range .Files}}
+<tr><td><a href="#{{.ID}}">{{.Path}}</a></td><td>{{.Statements}}</td><td>{{.Covered}}</td><td>{{.Percentage}}</td></tr>
+{{- end}}
+</table>
+{{- range .Files}}
+<h2 id="{{.ID}}">{{.Name}} <small>{{.Path}}: {{.Percentage}}</small></h2>
+<table class="source">
+{{- range .Lines}}
+<tr class="{{.Class}}"><td class="number">{{.Number}}</td><td class="hits">{{.Hits}}</td><td>{{.Source}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+func (e HTMLCoverageExporter) Export(writer io.Writer, report *runtime.CoverageReport) error {
+	files := coverageFiles(report, e.Paths)
+
+	htmlFiles := make([]htmlCoverageFile, 0, len(files))
+
+	for i, file := range files {
+		htmlFiles = append(htmlFiles, htmlCoverageFile{
+			ID:         fmt.Sprintf("file-%d", i),
+			Name:       coverageFileName(file),
+			Path:       file.Path,
+			Statements: file.Statements,
+			Covered:    file.CoveredStatements(),
+			Percentage: fmt.Sprintf("%.1f%%", 100*file.Rate()),
+			Lines:      e.lines(file),
+		})
+	}
+
+	return htmlCoverageTemplate.Execute(writer, struct {
+		Percentage string
+		Files      []htmlCoverageFile
+	}{
+		Percentage: fmt.Sprintf("%.1f%%", TotalCoverage(report)),
+		Files:      htmlFiles,
+	})
+}
+
+// lines returns the annotated lines of the source code of the given file,
+// or only the lines with statements, if the source code is not available.
+func (e HTMLCoverageExporter) lines(file coverageFile) []htmlCoverageLine {
+	newLine := func(number int, source string) htmlCoverageLine {
+		line := htmlCoverageLine{
+			Number: number,
+			Source: source,
+		}
+
+		hits, ok := file.LineHits[number]
+		if ok {
+			line.Hits = fmt.Sprintf("%dx", hits)
+			if hits > 0 {
+				line.Class = "covered"
+			} else {
+				line.Class = "missed"
+			}
+		}
+
+		return line
+	}
+
+	var source string
+	var err error
+	if e.FileResolver != nil {
+		source, err = e.FileResolver(file.Path)
+	}
+
+	if e.FileResolver == nil || err != nil {
+		lines := make([]htmlCoverageLine, 0, len(file.Lines))
+		for _, number := range file.Lines {
+			lines = append(lines, newLine(number, ""))
+		}
+		return lines
+	}
+
+	sourceLines := strings.Split(source, "\n")
+	lines := make([]htmlCoverageLine, 0, len(sourceLines))
+	for i, sourceLine := range sourceLines {
+		lines = append(lines, newLine(i+1, sourceLine))
+	}
+
+	return lines
+}
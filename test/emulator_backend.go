@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	"github.com/onflow/flow-emulator/adapters"
 	"github.com/onflow/flow-emulator/convert"
 	"github.com/onflow/flow-emulator/emulator"
+	"github.com/onflow/flow-emulator/storage/sqlite"
 	"github.com/onflow/flow-emulator/types"
 	sdk "github.com/onflow/flow-go-sdk"
 	"github.com/onflow/flow-go-sdk/crypto"
@@ -56,30 +58,65 @@ const initialAccountsNumber = 20
 
 var _ stdlib.Blockchain = &EmulatorBackend{}
 
+// systemClock is the clock of the blockchain.
+// By default, it follows the wall clock, shifted by TimeDelta.
+// In the deterministic mode, its time only changes when it is moved,
+// or by the block interval, when a block is committed.
 type systemClock struct {
 	TimeDelta int64
+
+	deterministic bool
+	// time is the time of the clock in the deterministic mode.
+	time time.Time
+	// blockInterval is the time between blocks in the deterministic mode.
+	blockInterval time.Duration
 }
 
 func (sc systemClock) Now() time.Time {
-	return time.Now().Add(time.Second * time.Duration(sc.TimeDelta)).UTC()
+	now := time.Now()
+	if sc.deterministic {
+		now = sc.time
+	}
+	return now.Add(time.Second * time.Duration(sc.TimeDelta)).UTC()
 }
 
 func newSystemClock() *systemClock {
 	return &systemClock{}
 }
 
+// newDeterministicClock returns a clock in the deterministic mode,
+// see EmulatorBackend.UseDeterministicClock.
+func newDeterministicClock(start time.Time, blockInterval time.Duration) *systemClock {
+	return &systemClock{
+		deterministic: true,
+		time:          start,
+		blockInterval: blockInterval,
+	}
+}
+
 // EmulatorBackend is the emulator-backed implementation of the interpreter.TestFramework.
 type EmulatorBackend struct {
 	blockchain *emulator.Blockchain
 
-	// blockOffset is the offset for the sequence number of the next transaction.
-	// This is equal to the number of transactions in the current block.
+	// blockOffset is the number of transactions in the current block.
 	// Must be reset once the block is committed.
 	blockOffset uint64
 
+	// sequenceNumberOffsets are the offsets for the sequence numbers of the proposal keys
+	// of the next transactions, i.e. the number of transactions in the current block
+	// proposed with each key.
+	// Must be reset once the block is committed.
+	sequenceNumberOffsets map[accountKeyID]uint64
+
+	// nextTransactionOptions are the options of the next added transaction.
+	nextTransactionOptions TransactionOptions
+
 	// accountKeys is a mapping of account addresses with their keys.
 	accountKeys map[common.Address]map[string]keyInfo
 
+	// revokedKeys caches the revoked keys of the accounts, see revokedKeyIndices.
+	revokedKeys revokedKeysCache
+
 	stdlibHandler stdlib.StandardLibraryHandler
 
 	// logCollection is a hook attached in the server logger, in order
@@ -100,6 +137,32 @@ type EmulatorBackend struct {
 	// contracts is a mapping of contract identifiers to their
 	// deployed account address.
 	contracts map[string]common.Address
+
+	// contractMocks is a mapping of contract locations to the code
+	// of the mocks which are deployed instead of the contracts.
+	contractMocks map[common.AddressLocation]string
+
+	// contractMockChecker checks that the mock of the contract with the given location
+	// conforms to the given code of the contract, before the mock is deployed.
+	contractMockChecker func(location common.AddressLocation, code string, mockCode string) error
+
+	// contractUpdateHandler is called after a deployed contract
+	// was updated or removed, e.g. to reload its value.
+	contractUpdateHandler func(location common.AddressLocation)
+
+	// events is the index of the events of the committed blocks.
+	events *EventStore
+
+	// transactions are the details of the executed transactions,
+	// in execution order.
+	transactions []*TransactionDetails
+
+	// store is the storage of the blockchain.
+	store *sqlite.Store
+
+	// randomSource is the source of randomness of the blockchain,
+	// if it is controlled, i.e. seeded, or if random values are injected.
+	randomSource *randomSource
 }
 
 type keyInfo struct {
@@ -151,37 +214,78 @@ func NewEmulatorBackend(
 	logger zerolog.Logger,
 	stdlibHandler stdlib.StandardLibraryHandler,
 	coverageReport *runtime.CoverageReport,
+	opts ...emulator.Option,
+) *EmulatorBackend {
+	return newEmulatorBackend(
+		logger,
+		stdlibHandler,
+		coverageReport,
+		newSystemClock(),
+		opts...,
+	)
+}
+
+// newEmulatorBackend returns a new EmulatorBackend, which uses the given clock.
+// The clock is used before the predefined accounts are created,
+// so that the timestamps of all blocks are produced by it.
+func newEmulatorBackend(
+	logger zerolog.Logger,
+	stdlibHandler stdlib.StandardLibraryHandler,
+	coverageReport *runtime.CoverageReport,
+	clock *systemClock,
+	opts ...emulator.Option,
 ) *EmulatorBackend {
 	logCollectionHook := newLogCollectionHook()
-	var blockchain *emulator.Blockchain
+
+	// Use an in-memory store, like the emulator does by default,
+	// which is also used to seed the blockchain with exported state.
+	store, err := sqlite.New(sqlite.InMemory)
+	if err != nil {
+		panic(err)
+	}
+	opts = append(opts, emulator.WithStore(store))
+
 	if coverageReport != nil {
 		excludeCommonLocations(coverageReport)
-		blockchain = newBlockchain(
-			logger,
-			logCollectionHook,
-			emulator.WithCoverageReport(coverageReport),
-		)
-	} else {
-		blockchain = newBlockchain(
-			logger,
-			logCollectionHook,
-		)
+		opts = append(opts, emulator.WithCoverageReport(coverageReport))
 	}
-	clock := newSystemClock()
+	blockchain := newBlockchain(
+		logger,
+		logCollectionHook,
+		opts...,
+	)
 	blockchain.SetClock(clock)
 
 	emulatorBackend := &EmulatorBackend{
-		blockchain:    blockchain,
-		blockOffset:   0,
-		accountKeys:   map[common.Address]map[string]keyInfo{},
-		stdlibHandler: stdlibHandler,
-		logCollection: logCollectionHook,
-		clock:         clock,
-		contracts:     map[string]common.Address{},
-		accounts:      map[common.Address]*stdlib.Account{},
+		blockchain:            blockchain,
+		blockOffset:           0,
+		sequenceNumberOffsets: map[accountKeyID]uint64{},
+		accountKeys:           map[common.Address]map[string]keyInfo{},
+		stdlibHandler:         stdlibHandler,
+		logCollection:         logCollectionHook,
+		clock:                 clock,
+		contracts:             map[string]common.Address{},
+		accounts:              map[common.Address]*stdlib.Account{},
+		events:                NewEventStore(),
+		store:                 store,
+	}
+
+	// Store the service account key, so that the service
+	// account can be selected as a proposer, or as a signer.
+	_, err = emulatorBackend.ServiceAccount()
+	if err != nil {
+		panic(err)
 	}
+
+	// In the deterministic mode, the time is frozen while the predefined accounts
+	// are created, so the first block of the tests has the start timestamp.
+	blockInterval := clock.blockInterval
+	clock.blockInterval = 0
+
 	emulatorBackend.bootstrapAccounts()
 
+	clock.blockInterval = blockInterval
+
 	return emulatorBackend
 }
 
@@ -190,6 +294,12 @@ func (e *EmulatorBackend) RunScript(
 	code string,
 	args []interpreter.Value,
 ) *stdlib.ScriptResult {
+	err := e.useRandomSource()
+	if err != nil {
+		return &stdlib.ScriptResult{
+			Error: err,
+		}
+	}
 
 	arguments := make([][]byte, 0, len(args))
 	for _, arg := range args {
@@ -289,28 +399,66 @@ func (e *EmulatorBackend) CreateAccount() (*stdlib.Account, error) {
 	keyGen := sdkTest.AccountKeyGenerator()
 	accountKey, signer := keyGen.NewWithSigner()
 
+	return e.createAccount(
+		[]*sdk.AccountKey{accountKey},
+		[]crypto.Signer{signer},
+	)
+}
+
+// CreateAccountWithKeys creates an account with a key for each of the given options,
+// e.g. to create an account with multiple keys, which have to sign a transaction together.
+// The keys have the indices of the options.
+func (e *EmulatorBackend) CreateAccountWithKeys(keys []AccountKeyOptions) (*stdlib.Account, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("account must have at least one key")
+	}
+
+	accountKeys := make([]*sdk.AccountKey, 0, len(keys))
+	signers := make([]crypto.Signer, 0, len(keys))
+
+	for index, options := range keys {
+		accountKey, signer, err := newAccountKey(index, options)
+		if err != nil {
+			return nil, err
+		}
+
+		accountKeys = append(accountKeys, accountKey)
+		signers = append(signers, signer)
+	}
+
+	return e.createAccount(accountKeys, signers)
+}
+
+func (e *EmulatorBackend) createAccount(
+	accountKeys []*sdk.AccountKey,
+	signers []crypto.Signer,
+) (*stdlib.Account, error) {
+
 	sdkAdapter := adapters.NewSDKAdapter(zerolog.DefaultContextLogger, e.blockchain)
-	address, err := sdkAdapter.CreateAccount(context.Background(), []*sdk.AccountKey{accountKey}, nil)
+	address, err := sdkAdapter.CreateAccount(context.Background(), accountKeys, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	publicKey := accountKey.PublicKey.Encode()
-	encodedPublicKey := string(publicKey)
-
-	// Store the generated key and signer info.
+	// Store the generated keys and signer info.
 	// This info is used to sign transactions.
-	e.accountKeys[common.Address(address)] = map[string]keyInfo{
-		encodedPublicKey: {
+	keys := make(map[string]keyInfo, len(accountKeys))
+	for index, accountKey := range accountKeys {
+		accountKey.Index = index
+		keys[string(accountKey.PublicKey.Encode())] = keyInfo{
 			accountKey: accountKey,
-			signer:     signer,
-		},
+			signer:     signers[index],
+		}
 	}
+	e.accountKeys[common.Address(address)] = keys
+
+	// The public key of the account is the one of the first key.
+	accountKey := accountKeys[0]
 
 	account := &stdlib.Account{
 		Address: common.Address(address),
 		PublicKey: &stdlib.PublicKey{
-			PublicKey: publicKey,
+			PublicKey: accountKey.PublicKey.Encode(),
 			SignAlgo:  fvmCrypto.CryptoToRuntimeSigningAlgorithm(accountKey.PublicKey.Algorithm()),
 		},
 	}
@@ -339,7 +487,14 @@ func (e *EmulatorBackend) AddTransaction(
 
 	code = e.replaceImports(code)
 
-	tx := e.newTransaction(code, authorizers)
+	// The options only apply to the next transaction.
+	options := e.nextTransactionOptions
+	e.nextTransactionOptions = TransactionOptions{}
+
+	tx, err := e.newTransaction(code, authorizers, options)
+	if err != nil {
+		return err
+	}
 
 	for _, arg := range args {
 		exportedValue, err := runtime.ExportValue(arg, inter, interpreter.EmptyLocationRange)
@@ -353,54 +508,180 @@ func (e *EmulatorBackend) AddTransaction(
 		}
 	}
 
-	err := e.signTransaction(tx, signers)
+	err = e.signTransaction(tx, signers, options)
 	if err != nil {
 		return err
 	}
 
+	return e.submitTransaction(tx)
+}
+
+// SetNextTransactionOptions sets the options of the next added transaction,
+// e.g. to add a transaction with a proposer and payer other than the service account.
+func (e *EmulatorBackend) SetNextTransactionOptions(options TransactionOptions) {
+	e.nextTransactionOptions = options
+}
+
+// submitTransaction adds the given signed transaction to the current block.
+func (e *EmulatorBackend) submitTransaction(tx *sdk.Transaction) error {
 	flowTx := convert.SDKTransactionToFlow(*tx)
-	err = e.blockchain.AddTransaction(*flowTx)
+	err := e.blockchain.AddTransaction(*flowTx)
 	if err != nil {
 		return err
 	}
 
 	// Increment the transaction sequence number offset for the current block.
 	e.blockOffset++
+	e.sequenceNumberOffsets[accountKeyID{
+		address: common.Address(tx.ProposalKey.Address),
+		index:   tx.ProposalKey.KeyIndex,
+	}]++
 
 	return nil
 }
 
 func (e *EmulatorBackend) ExecuteNextTransaction() *stdlib.TransactionResult {
+	details, err := e.ExecuteNextTransactionDetails()
+	if err != nil {
+		return &stdlib.TransactionResult{
+			Error: err,
+		}
+	}
+
+	// If there are no transactions to execute, return a nil result.
+	if details == nil {
+		return nil
+	}
+
+	return &stdlib.TransactionResult{
+		Error: details.Error,
+	}
+}
+
+// ExecuteNextTransactionDetails executes the next transaction of the pending block,
+// and returns its recorded details, which include the error of a failed transaction.
+// The returned details are nil if there are no transactions to execute.
+// The returned error is only non-nil if the transaction could not be executed.
+func (e *EmulatorBackend) ExecuteNextTransactionDetails() (*TransactionDetails, error) {
+	err := e.useRandomSource()
+	if err != nil {
+		return nil, err
+	}
+
+	// The injected random values are only read by the next transaction.
+	if e.randomSource != nil {
+		e.randomSource.values = e.randomSource.nextValues
+		e.randomSource.nextValues = nil
+		defer func() {
+			e.randomSource.values = nil
+		}()
+	}
+
 	result, err := e.blockchain.ExecuteNextTransaction()
 
 	if err != nil {
 		// If the returned error is `emulator.PendingBlockTransactionsExhaustedError`,
 		// that means there are no transactions to execute.
-		// Hence, return a nil result.
 		if _, ok := err.(*types.PendingBlockTransactionsExhaustedError); ok {
-			return nil
+			return nil, nil
 		}
 
-		return &stdlib.TransactionResult{
-			Error: err,
-		}
+		return nil, err
 	}
 
-	if result.Error != nil {
-		return &stdlib.TransactionResult{
-			Error: result.Error,
+	return e.addTransactionDetails(result)
+}
+
+// addTransactionDetails records and returns the details of the given transaction result,
+// which was executed in the pending block.
+func (e *EmulatorBackend) addTransactionDetails(result *types.TransactionResult) (*TransactionDetails, error) {
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.revokeCachedKeys(result.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &TransactionDetails{
+		ID:              result.TransactionID,
+		BlockHeight:     latestBlock.Header.Height + 1,
+		Error:           result.Error,
+		ComputationUsed: result.ComputationUsed,
+		MemoryEstimate:  result.MemoryEstimate,
+		Events:          result.Events,
+		Logs:            result.Logs,
+	}
+	e.transactions = append(e.transactions, details)
+
+	return details, nil
+}
+
+// ExecutedTransactions returns the details of all executed transactions,
+// in execution order.
+func (e *EmulatorBackend) ExecutedTransactions() []*TransactionDetails {
+	return e.transactions
+}
+
+// LastTransaction returns the details of the most recently executed transaction,
+// or nil if no transaction was executed.
+func (e *EmulatorBackend) LastTransaction() *TransactionDetails {
+	if len(e.transactions) == 0 {
+		return nil
+	}
+
+	return e.transactions[len(e.transactions)-1]
+}
+
+// TransactionDetails returns the details of the executed transaction with the given ID.
+func (e *EmulatorBackend) TransactionDetails(id sdk.Identifier) (*TransactionDetails, error) {
+	for i := len(e.transactions) - 1; i >= 0; i-- {
+		if e.transactions[i].ID == id {
+			return e.transactions[i], nil
 		}
 	}
 
-	return &stdlib.TransactionResult{}
+	return nil, TransactionNotFoundError{
+		ID: id.String(),
+	}
+}
+
+// truncateTransactions discards the details of the transactions
+// executed in blocks after the given height.
+func (e *EmulatorBackend) truncateTransactions(height uint64) {
+	count := len(e.transactions)
+	for count > 0 && e.transactions[count-1].BlockHeight > height {
+		count--
+	}
+
+	e.transactions = e.transactions[:count]
 }
 
 func (e *EmulatorBackend) CommitBlock() error {
-	// Reset the transaction offset for the current block.
+	// Reset the transaction offsets for the current block.
 	e.blockOffset = 0
+	e.sequenceNumberOffsets = map[accountKeyID]uint64{}
+
+	// The system transaction of the block reads the source of randomness.
+	err := e.useRandomSource()
+	if err != nil {
+		return err
+	}
+
+	_, err = e.blockchain.CommitBlock()
+	if err != nil {
+		return err
+	}
 
-	_, err := e.blockchain.CommitBlock()
-	return err
+	// The next block is produced one block interval later.
+	if e.clock.deterministic && e.clock.blockInterval > 0 {
+		e.clock.time = e.clock.time.Add(e.clock.blockInterval)
+		e.blockchain.SetClock(e.clock)
+	}
+
+	return e.indexEvents()
 }
 
 func (e *EmulatorBackend) DeployContract(
@@ -418,11 +699,22 @@ func (e *EmulatorBackend) DeployContract(
         }
 	`
 
-	// Retrieve the contract source code, by using the given path.
+	location := common.AddressLocation{
+		Address: e.contracts[name],
+		Name:    name,
+	}
+
+	// Retrieve the contract source code, by using the given path,
+	// and deploy the mock of the contract instead, if any.
 	code, err := e.fileResolver(path)
 	if err != nil {
 		panic(err)
 	}
+
+	code, err = e.contractMockCode(location, code)
+	if err != nil {
+		return err
+	}
 	code = e.replaceImports(code)
 
 	hexEncodedCode := hex.EncodeToString([]byte(code))
@@ -455,6 +747,134 @@ func (e *EmulatorBackend) DeployContract(
 		addArgsBuilder.String(),
 	)
 
+	return e.executeContractTransaction(name, script, cadenceArgs)
+}
+
+// UpdateContract updates the deployed contract with the given name
+// to the code of the contract file with the given path.
+// The update is validated like on a real network, i.e. the new code
+// must be compatible with the stored data of the contract.
+func (e *EmulatorBackend) UpdateContract(name string, path string) error {
+	const updateContractTransactionTemplate = `
+        transaction {
+            prepare(signer: auth(UpdateContract) &Account) {
+                signer.contracts.update(name: "%s", code: "%s".decodeHex())
+            }
+        }
+	`
+
+	if e.fileResolver == nil {
+		return FileResolverNotProvidedError{}
+	}
+
+	code, err := e.fileResolver(path)
+	if err != nil {
+		return err
+	}
+
+	location := common.AddressLocation{
+		Address: e.contracts[name],
+		Name:    name,
+	}
+
+	// The contract stays substituted by its mock, if any,
+	// which must also conform to the updated contract.
+	code, err = e.contractMockCode(location, code)
+	if err != nil {
+		return &ContractUpdateError{
+			Name: name,
+			Err:  err,
+		}
+	}
+	code = e.replaceImports(code)
+
+	script := fmt.Sprintf(
+		updateContractTransactionTemplate,
+		name,
+		hex.EncodeToString([]byte(code)),
+	)
+
+	err = e.executeContractTransaction(name, script, nil)
+	if err != nil {
+		return &ContractUpdateError{
+			Name: name,
+			Err:  err,
+		}
+	}
+
+	e.refreshContract(name)
+
+	return nil
+}
+
+// RemoveContract removes the deployed contract with the given name.
+func (e *EmulatorBackend) RemoveContract(name string) error {
+	const removeContractTransactionTemplate = `
+        transaction {
+            prepare(signer: auth(RemoveContract) &Account) {
+                if signer.contracts.remove(name: "%s") == nil {
+                    panic("contract does not exist")
+                }
+            }
+        }
+	`
+
+	script := fmt.Sprintf(removeContractTransactionTemplate, name)
+
+	err := e.executeContractTransaction(name, script, nil)
+	if err != nil {
+		return &ContractRemovalError{
+			Name: name,
+			Err:  err,
+		}
+	}
+
+	e.refreshContract(name)
+
+	return nil
+}
+
+// contractMockCode returns the code of the mock of the contract with the given location and code,
+// after checking that the mock conforms to the contract.
+// If the contract is not mocked, the code of the contract is returned.
+func (e *EmulatorBackend) contractMockCode(location common.AddressLocation, code string) (string, error) {
+	mockCode, ok := e.contractMocks[location]
+	if !ok {
+		return code, nil
+	}
+
+	if e.contractMockChecker != nil {
+		err := e.contractMockChecker(location, code, mockCode)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return mockCode, nil
+}
+
+// refreshContract notifies the contract update handler, if any,
+// that the deployed contract with the given name changed.
+func (e *EmulatorBackend) refreshContract(name string) {
+	if e.contractUpdateHandler == nil {
+		return
+	}
+
+	e.contractUpdateHandler(common.AddressLocation{
+		Address: e.contracts[name],
+		Name:    name,
+	})
+}
+
+// executeContractTransaction executes the given transaction,
+// which is authorized and signed by the account of the contract
+// with the given name, and commits its block.
+func (e *EmulatorBackend) executeContractTransaction(
+	name string,
+	script string,
+	args []cadence.Value,
+) error {
+
 	address, ok := e.contracts[name]
 	if !ok {
 		return fmt.Errorf("could not find the address of contract: %s", name)
@@ -463,29 +883,36 @@ func (e *EmulatorBackend) DeployContract(
 	if !ok {
 		return fmt.Errorf("could not find an account with address: %s", address)
 	}
-	tx := e.newTransaction(script, []common.Address{account.Address})
+	tx, err := e.newTransaction(
+		script,
+		[]common.Address{account.Address},
+		TransactionOptions{},
+	)
+	if err != nil {
+		return err
+	}
 
-	for _, arg := range cadenceArgs {
+	for _, arg := range args {
 		err := tx.AddArgument(arg)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = e.signTransaction(tx, []*stdlib.Account{account})
+	err = e.signTransaction(
+		tx,
+		[]*stdlib.Account{account},
+		TransactionOptions{},
+	)
 	if err != nil {
 		return err
 	}
 
-	flowTx := convert.SDKTransactionToFlow(*tx)
-	err = e.blockchain.AddTransaction(*flowTx)
+	err = e.submitTransaction(tx)
 	if err != nil {
 		return err
 	}
 
-	// Increment the transaction sequence number offset for the current block.
-	e.blockOffset++
-
 	result := e.ExecuteNextTransaction()
 	if result.Error != nil {
 		return result.Error
@@ -509,8 +936,13 @@ func (e *EmulatorBackend) Reset(height uint64) {
 		panic(err)
 	}
 
-	// Reset the transaction offset.
+	// Reset the transaction offsets.
 	e.blockOffset = 0
+	e.sequenceNumberOffsets = map[accountKeyID]uint64{}
+	e.revokedKeys = revokedKeysCache{}
+
+	e.events.Truncate(height)
+	e.truncateTransactions(height)
 }
 
 // Events returns all the emitted events up until the latest block,
@@ -519,15 +951,6 @@ func (e *EmulatorBackend) Events(
 	inter *interpreter.Interpreter,
 	eventType interpreter.StaticType,
 ) interpreter.Value {
-	latestBlock, err := e.blockchain.GetLatestBlock()
-	if err != nil {
-		panic(err)
-	}
-
-	latestBlockHeight := latestBlock.Header.Height
-	height := uint64(0)
-	values := make([]interpreter.Value, 0)
-
 	var eventTypeString string
 	switch eventType := eventType.(type) {
 	case nil:
@@ -538,32 +961,27 @@ func (e *EmulatorBackend) Events(
 		panic(errors.NewUnreachableError())
 	}
 
-	for height <= latestBlockHeight {
-		events, err := e.blockchain.GetEventsByHeight(height, eventTypeString)
-		if err != nil {
-			panic(err)
-		}
+	events, err := e.QueryEvents(EventFilter{
+		Type: eventTypeString,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-		sdkEvents, err := convert.FlowEventsToSDK(events)
+	values := make([]interpreter.Value, 0, len(events))
+
+	for _, event := range events {
+		value, err := runtime.ImportValue(
+			inter,
+			interpreter.EmptyLocationRange,
+			e.stdlibHandler,
+			event.Value,
+			nil,
+		)
 		if err != nil {
 			panic(err)
 		}
-
-		for _, event := range sdkEvents {
-			value, err := runtime.ImportValue(
-				inter,
-				interpreter.EmptyLocationRange,
-				e.stdlibHandler,
-				event.Value,
-				nil,
-			)
-			if err != nil {
-				panic(err)
-			}
-			values = append(values, value)
-
-		}
-		height += 1
+		values = append(values, value)
 	}
 
 	arrayType := interpreter.NewVariableSizedStaticType(
@@ -583,6 +1001,43 @@ func (e *EmulatorBackend) Events(
 	)
 }
 
+// QueryEvents returns the events of the committed blocks matching the given filter,
+// in the order they were emitted, together with the emitting transaction and the event index,
+// e.g. to assert that a transaction emitted exactly a certain list of events.
+func (e *EmulatorBackend) QueryEvents(filter EventFilter) ([]IndexedEvent, error) {
+	err := e.indexEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	return e.events.Query(filter), nil
+}
+
+// indexEvents adds the events of all committed blocks
+// which are not indexed yet to the event store.
+func (e *EmulatorBackend) indexEvents() error {
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+
+	for height := e.events.NextHeight(); height <= latestBlock.Header.Height; height++ {
+		events, err := e.blockchain.GetEventsByHeight(height, "")
+		if err != nil {
+			return err
+		}
+
+		sdkEvents, err := convert.FlowEventsToSDK(events)
+		if err != nil {
+			return err
+		}
+
+		e.events.AddBlock(height, sdkEvents)
+	}
+
+	return nil
+}
+
 // MoveTime Moves the time of the Blockchain's clock, by the
 // given time delta, in the form of seconds.
 func (e *EmulatorBackend) MoveTime(timeDelta int64) {
@@ -595,6 +1050,114 @@ func (e *EmulatorBackend) MoveTime(timeDelta int64) {
 	}
 }
 
+// UseDeterministicClock switches the blockchain's clock to the deterministic mode,
+// in which the clock no longer follows the wall clock:
+// The next block has the given timestamp, and each following block
+// is produced the given block interval later, unless the time is moved.
+// The start must not be before the timestamp of the latest block.
+// As the start is absolute, the time moved so far no longer applies.
+func (e *EmulatorBackend) UseDeterministicClock(start time.Time, blockInterval time.Duration) error {
+	if blockInterval < 0 {
+		return fmt.Errorf("block interval must not be negative, got %s", blockInterval)
+	}
+
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+
+	latestTimestamp := latestBlock.Header.Timestamp
+	if start.Before(latestTimestamp) {
+		return fmt.Errorf(
+			"timestamp %s is before the timestamp of the latest block %s",
+			start.UTC().Format(time.RFC3339Nano),
+			latestTimestamp.UTC().Format(time.RFC3339Nano),
+		)
+	}
+
+	e.clock.deterministic = true
+	e.clock.time = start
+	e.clock.TimeDelta = 0
+	e.clock.blockInterval = blockInterval
+	e.blockchain.SetClock(e.clock)
+
+	return nil
+}
+
+// SetTimestamp sets the timestamp of the next block, which must not be
+// before the timestamp of the latest block. The clock is switched to
+// the deterministic mode, if it is not already, with the current block interval.
+func (e *EmulatorBackend) SetTimestamp(timestamp time.Time) error {
+	return e.UseDeterministicClock(timestamp, e.clock.blockInterval)
+}
+
+// SetBlockInterval sets the time between blocks, which is only used
+// when the clock is in the deterministic mode. An interval of zero freezes the time,
+// i.e. blocks have the same timestamp, unless the time is moved.
+func (e *EmulatorBackend) SetBlockInterval(blockInterval time.Duration) error {
+	if blockInterval < 0 {
+		return fmt.Errorf("block interval must not be negative, got %s", blockInterval)
+	}
+
+	e.clock.blockInterval = blockInterval
+
+	return nil
+}
+
+// UseRandomnessSeed makes the blockchain's randomness deterministic, e.g. of
+// `revertibleRandom` and of the `RandomBeaconHistory` contract: Random values
+// are generated from the given seed, instead of the hashes of the blocks,
+// so the same seed produces the same random values.
+func (e *EmulatorBackend) UseRandomnessSeed(seed int64) {
+	if e.randomSource == nil {
+		e.randomSource = &randomSource{}
+	}
+
+	e.randomSource.seeded = true
+	e.randomSource.seed = seed
+	e.randomSource.counter = 0
+}
+
+// SetNextRandomValues injects the given random values for the next transaction:
+// Each call of `revertibleRandom` in the transaction returns the next value,
+// if it fits the requested type and modulo. Once the values are used up,
+// random values are produced as usual.
+func (e *EmulatorBackend) SetNextRandomValues(values []uint64) {
+	if e.randomSource == nil {
+		e.randomSource = &randomSource{}
+	}
+
+	e.randomSource.nextValues = values
+}
+
+// useRandomSource makes the blockchain's runtime use the controlled source of randomness,
+// if any. The runtime is replaced when the blockchain is reloaded, e.g. when a snapshot
+// is loaded, so this must be called before executing transactions and scripts.
+func (e *EmulatorBackend) useRandomSource() error {
+	if e.randomSource == nil {
+		return nil
+	}
+
+	coverageReportedRuntime, ok := e.blockchain.Runtime().(*emulator.CoverageReportedRuntime)
+	if !ok {
+		return fmt.Errorf(
+			"failed to use the source of randomness: unexpected runtime of the blockchain: %T",
+			e.blockchain.Runtime(),
+		)
+	}
+
+	if _, ok := coverageReportedRuntime.Runtime.(*randomSourceRuntime); ok {
+		return nil
+	}
+
+	coverageReportedRuntime.Runtime = &randomSourceRuntime{
+		Runtime: coverageReportedRuntime.Runtime,
+		source:  e.randomSource,
+	}
+
+	return nil
+}
+
 // CreateSnapshot Creates a snapshot of the blockchain, at the
 // current ledger state, with the given name.
 func (e *EmulatorBackend) CreateSnapshot(name string) error {
@@ -605,7 +1168,112 @@ func (e *EmulatorBackend) CreateSnapshot(name string) error {
 // given name, and updates the current ledger
 // state.
 func (e *EmulatorBackend) LoadSnapshot(name string) error {
-	return e.blockchain.LoadSnapshot(name)
+	err := e.blockchain.LoadSnapshot(name)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot may have different blocks, so index them again.
+	e.events = NewEventStore()
+	e.transactions = nil
+
+	return nil
+}
+
+// backendSnapshot is the state of the blockchain at a certain block height,
+// together with the state of the backend, e.g. the created accounts.
+type backendSnapshot struct {
+	height uint64
+	clock  systemClock
+	// randomCounter is the state of the seeded source of randomness, if any.
+	randomCounter uint64
+	accounts      map[common.Address]*stdlib.Account
+	accountKeys   map[common.Address]map[string]keyInfo
+}
+
+// snapshot commits the pending block, if it has any transactions,
+// and returns the current state of the blockchain and the backend,
+// which can be restored later using restore.
+func (e *EmulatorBackend) snapshot() (*backendSnapshot, error) {
+	if e.blockOffset > 0 {
+		err := e.CommitBlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[common.Address]*stdlib.Account, len(e.accounts))
+	for address, account := range e.accounts {
+		accounts[address] = account
+	}
+
+	accountKeys := make(map[common.Address]map[string]keyInfo, len(e.accountKeys))
+	for address, keys := range e.accountKeys {
+		accountKeys[address] = keys
+	}
+
+	snapshot := &backendSnapshot{
+		height:      latestBlock.Header.Height,
+		clock:       *e.clock,
+		accounts:    accounts,
+		accountKeys: accountKeys,
+	}
+	if e.randomSource != nil {
+		snapshot.randomCounter = e.randomSource.counter
+	}
+
+	return snapshot, nil
+}
+
+// restore rolls back the blockchain to the given snapshot,
+// discarding all blocks and pending transactions after the snapshot.
+func (e *EmulatorBackend) restore(snapshot *backendSnapshot) error {
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+
+	if latestBlock.Header.Height > snapshot.height {
+		err = e.blockchain.RollbackToBlockHeight(snapshot.height)
+	} else {
+		// Only discard the pending block.
+		err = e.blockchain.ReloadBlockchain()
+	}
+	if err != nil {
+		return err
+	}
+
+	e.blockOffset = 0
+	e.sequenceNumberOffsets = map[accountKeyID]uint64{}
+	e.nextTransactionOptions = TransactionOptions{}
+	e.revokedKeys = revokedKeysCache{}
+	e.events.Truncate(snapshot.height)
+	e.truncateTransactions(snapshot.height)
+
+	*e.clock = snapshot.clock
+	e.blockchain.SetClock(e.clock)
+
+	if e.randomSource != nil {
+		e.randomSource.counter = snapshot.randomCounter
+		e.randomSource.nextValues = nil
+	}
+
+	e.accounts = make(map[common.Address]*stdlib.Account, len(snapshot.accounts))
+	for address, account := range snapshot.accounts {
+		e.accounts[address] = account
+	}
+
+	e.accountKeys = make(map[common.Address]map[string]keyInfo, len(snapshot.accountKeys))
+	for address, keys := range snapshot.accountKeys {
+		e.accountKeys[address] = keys
+	}
+
+	return nil
 }
 
 // Creates the number of predefined accounts that will be used
@@ -619,61 +1287,117 @@ func (e *EmulatorBackend) bootstrapAccounts() {
 	}
 }
 
-func (e *EmulatorBackend) newTransaction(code string, authorizers []common.Address) *sdk.Transaction {
+func (e *EmulatorBackend) newTransaction(
+	code string,
+	authorizers []common.Address,
+	options TransactionOptions,
+) (*sdk.Transaction, error) {
+
 	serviceKey := e.blockchain.ServiceKey()
 
-	sequenceNumber := serviceKey.SequenceNumber + e.blockOffset
+	proposer := common.Address(serviceKey.Address)
+	proposerKeyIndex := serviceKey.Index
+	sequenceNumber := serviceKey.SequenceNumber
+
+	if options.Proposer != nil {
+		proposer = *options.Proposer
+		proposerKeyIndex = options.ProposerKeyIndex
+
+		proposalKey, err := e.onChainAccountKey(proposer, proposerKeyIndex)
+		if err != nil {
+			return nil, err
+		}
+		sequenceNumber = proposalKey.SeqNumber
+	}
+
+	sequenceNumber += e.sequenceNumberOffsets[accountKeyID{
+		address: proposer,
+		index:   proposerKeyIndex,
+	}]
+
+	payer := common.Address(serviceKey.Address)
+	if options.Payer != nil {
+		payer = *options.Payer
+	}
 
 	tx := sdk.NewTransaction().
 		SetScript([]byte(code)).
-		SetProposalKey(serviceKey.Address, serviceKey.Index, sequenceNumber).
-		SetPayer(serviceKey.Address)
+		SetProposalKey(sdk.Address(proposer), proposerKeyIndex, sequenceNumber).
+		SetPayer(sdk.Address(payer))
 
 	for _, authorizer := range authorizers {
 		tx = tx.AddAuthorizer(sdk.Address(authorizer))
 	}
 
-	return tx
+	return tx, nil
 }
 
 func (e *EmulatorBackend) signTransaction(
 	tx *sdk.Transaction,
 	signerAccounts []*stdlib.Account,
+	options TransactionOptions,
 ) error {
 
 	// Sign transaction with each signer
 	// Note: Following logic is borrowed from the flow-ft.
 
-	serviceKey := e.blockchain.ServiceKey()
+	payer := common.Address(tx.Payer)
+	proposer := common.Address(tx.ProposalKey.Address)
+
+	payloadSigners := make(map[common.Address]struct{}, len(signerAccounts))
 
 	for i := len(signerAccounts) - 1; i >= 0; i-- {
 		signerAccount := signerAccounts[i]
-		if signerAccount.Address == common.Address(serviceKey.Address) {
-			// skip payload signing for service account, since we always
-			// sign the envelope with the service account just below
+		if signerAccount.Address == payer {
+			// skip payload signing for the payer, since we always
+			// sign the envelope with the payer just below
 			continue
 		}
 
-		publicKey := signerAccount.PublicKey.PublicKey
-		accountKeys := e.accountKeys[signerAccount.Address]
-		keyInfo := accountKeys[string(publicKey)]
+		if _, ok := payloadSigners[signerAccount.Address]; ok {
+			continue
+		}
+		payloadSigners[signerAccount.Address] = struct{}{}
 
-		err := tx.SignPayload(sdk.Address(signerAccount.Address), 0, keyInfo.signer)
+		keys, err := e.transactionSigningKeys(tx, signerAccount.Address, options)
 		if err != nil {
 			return err
 		}
+
+		for _, key := range keys {
+			err := tx.SignPayload(sdk.Address(signerAccount.Address), key.accountKey.Index, key.signer)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	serviceSigner, err := serviceKey.Signer()
-	if err != nil {
-		return err
+	// The proposer must sign with the proposal key,
+	// even if it is not an authorizer of the transaction.
+	if _, ok := payloadSigners[proposer]; !ok && proposer != payer {
+		key, err := e.accountKey(proposer, tx.ProposalKey.KeyIndex)
+		if err != nil {
+			return err
+		}
+
+		err = tx.SignPayload(sdk.Address(proposer), key.accountKey.Index, key.signer)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = tx.SignEnvelope(serviceKey.Address, 0, serviceSigner)
+	keys, err := e.transactionSigningKeys(tx, payer, options)
 	if err != nil {
 		return err
 	}
 
+	for _, key := range keys {
+		err := tx.SignEnvelope(sdk.Address(payer), key.accountKey.Index, key.signer)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -735,8 +1459,13 @@ func newBlockchain(
 	hook *logCollectionHook,
 	opts ...emulator.Option,
 ) *emulator.Blockchain {
-	testLogger := logger.With().Timestamp().
-		Logger().Hook(hook).Level(zerolog.InfoLevel)
+	var writer io.Writer = io.Discard
+	if logger.GetLevel() != zerolog.Disabled {
+		writer = loggerWriter{
+			logger: logger,
+		}
+	}
+	testLogger := zerolog.New(writer).Hook(hook).Level(zerolog.InfoLevel)
 
 	b, err := emulator.New(
 		append(
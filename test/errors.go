@@ -19,6 +19,15 @@
 
 package test
 
+import (
+	goErrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
 // ImportResolverNotProvidedError is thrown if the import resolver is not
 // set in the TestRunner, when running tests.
 //
@@ -52,3 +61,148 @@ func (e FileResolverNotProvidedError) Unwrap() error {
 func (e FileResolverNotProvidedError) Error() string {
 	return "file resolver not provided"
 }
+
+// SetupError is returned if one of the `setup`, `tearDown`,
+// `beforeEach` or `afterEach` functions fails, when running tests.
+//
+type SetupError struct {
+	FunctionName string
+	Err          error
+}
+
+var _ error = &SetupError{}
+
+func (e *SetupError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SetupError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.FunctionName, e.Err.Error())
+}
+
+// FuzzError is returned if a fuzz test function fails for a generated input.
+// The input is shrunk, i.e. simplified while the test function still fails.
+//
+type FuzzError struct {
+	// Seed is the seed the inputs were generated with,
+	// which reproduces the failure when used as the random seed of the test runner.
+	Seed int64
+	// Input is the shrunk input, e.g. `amount=0.00000000`.
+	Input string
+	// OriginalInput is the generated input, before shrinking.
+	OriginalInput string
+	Err           error
+}
+
+var _ error = &FuzzError{}
+
+func (e *FuzzError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FuzzError) Error() string {
+	return fmt.Sprintf(
+		"failed for input (%s), seed %d: %s",
+		e.Input,
+		e.Seed,
+		e.Err.Error(),
+	)
+}
+
+// TransactionNotFoundError is returned if no transaction
+// with the given ID was executed by the EmulatorBackend.
+//
+type TransactionNotFoundError struct {
+	ID string
+}
+
+var _ error = TransactionNotFoundError{}
+
+func (e TransactionNotFoundError) Error() string {
+	return fmt.Sprintf("transaction not found: %s", e.ID)
+}
+
+// CoverageThresholdError is returned if the percentage of
+// the covered statements is below the minimum coverage.
+//
+type CoverageThresholdError struct {
+	Coverage float64
+	Minimum  float64
+}
+
+var _ error = &CoverageThresholdError{}
+
+func (e *CoverageThresholdError) Error() string {
+	return fmt.Sprintf(
+		"coverage of %.1f%% is below the minimum coverage of %.1f%%",
+		e.Coverage,
+		e.Minimum,
+	)
+}
+
+// ContractUpdateError is returned if a deployed contract could not be updated,
+// e.g. because the new code is incompatible with the stored data of the contract.
+//
+type ContractUpdateError struct {
+	Name string
+	Err  error
+}
+
+var _ error = &ContractUpdateError{}
+
+func (e *ContractUpdateError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ContractUpdateError) Error() string {
+	return fmt.Sprintf("failed to update contract %s: %s", e.Name, e.Err.Error())
+}
+
+// ValidationErrors returns the errors reported by the validation of the update,
+// e.g. a field type mismatch, if the update is invalid.
+func (e *ContractUpdateError) ValidationErrors() []error {
+	var updateErr *stdlib.ContractUpdateError
+	if !goErrors.As(e.Err, &updateErr) {
+		return nil
+	}
+	return updateErr.ChildErrors()
+}
+
+// ContractRemovalError is returned if a deployed contract could not be removed.
+//
+type ContractRemovalError struct {
+	Name string
+	Err  error
+}
+
+var _ error = &ContractRemovalError{}
+
+func (e *ContractRemovalError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ContractRemovalError) Error() string {
+	return fmt.Sprintf("failed to remove contract %s: %s", e.Name, e.Err.Error())
+}
+
+// ContractMockError is returned if the mock of a contract
+// does not conform to the public interface of the contract.
+//
+type ContractMockError struct {
+	Location common.Location
+	// Mismatches describe the public declarations of the contract
+	// which are missing in the mock, or differ.
+	Mismatches []string
+}
+
+var _ error = &ContractMockError{}
+
+func (e *ContractMockError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "mock of contract %s does not conform to the contract:", e.Location)
+	for _, mismatch := range e.Mismatches {
+		sb.WriteString("\n\t- ")
+		sb.WriteString(mismatch)
+	}
+	return sb.String()
+}
@@ -0,0 +1,175 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	"sort"
+
+	"github.com/onflow/cadence"
+	sdk "github.com/onflow/flow-go-sdk"
+)
+
+// IndexedEvent is an event emitted by a transaction of a committed block.
+type IndexedEvent struct {
+	BlockHeight      uint64
+	TransactionID    sdk.Identifier
+	TransactionIndex int
+	// EventIndex is the index of the event in the events emitted by the transaction.
+	EventIndex int
+	// Type is the type ID of the event, e.g. `A.0000000000000001.Foo.Bar`.
+	Type  string
+	Value cadence.Event
+}
+
+// EventFilter selects events from the EventStore.
+// The zero value of each field matches all events.
+type EventFilter struct {
+	// StartHeight and EndHeight are the inclusive range of block heights.
+	// An end height of zero is the height of the latest block.
+	StartHeight uint64
+	EndHeight   uint64
+
+	TransactionID sdk.Identifier
+
+	// Type is the type ID of the events.
+	Type string
+}
+
+// EventStore is an index of the events of the committed blocks,
+// which is maintained as blocks are committed, and rolled back.
+type EventStore struct {
+	// events are ordered by block height, transaction index and event index.
+	events []IndexedEvent
+
+	// byType and byTransaction map to the ordered indices of the events.
+	byType        map[string][]int
+	byTransaction map[sdk.Identifier][]int
+
+	// nextHeight is the height of the next block to index.
+	nextHeight uint64
+}
+
+func NewEventStore() *EventStore {
+	return &EventStore{
+		byType:        map[string][]int{},
+		byTransaction: map[sdk.Identifier][]int{},
+	}
+}
+
+// NextHeight returns the height of the next block to be indexed.
+func (s *EventStore) NextHeight() uint64 {
+	return s.nextHeight
+}
+
+// AddBlock indexes the events of the block with the given height,
+// which must be the next height.
+func (s *EventStore) AddBlock(height uint64, events []sdk.Event) {
+	for _, event := range events {
+		index := len(s.events)
+
+		s.events = append(s.events, IndexedEvent{
+			BlockHeight:      height,
+			TransactionID:    event.TransactionID,
+			TransactionIndex: event.TransactionIndex,
+			EventIndex:       event.EventIndex,
+			Type:             event.Type,
+			Value:            event.Value,
+		})
+
+		s.byType[event.Type] = append(s.byType[event.Type], index)
+		s.byTransaction[event.TransactionID] = append(s.byTransaction[event.TransactionID], index)
+	}
+
+	s.nextHeight = height + 1
+}
+
+// Truncate removes the events of all blocks above the given height,
+// e.g. when the blockchain is rolled back.
+func (s *EventStore) Truncate(height uint64) {
+	if height+1 >= s.nextHeight {
+		return
+	}
+
+	count := sort.Search(len(s.events), func(i int) bool {
+		return s.events[i].BlockHeight > height
+	})
+
+	for _, event := range s.events[count:] {
+		s.byType[event.Type] = truncateIndices(s.byType[event.Type], count)
+		s.byTransaction[event.TransactionID] = truncateIndices(s.byTransaction[event.TransactionID], count)
+	}
+
+	s.events = s.events[:count]
+	s.nextHeight = height + 1
+}
+
+// truncateIndices removes the indices which are not less than the given count.
+func truncateIndices(indices []int, count int) []int {
+	end := sort.SearchInts(indices, count)
+	if end == 0 {
+		return nil
+	}
+	return indices[:end]
+}
+
+// Query returns the events matching the given filter, in the order they were emitted.
+func (s *EventStore) Query(filter EventFilter) []IndexedEvent {
+	// Only scan the events of the given transaction or type, if any
+	var indices []int
+	switch {
+	case filter.TransactionID != sdk.EmptyID:
+		indices = s.byTransaction[filter.TransactionID]
+	case filter.Type != "":
+		indices = s.byType[filter.Type]
+	default:
+		indices = make([]int, len(s.events))
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+
+	// The indices are ordered by block height,
+	// so find the range of the given block heights
+	start := sort.Search(len(indices), func(i int) bool {
+		return s.events[indices[i]].BlockHeight >= filter.StartHeight
+	})
+	end := len(indices)
+	if filter.EndHeight > 0 {
+		end = sort.Search(len(indices), func(i int) bool {
+			return s.events[indices[i]].BlockHeight > filter.EndHeight
+		})
+	}
+	if end < start {
+		end = start
+	}
+
+	events := make([]IndexedEvent, 0)
+
+	for _, index := range indices[start:end] {
+		event := s.events[index]
+
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
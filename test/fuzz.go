@@ -0,0 +1,878 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// Fuzz test functions have the prefix `fuzz`, followed by an upper-case letter, and declare parameters,
+// for which inputs are generated randomly, based on the types of the parameters, e.g.:
+//
+//	access(all) fun fuzzTransfer(amount: UFix64, recipient: Address) {}
+//
+// The supported parameter types are integers, fixed-point numbers, booleans,
+// strings, characters, addresses, arrays, dictionaries, optionals,
+// and structs declared in the test script.
+//
+// If the test function fails for an input, the input is shrunk,
+// i.e. simplified while the test function still fails, and reported in a FuzzError.
+const fuzzFunctionPrefix = "fuzz"
+
+const defaultFuzzIterations = 100
+
+const (
+	// maxFuzzShrinkAttempts is the maximum number of
+	// inputs tried while shrinking a failing input.
+	maxFuzzShrinkAttempts = 1000
+
+	// maxFuzzSize is the maximum length of generated strings,
+	// and the maximum number of elements of generated arrays and dictionaries.
+	maxFuzzSize = 8
+
+	// maxFuzzDepth is the maximum nesting depth of generated values.
+	maxFuzzDepth = 4
+)
+
+// fuzzCorpusFileExtension is the file extension of corpus entries.
+// Each entry is a JSON-CDC encoded array of the arguments of a fuzz test function.
+const fuzzCorpusFileExtension = ".json"
+
+// isFuzzFunction returns true if the function with the given name is a fuzz test function,
+// i.e. its name is the prefix `fuzz`, followed by an upper-case letter, e.g. `fuzzTransfer`,
+// so that functions like `fuzzy` are not run as fuzz tests.
+func isFuzzFunction(funcName string) bool {
+	rest := strings.TrimPrefix(funcName, fuzzFunctionPrefix)
+	if rest == funcName {
+		return false
+	}
+
+	first, _ := utf8.DecodeRuneInString(rest)
+	return unicode.IsUpper(first)
+}
+
+// fuzzInput is the list of arguments of a single invocation of a fuzz test function.
+type fuzzInput []cadence.Value
+
+func (input fuzzInput) String(parameters []sema.Parameter) string {
+	var sb strings.Builder
+	for i, argument := range input {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(parameters[i].Identifier)
+		sb.WriteRune('=')
+		sb.WriteString(argument.String())
+	}
+	return sb.String()
+}
+
+// runFuzzTest runs the given fuzz test function for the inputs of the corpus,
+// and then for the configured number of randomly generated inputs, until it fails.
+// If isolation is enabled, each input is run against the state right before the fuzz test,
+// like each test function is run against the state after `setup()`.
+// Otherwise, the inputs are run one after another, and a failing input
+// is shrunk against the state it failed for.
+// Only failures of `beforeEach()` and `afterEach()` are returned as an error,
+// failures of the test function are returned as a FuzzError,
+// and parameter types which are not supported fail the test function.
+func (r *TestRunner) runFuzzTest(inter *interpreter.Interpreter, funcName string) (testErr error, err error) {
+	functionVariable, ok := inter.Program.Elaboration.GetGlobalValue(funcName)
+	if !ok {
+		return nil, fmt.Errorf("unknown fuzz test function `%s`", funcName)
+	}
+	parameters := functionVariable.Type.(*sema.FunctionType).Parameters
+
+	var snapshot *testSnapshot
+	if r.isolation {
+		snapshot, err = r.createSnapshot(inter.Program, inter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hasRun := false
+
+	run := func(input fuzzInput) (testErr error, err error) {
+		if snapshot != nil && hasRun {
+			err = r.restoreSnapshot(snapshot, inter)
+			if err != nil {
+				return nil, err
+			}
+		}
+		hasRun = true
+
+		arguments := make([]interpreter.Value, 0, len(input))
+		for i, argument := range input {
+			value, err := runtime.ImportValue(
+				inter,
+				interpreter.EmptyLocationRange,
+				r.backend.stdlibHandler,
+				argument,
+				parameters[i].TypeAnnotation.Type,
+			)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, value)
+		}
+
+		return r.invokeTestCase(inter, funcName, arguments)
+	}
+
+	seed := r.randomSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	corpus, err := r.fuzzCorpus(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	iterations := r.fuzzIterations
+	if iterations < 1 {
+		iterations = defaultFuzzIterations
+	}
+	// The inputs of a fuzz test function without parameters are all the same
+	if len(parameters) == 0 {
+		iterations = 1
+	}
+
+	fuzzer := newFuzzer(seed, funcName)
+
+	for i := 0; i < len(corpus)+iterations; i++ {
+		var input fuzzInput
+		if i < len(corpus) {
+			input = corpus[i]
+		} else {
+			input, err = fuzzer.generateInput(parameters)
+			if err != nil {
+				return fmt.Errorf("cannot fuzz test function `%s`: %w", funcName, err), nil
+			}
+		}
+
+		// Without isolation, the state before each input is kept,
+		// so that shrinking a failing input runs against the same state.
+		if !r.isolation {
+			snapshot, err = r.createSnapshot(inter.Program, inter)
+			if err != nil {
+				return nil, err
+			}
+			hasRun = false
+		}
+
+		testErr, err = run(input)
+		if err != nil {
+			return nil, err
+		}
+		if testErr == nil {
+			continue
+		}
+
+		shrunkInput, shrunkErr, err := fuzzer.shrinkInput(parameters, input, testErr, run)
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.addToFuzzCorpus(funcName, shrunkInput)
+		if err != nil {
+			return nil, err
+		}
+
+		return &FuzzError{
+			Seed:          seed,
+			Input:         shrunkInput.String(parameters),
+			OriginalInput: input.String(parameters),
+			Err:           shrunkErr,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// fuzzCorpus returns the inputs of the corpus of the given fuzz test function,
+// which are stored in a directory named after the function, in the corpus directory.
+func (r *TestRunner) fuzzCorpus(funcName string) ([]fuzzInput, error) {
+	if r.fuzzCorpusDir == "" {
+		return nil, nil
+	}
+
+	dir := filepath.Join(r.fuzzCorpusDir, funcName)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Replay the inputs in a deterministic order
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var corpus []fuzzInput
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fuzzCorpusFileExtension {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Decode(nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fuzz corpus entry %s: %w", path, err)
+		}
+
+		array, ok := value.(cadence.Array)
+		if !ok {
+			return nil, fmt.Errorf("invalid fuzz corpus entry %s: expected array of arguments", path)
+		}
+
+		corpus = append(corpus, array.Values)
+	}
+
+	return corpus, nil
+}
+
+// addToFuzzCorpus adds the given failing input to the corpus of the given fuzz test function,
+// so it is replayed by future runs.
+func (r *TestRunner) addToFuzzCorpus(funcName string, input fuzzInput) error {
+	if r.fuzzCorpusDir == "" {
+		return nil
+	}
+
+	data, err := json.Encode(cadence.NewArray(input))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(r.fuzzCorpusDir, funcName)
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+	name := hex.EncodeToString(hash[:8]) + fuzzCorpusFileExtension
+
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// fuzzer generates and shrinks inputs of fuzz test functions.
+type fuzzer struct {
+	rng *rand.Rand
+}
+
+// newFuzzer returns a fuzzer for the given fuzz test function.
+// The inputs only depend on the seed and the name of the function,
+// so they do not depend on the order in which the test functions are run.
+func newFuzzer(seed int64, funcName string) *fuzzer {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(funcName))
+
+	return &fuzzer{
+		rng: rand.New(rand.NewSource(seed ^ int64(hash.Sum64()))),
+	}
+}
+
+func (f *fuzzer) generateInput(parameters []sema.Parameter) (fuzzInput, error) {
+	input := make(fuzzInput, 0, len(parameters))
+
+	for _, parameter := range parameters {
+		value, err := f.generate(parameter.TypeAnnotation.Type, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parameter `%s`: %w", parameter.Identifier, err)
+		}
+		input = append(input, value)
+	}
+
+	return input, nil
+}
+
+// shrinkInput repeatedly replaces an argument of the given failing input
+// with a simpler one, as long as the test function still fails.
+// It returns the shrunk input and its failure.
+func (f *fuzzer) shrinkInput(
+	parameters []sema.Parameter,
+	input fuzzInput,
+	testErr error,
+	run func(input fuzzInput) (testErr error, err error),
+) (fuzzInput, error, error) {
+	attempts := 0
+
+	for {
+		shrunk := false
+
+	Arguments:
+		for i, parameter := range parameters {
+			for _, candidate := range f.shrink(parameter.TypeAnnotation.Type, input[i]) {
+				if attempts >= maxFuzzShrinkAttempts {
+					return input, testErr, nil
+				}
+				attempts++
+
+				candidateInput := make(fuzzInput, len(input))
+				copy(candidateInput, input)
+				candidateInput[i] = candidate
+
+				candidateErr, err := run(candidateInput)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				if candidateErr != nil {
+					input = candidateInput
+					testErr = candidateErr
+					shrunk = true
+					break Arguments
+				}
+			}
+		}
+
+		if !shrunk {
+			return input, testErr, nil
+		}
+	}
+}
+
+const fuzzStringCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-.,:/\\\"'\n\té€😀"
+
+func (f *fuzzer) generateCharacter() string {
+	characters := []rune(fuzzStringCharacters)
+	return string(characters[f.rng.Intn(len(characters))])
+}
+
+// generate returns a random value of the given type.
+func (f *fuzzer) generate(ty sema.Type, depth int) (cadence.Value, error) {
+	switch ty := ty.(type) {
+	case *sema.OptionalType:
+		if depth >= maxFuzzDepth || f.rng.Intn(4) == 0 {
+			return cadence.NewOptional(nil), nil
+		}
+		value, err := f.generate(ty.Type, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return cadence.NewOptional(value), nil
+
+	case *sema.VariableSizedType:
+		size := 0
+		if depth < maxFuzzDepth {
+			size = f.rng.Intn(maxFuzzSize + 1)
+		}
+		return f.generateArray(ty, size, depth)
+
+	case *sema.ConstantSizedType:
+		return f.generateArray(ty, int(ty.Size), depth)
+
+	case *sema.DictionaryType:
+		return f.generateDictionary(ty, depth)
+
+	case *sema.CompositeType:
+		return f.generateStruct(ty, depth)
+
+	case *sema.AddressType:
+		var address cadence.Address
+		// Prefer the zero address, which is often a special case
+		if f.rng.Intn(8) != 0 {
+			f.rng.Read(address[:])
+		}
+		return address, nil
+	}
+
+	switch ty {
+	case sema.BoolType:
+		return cadence.NewBool(f.rng.Intn(2) == 0), nil
+
+	case sema.StringType:
+		var sb strings.Builder
+		length := f.rng.Intn(maxFuzzSize*2 + 1)
+		for i := 0; i < length; i++ {
+			sb.WriteString(f.generateCharacter())
+		}
+		return cadence.NewString(sb.String())
+
+	case sema.CharacterType:
+		return cadence.NewCharacter(f.generateCharacter())
+
+	case sema.Fix64Type:
+		return cadence.Fix64(f.generateFix64()), nil
+
+	case sema.UFix64Type:
+		return cadence.UFix64(f.generateUFix64()), nil
+	}
+
+	if integerType, ok := ty.(sema.IntegerRangedType); ok && sema.IsSubType(ty, sema.IntegerType) {
+		return newIntegerValue(ty, f.generateInteger(integerType))
+	}
+
+	return nil, fmt.Errorf("unsupported type `%s`", ty.QualifiedString())
+}
+
+func (f *fuzzer) generateArray(ty sema.ArrayType, size int, depth int) (cadence.Value, error) {
+	values := make([]cadence.Value, 0, size)
+	for i := 0; i < size; i++ {
+		value, err := f.generate(ty.ElementType(false), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	arrayType := runtime.ExportType(ty, map[sema.TypeID]cadence.Type{}).(cadence.ArrayType)
+	return cadence.NewArray(values).WithType(arrayType), nil
+}
+
+func (f *fuzzer) generateDictionary(ty *sema.DictionaryType, depth int) (cadence.Value, error) {
+	size := 0
+	if depth < maxFuzzDepth {
+		size = f.rng.Intn(maxFuzzSize + 1)
+	}
+
+	keys := map[string]struct{}{}
+	pairs := make([]cadence.KeyValuePair, 0, size)
+
+	for i := 0; i < size; i++ {
+		key, err := f.generate(ty.KeyType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip duplicate keys
+		if _, ok := keys[key.String()]; ok {
+			continue
+		}
+		keys[key.String()] = struct{}{}
+
+		value, err := f.generate(ty.ValueType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, cadence.KeyValuePair{
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	dictionaryType := runtime.ExportType(ty, map[sema.TypeID]cadence.Type{}).(*cadence.DictionaryType)
+	return cadence.NewDictionary(pairs).WithType(dictionaryType), nil
+}
+
+func (f *fuzzer) generateStruct(ty *sema.CompositeType, depth int) (cadence.Value, error) {
+	if ty.Kind != common.CompositeKindStructure || ty.Location == nil {
+		return nil, fmt.Errorf("unsupported type `%s`", ty.QualifiedString())
+	}
+
+	fields := make([]cadence.Value, 0, len(ty.Fields))
+	for _, fieldName := range ty.Fields {
+		member, ok := ty.Members.Get(fieldName)
+		if !ok {
+			continue
+		}
+
+		value, err := f.generate(member.TypeAnnotation.Type, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("field `%s` of `%s`: %w", fieldName, ty.QualifiedString(), err)
+		}
+		fields = append(fields, value)
+	}
+
+	structType := runtime.ExportType(ty, map[sema.TypeID]cadence.Type{}).(*cadence.StructType)
+	return cadence.NewStruct(fields).WithType(structType), nil
+}
+
+var fuzzUnboundedIntegerLimit = new(big.Int).Lsh(big.NewInt(1), 64)
+
+// generateInteger returns a random integer in the range of the given integer type,
+// preferring edge cases and small integers.
+func (f *fuzzer) generateInteger(ty sema.IntegerRangedType) *big.Int {
+	min := ty.MinInt()
+	if min == nil {
+		min = new(big.Int).Neg(fuzzUnboundedIntegerLimit)
+	}
+	max := ty.MaxInt()
+	if max == nil {
+		max = fuzzUnboundedIntegerLimit
+	}
+
+	var value *big.Int
+
+	switch f.rng.Intn(4) {
+	case 0:
+		edgeCases := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(-1), min, max}
+		value = edgeCases[f.rng.Intn(len(edgeCases))]
+
+	case 1:
+		value = big.NewInt(int64(f.rng.Intn(201) - 100))
+
+	default:
+		span := new(big.Int).Sub(max, min)
+		span.Add(span, big.NewInt(1))
+		value = new(big.Int).Rand(f.rng, span)
+		value.Add(value, min)
+	}
+
+	// Clamp the edge cases and small integers to the range of the type
+	if value.Cmp(min) < 0 {
+		return new(big.Int).Set(min)
+	}
+	if value.Cmp(max) > 0 {
+		return new(big.Int).Set(max)
+	}
+	return new(big.Int).Set(value)
+}
+
+const fuzzFixedPointFactor = 100_000_000
+
+// generateFix64 returns the raw integer value of a random Fix64 number,
+// preferring edge cases and small numbers.
+func (f *fuzzer) generateFix64() int64 {
+	switch f.rng.Intn(4) {
+	case 0:
+		edgeCases := []int64{0, 1, fuzzFixedPointFactor, -fuzzFixedPointFactor, math.MinInt64, math.MaxInt64}
+		return edgeCases[f.rng.Intn(len(edgeCases))]
+	case 1:
+		return f.rng.Int63n(200*fuzzFixedPointFactor) - 100*fuzzFixedPointFactor
+	default:
+		return int64(f.rng.Uint64())
+	}
+}
+
+// generateUFix64 returns the raw integer value of a random UFix64 number,
+// preferring edge cases and small numbers.
+func (f *fuzzer) generateUFix64() uint64 {
+	switch f.rng.Intn(4) {
+	case 0:
+		edgeCases := []uint64{0, 1, fuzzFixedPointFactor, math.MaxUint64}
+		return edgeCases[f.rng.Intn(len(edgeCases))]
+	case 1:
+		return uint64(f.rng.Int63n(100 * fuzzFixedPointFactor))
+	default:
+		return f.rng.Uint64()
+	}
+}
+
+// shrink returns simpler values of the given type than the given value,
+// the simplest first.
+func (f *fuzzer) shrink(ty sema.Type, value cadence.Value) []cadence.Value {
+	switch value := value.(type) {
+	case cadence.Optional:
+		if value.Value == nil {
+			return nil
+		}
+		optionalType, ok := ty.(*sema.OptionalType)
+		if !ok {
+			return nil
+		}
+		candidates := []cadence.Value{cadence.NewOptional(nil)}
+		for _, candidate := range f.shrink(optionalType.Type, value.Value) {
+			candidates = append(candidates, cadence.NewOptional(candidate))
+		}
+		return candidates
+
+	case cadence.Array:
+		arrayType, ok := ty.(sema.ArrayType)
+		if !ok {
+			return nil
+		}
+		return f.shrinkArray(arrayType, value)
+
+	case cadence.Dictionary:
+		dictionaryType, ok := ty.(*sema.DictionaryType)
+		if !ok {
+			return nil
+		}
+		return f.shrinkDictionary(dictionaryType, value)
+
+	case cadence.Struct:
+		compositeType, ok := ty.(*sema.CompositeType)
+		if !ok {
+			return nil
+		}
+		return f.shrinkStruct(compositeType, value)
+
+	case cadence.Bool:
+		if value {
+			return []cadence.Value{cadence.NewBool(false)}
+		}
+		return nil
+
+	case cadence.String:
+		return shrinkString(string(value), func(s string) cadence.Value {
+			return cadence.String(s)
+		})
+
+	case cadence.Character:
+		if value != "a" {
+			return []cadence.Value{cadence.Character("a")}
+		}
+		return nil
+
+	case cadence.Address:
+		if value != (cadence.Address{}) {
+			return []cadence.Value{cadence.Address{}}
+		}
+		return nil
+
+	case cadence.Fix64:
+		var candidates []cadence.Value
+		for _, candidate := range shrinkFixedPoint(big.NewInt(int64(value))) {
+			candidates = append(candidates, cadence.Fix64(candidate.Int64()))
+		}
+		return candidates
+
+	case cadence.UFix64:
+		var candidates []cadence.Value
+		for _, candidate := range shrinkFixedPoint(new(big.Int).SetUint64(uint64(value))) {
+			candidates = append(candidates, cadence.UFix64(candidate.Uint64()))
+		}
+		return candidates
+	}
+
+	integer, ok := new(big.Int).SetString(value.String(), 10)
+	if !ok {
+		return nil
+	}
+
+	var candidates []cadence.Value
+	for _, candidate := range shrinkInteger(integer) {
+		candidateValue, err := newIntegerValue(ty, candidate)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidateValue)
+	}
+	return candidates
+}
+
+func (f *fuzzer) shrinkArray(ty sema.ArrayType, value cadence.Array) []cadence.Value {
+	var candidates []cadence.Value
+
+	newArray := func(values []cadence.Value) cadence.Value {
+		return cadence.NewArray(values).WithType(value.ArrayType)
+	}
+
+	// Constant-sized arrays can only shrink their elements
+	if _, ok := ty.(*sema.VariableSizedType); ok && len(value.Values) > 0 {
+		candidates = append(candidates, newArray(nil))
+
+		if len(value.Values) > 1 {
+			candidates = append(candidates, newArray(value.Values[:len(value.Values)/2]))
+		}
+
+		for i := range value.Values {
+			values := make([]cadence.Value, 0, len(value.Values)-1)
+			values = append(values, value.Values[:i]...)
+			values = append(values, value.Values[i+1:]...)
+			candidates = append(candidates, newArray(values))
+		}
+	}
+
+	for i, element := range value.Values {
+		for _, candidate := range f.shrink(ty.ElementType(false), element) {
+			values := make([]cadence.Value, len(value.Values))
+			copy(values, value.Values)
+			values[i] = candidate
+			candidates = append(candidates, newArray(values))
+		}
+	}
+
+	return candidates
+}
+
+func (f *fuzzer) shrinkDictionary(ty *sema.DictionaryType, value cadence.Dictionary) []cadence.Value {
+	var candidates []cadence.Value
+
+	newDictionary := func(pairs []cadence.KeyValuePair) cadence.Value {
+		return cadence.NewDictionary(pairs).WithType(value.DictionaryType)
+	}
+
+	if len(value.Pairs) > 0 {
+		candidates = append(candidates, newDictionary(nil))
+	}
+
+	for i := range value.Pairs {
+		pairs := make([]cadence.KeyValuePair, 0, len(value.Pairs)-1)
+		pairs = append(pairs, value.Pairs[:i]...)
+		pairs = append(pairs, value.Pairs[i+1:]...)
+		candidates = append(candidates, newDictionary(pairs))
+	}
+
+	for i, pair := range value.Pairs {
+		for _, candidate := range f.shrink(ty.ValueType, pair.Value) {
+			pairs := make([]cadence.KeyValuePair, len(value.Pairs))
+			copy(pairs, value.Pairs)
+			pairs[i].Value = candidate
+			candidates = append(candidates, newDictionary(pairs))
+		}
+	}
+
+	return candidates
+}
+
+func (f *fuzzer) shrinkStruct(ty *sema.CompositeType, value cadence.Struct) []cadence.Value {
+	var candidates []cadence.Value
+
+	for i, field := range value.StructType.Fields {
+		member, ok := ty.Members.Get(field.Identifier)
+		if !ok {
+			continue
+		}
+
+		for _, candidate := range f.shrink(member.TypeAnnotation.Type, value.Fields[i]) {
+			fields := make([]cadence.Value, len(value.Fields))
+			copy(fields, value.Fields)
+			fields[i] = candidate
+			candidates = append(candidates, cadence.NewStruct(fields).WithType(value.StructType))
+		}
+	}
+
+	return candidates
+}
+
+func shrinkString(s string, newValue func(string) cadence.Value) []cadence.Value {
+	length := utf8.RuneCountInString(s)
+	if length == 0 {
+		return nil
+	}
+
+	runes := []rune(s)
+
+	candidates := []cadence.Value{newValue("")}
+	if length > 1 {
+		candidates = append(
+			candidates,
+			newValue(string(runes[:length/2])),
+			newValue(string(runes[1:])),
+			newValue(string(runes[:length-1])),
+		)
+	}
+	return candidates
+}
+
+// shrinkInteger returns the shrink candidates of the given integer:
+// zero, and integers approaching the given integer, e.g. for 100: 0, 50, 75, 88, ..., 99.
+func shrinkInteger(value *big.Int) []*big.Int {
+	if value.Sign() == 0 {
+		return nil
+	}
+
+	candidates := []*big.Int{big.NewInt(0)}
+
+	delta := new(big.Int).Quo(value, big.NewInt(2))
+	for delta.Sign() != 0 {
+		candidates = append(candidates, new(big.Int).Sub(value, delta))
+		delta.Quo(delta, big.NewInt(2))
+	}
+
+	return candidates
+}
+
+// shrinkFixedPoint returns the shrink candidates of the raw integer value of a fixed-point number.
+// Whole numbers are preferred over fractional numbers.
+func shrinkFixedPoint(value *big.Int) []*big.Int {
+	candidates := shrinkInteger(value)
+
+	whole := new(big.Int).Rem(value, big.NewInt(fuzzFixedPointFactor))
+	whole.Sub(value, whole)
+	if len(candidates) > 0 && whole.Sign() != 0 && whole.Cmp(value) != 0 {
+		candidates = append(candidates[:1], append([]*big.Int{whole}, candidates[1:]...)...)
+	}
+
+	return candidates
+}
+
+// newIntegerValue returns the value of the given integer type for the given integer.
+func newIntegerValue(ty sema.Type, value *big.Int) (cadence.Value, error) {
+	switch ty {
+	case sema.IntType:
+		return cadence.NewIntFromBig(value), nil
+	case sema.Int8Type:
+		return cadence.Int8(value.Int64()), nil
+	case sema.Int16Type:
+		return cadence.Int16(value.Int64()), nil
+	case sema.Int32Type:
+		return cadence.Int32(value.Int64()), nil
+	case sema.Int64Type:
+		return cadence.Int64(value.Int64()), nil
+	case sema.Int128Type:
+		return cadence.NewInt128FromBig(value)
+	case sema.Int256Type:
+		return cadence.NewInt256FromBig(value)
+	case sema.UIntType:
+		return cadence.NewUIntFromBig(value)
+	case sema.UInt8Type:
+		return cadence.UInt8(value.Uint64()), nil
+	case sema.UInt16Type:
+		return cadence.UInt16(value.Uint64()), nil
+	case sema.UInt32Type:
+		return cadence.UInt32(value.Uint64()), nil
+	case sema.UInt64Type:
+		return cadence.UInt64(value.Uint64()), nil
+	case sema.UInt128Type:
+		return cadence.NewUInt128FromBig(value)
+	case sema.UInt256Type:
+		return cadence.NewUInt256FromBig(value)
+	case sema.Word8Type:
+		return cadence.Word8(value.Uint64()), nil
+	case sema.Word16Type:
+		return cadence.Word16(value.Uint64()), nil
+	case sema.Word32Type:
+		return cadence.Word32(value.Uint64()), nil
+	case sema.Word64Type:
+		return cadence.Word64(value.Uint64()), nil
+	case sema.Word128Type:
+		return cadence.NewWord128FromBig(value)
+	case sema.Word256Type:
+		return cadence.NewWord256FromBig(value)
+	}
+
+	return nil, fmt.Errorf("unsupported type `%s`", ty.QualifiedString())
+}
@@ -0,0 +1,453 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/stdlib"
+	sdk "github.com/onflow/flow-go-sdk"
+	fvmCrypto "github.com/onflow/flow-go/fvm/crypto"
+
+	"github.com/onflow/cadence-tools/test/helpers"
+)
+
+// helperFunctions returns the implementations of the native functions
+// of the BlockchainHelpers, see helpers.NativeFunctions.
+// The functions access the backend directly, as they are only
+// available to the BlockchainHelpers of the test script.
+func (e *EmulatorBackend) helperFunctions() []stdlib.StandardLibraryValue {
+	return []stdlib.StandardLibraryValue{
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionIDsFunctionName,
+			helpers.TransactionIDsFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+
+				values := make([]interpreter.Value, 0, len(e.transactions))
+				for _, transaction := range e.transactions {
+					values = append(values, interpreter.NewUnmeteredStringValue(transaction.ID.String()))
+				}
+
+				return interpreter.NewArrayValue(
+					inter,
+					invocation.LocationRange,
+					interpreter.NewVariableSizedStaticType(
+						inter,
+						interpreter.NewPrimitiveStaticType(inter, interpreter.PrimitiveStaticTypeString),
+					),
+					common.ZeroAddress,
+					values...,
+				)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionErrorFunctionName,
+			helpers.TransactionErrorFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				transaction := e.invokedTransaction(invocation)
+				return optionalErrorMessage(invocation.Interpreter, transaction.Error)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionComputationUsedFunctionName,
+			helpers.TransactionComputationUsedFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				transaction := e.invokedTransaction(invocation)
+				return interpreter.NewUnmeteredUInt64Value(transaction.ComputationUsed)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionMemoryEstimateFunctionName,
+			helpers.TransactionMemoryEstimateFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				transaction := e.invokedTransaction(invocation)
+				return interpreter.NewUnmeteredUInt64Value(transaction.MemoryEstimate)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionEventsFunctionName,
+			helpers.TransactionEventsFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+				transaction := e.invokedTransaction(invocation)
+
+				values := make([]interpreter.Value, 0, len(transaction.Events))
+				for _, event := range transaction.Events {
+					value, err := runtime.ImportValue(
+						inter,
+						invocation.LocationRange,
+						e.stdlibHandler,
+						event.Value,
+						nil,
+					)
+					if err != nil {
+						panic(err)
+					}
+					values = append(values, value)
+				}
+
+				return interpreter.NewArrayValue(
+					inter,
+					invocation.LocationRange,
+					interpreter.NewVariableSizedStaticType(
+						inter,
+						interpreter.NewPrimitiveStaticType(inter, interpreter.PrimitiveStaticTypeAnyStruct),
+					),
+					common.ZeroAddress,
+					values...,
+				)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.TransactionLogsFunctionName,
+			helpers.TransactionLogsFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+				transaction := e.invokedTransaction(invocation)
+
+				values := make([]interpreter.Value, 0, len(transaction.Logs))
+				for _, log := range transaction.Logs {
+					values = append(values, interpreter.NewUnmeteredStringValue(log))
+				}
+
+				return interpreter.NewArrayValue(
+					inter,
+					invocation.LocationRange,
+					interpreter.NewVariableSizedStaticType(
+						inter,
+						interpreter.NewPrimitiveStaticType(inter, interpreter.PrimitiveStaticTypeString),
+					),
+					common.ZeroAddress,
+					values...,
+				)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.NewAccountFunctionName,
+			helpers.NewAccountFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+				locationRange := invocation.LocationRange
+
+				weights := arrayElements(inter, locationRange, invocation.Arguments[0])
+				signatureAlgorithms := arrayElements(inter, locationRange, invocation.Arguments[1])
+				hashAlgorithms := arrayElements(inter, locationRange, invocation.Arguments[2])
+
+				keys := make([]AccountKeyOptions, 0, len(weights))
+				for i, weight := range weights {
+					weight := weight.(interpreter.UFix64Value)
+					// Key weights are integers, so reject fractional weights instead of truncating them
+					if weight%sema.Fix64Factor != 0 {
+						panic(fmt.Errorf(
+							"invalid weight of key %d: expected a whole number, got %s",
+							i,
+							weight,
+						))
+					}
+
+					signatureAlgorithm := signatureAlgorithms[i].(interpreter.UInt8Value)
+					hashAlgorithm := hashAlgorithms[i].(interpreter.UInt8Value)
+
+					keys = append(keys, AccountKeyOptions{
+						Weight: int(weight / sema.Fix64Factor),
+						SignatureAlgorithm: fvmCrypto.RuntimeToCryptoSigningAlgorithm(
+							sema.SignatureAlgorithm(signatureAlgorithm),
+						),
+						HashAlgorithm: fvmCrypto.RuntimeToCryptoHashingAlgorithm(
+							sema.HashAlgorithm(hashAlgorithm),
+						),
+					})
+				}
+
+				account, err := e.CreateAccountWithKeys(keys)
+				if err != nil {
+					panic(err)
+				}
+
+				return interpreter.NewAddressValue(inter, account.Address)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetProposerFunctionName,
+			helpers.SetProposerFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				address := invocation.Arguments[0].(interpreter.AddressValue).ToAddress()
+				keyIndex := invocation.Arguments[1].(interpreter.IntValue).ToInt(invocation.LocationRange)
+
+				e.nextTransactionOptions.Proposer = &address
+				e.nextTransactionOptions.ProposerKeyIndex = keyIndex
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetPayerFunctionName,
+			helpers.SetPayerFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				address := invocation.Arguments[0].(interpreter.AddressValue).ToAddress()
+
+				e.nextTransactionOptions.Payer = &address
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetSigningKeysFunctionName,
+			helpers.SetSigningKeysFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+				locationRange := invocation.LocationRange
+
+				address := invocation.Arguments[0].(interpreter.AddressValue).ToAddress()
+
+				elements := arrayElements(inter, locationRange, invocation.Arguments[1])
+				keyIndices := make([]int, 0, len(elements))
+				for _, element := range elements {
+					keyIndices = append(keyIndices, element.(interpreter.IntValue).ToInt(locationRange))
+				}
+
+				if e.nextTransactionOptions.SigningKeys == nil {
+					e.nextTransactionOptions.SigningKeys = map[common.Address][]int{}
+				}
+				e.nextTransactionOptions.SigningKeys[address] = keyIndices
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.UpdateDeployedContractFunctionName,
+			helpers.UpdateDeployedContractFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				name := invocation.Arguments[0].(*interpreter.StringValue).Str
+				path := invocation.Arguments[1].(*interpreter.StringValue).Str
+
+				err := e.UpdateContract(name, path)
+				return optionalErrorMessage(invocation.Interpreter, err)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.RemoveDeployedContractFunctionName,
+			helpers.RemoveDeployedContractFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				name := invocation.Arguments[0].(*interpreter.StringValue).Str
+
+				err := e.RemoveContract(name)
+				return optionalErrorMessage(invocation.Interpreter, err)
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetBlockTimestampFunctionName,
+			helpers.SetBlockTimestampFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				timestamp := ufix64Duration(invocation.Arguments[0].(interpreter.UFix64Value))
+
+				err := e.SetTimestamp(time.Unix(0, 0).Add(timestamp))
+				if err != nil {
+					panic(err)
+				}
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetBlockIntervalFunctionName,
+			helpers.SetBlockIntervalFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				interval := ufix64Duration(invocation.Arguments[0].(interpreter.UFix64Value))
+
+				err := e.SetBlockInterval(interval)
+				if err != nil {
+					panic(err)
+				}
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.SetRandomValuesFunctionName,
+			helpers.SetRandomValuesFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				elements := arrayElements(
+					invocation.Interpreter,
+					invocation.LocationRange,
+					invocation.Arguments[0],
+				)
+				values := make([]uint64, 0, len(elements))
+				for _, element := range elements {
+					values = append(values, uint64(element.(interpreter.UInt64Value)))
+				}
+
+				e.SetNextRandomValues(values)
+
+				return interpreter.Void
+			},
+		),
+		stdlib.NewStandardLibraryFunction(
+			helpers.QueryEventsFunctionName,
+			helpers.QueryEventsFunctionType,
+			"",
+			func(invocation interpreter.Invocation) interpreter.Value {
+				inter := invocation.Interpreter
+				locationRange := invocation.LocationRange
+
+				filter := EventFilter{
+					StartHeight: uint64(invocation.Arguments[1].(interpreter.UInt64Value)),
+					EndHeight:   uint64(invocation.Arguments[2].(interpreter.UInt64Value)),
+				}
+				if eventType, ok := optionalString(inter, locationRange, invocation.Arguments[0]); ok {
+					filter.Type = eventType
+				}
+				if id, ok := optionalString(inter, locationRange, invocation.Arguments[3]); ok {
+					filter.TransactionID = sdk.HexToID(id)
+				}
+
+				events, err := e.QueryEvents(filter)
+				if err != nil {
+					panic(err)
+				}
+
+				anyStructArrayType := interpreter.NewVariableSizedStaticType(
+					inter,
+					interpreter.NewPrimitiveStaticType(inter, interpreter.PrimitiveStaticTypeAnyStruct),
+				)
+
+				values := make([]interpreter.Value, 0, len(events))
+				for _, event := range events {
+					value, err := runtime.ImportValue(
+						inter,
+						locationRange,
+						e.stdlibHandler,
+						event.Value,
+						nil,
+					)
+					if err != nil {
+						panic(err)
+					}
+
+					values = append(values, interpreter.NewArrayValue(
+						inter,
+						locationRange,
+						anyStructArrayType,
+						common.ZeroAddress,
+						interpreter.NewUnmeteredUInt64Value(event.BlockHeight),
+						interpreter.NewUnmeteredStringValue(event.TransactionID.String()),
+						interpreter.NewUnmeteredIntValueFromInt64(int64(event.TransactionIndex)),
+						interpreter.NewUnmeteredIntValueFromInt64(int64(event.EventIndex)),
+						value,
+					))
+				}
+
+				return interpreter.NewArrayValue(
+					inter,
+					locationRange,
+					interpreter.NewVariableSizedStaticType(inter, anyStructArrayType),
+					common.ZeroAddress,
+					values...,
+				)
+			},
+		),
+	}
+}
+
+// ufix64Duration returns the duration of the given number of seconds.
+func ufix64Duration(seconds interpreter.UFix64Value) time.Duration {
+	// UFix64 values have 8 decimal places, i.e. a resolution of 10 nanoseconds.
+	return time.Duration(seconds) * (time.Second / sema.Fix64Factor)
+}
+
+// optionalErrorMessage returns the message of the given error,
+// as an optional string value, which is nil if there is no error.
+func optionalErrorMessage(inter *interpreter.Interpreter, err error) interpreter.Value {
+	if err == nil {
+		return interpreter.Nil
+	}
+
+	return interpreter.NewSomeValueNonCopying(
+		inter,
+		interpreter.NewUnmeteredStringValue(err.Error()),
+	)
+}
+
+// optionalString returns the string of the given optional string value,
+// and whether the value is not nil.
+func optionalString(
+	inter *interpreter.Interpreter,
+	locationRange interpreter.LocationRange,
+	value interpreter.Value,
+) (string, bool) {
+	someValue, ok := value.(*interpreter.SomeValue)
+	if !ok {
+		return "", false
+	}
+
+	return someValue.InnerValue(inter, locationRange).(*interpreter.StringValue).Str, true
+}
+
+// arrayElements returns the elements of the given array value.
+func arrayElements(
+	inter *interpreter.Interpreter,
+	locationRange interpreter.LocationRange,
+	value interpreter.Value,
+) []interpreter.Value {
+	array := value.(*interpreter.ArrayValue)
+
+	elements := make([]interpreter.Value, 0, array.Count())
+	for i := 0; i < array.Count(); i++ {
+		elements = append(elements, array.Get(inter, locationRange, i))
+	}
+
+	return elements
+}
+
+// invokedTransaction returns the details of the transaction
+// with the ID given as the first argument of the invocation.
+func (e *EmulatorBackend) invokedTransaction(invocation interpreter.Invocation) *TransactionDetails {
+	id, ok := invocation.Arguments[0].(*interpreter.StringValue)
+	if !ok {
+		panic(fmt.Errorf("invalid transaction ID argument: expected `%s`", sema.StringType))
+	}
+
+	transaction, err := e.TransactionDetails(sdk.HexToID(id.Str))
+	if err != nil {
+		panic(err)
+	}
+
+	return transaction
+}
@@ -34,6 +34,309 @@ var BlockchainHelpers []byte
 
 const BlockchainHelpersLocation = common.IdentifierLocation("BlockchainHelpers")
 
+const (
+	TransactionIDsFunctionName             = "transactionIDs"
+	TransactionErrorFunctionName           = "transactionError"
+	TransactionComputationUsedFunctionName = "transactionComputationUsed"
+	TransactionMemoryEstimateFunctionName  = "transactionMemoryEstimate"
+	TransactionEventsFunctionName          = "transactionEvents"
+	TransactionLogsFunctionName            = "transactionLogs"
+	NewAccountFunctionName                 = "newAccount"
+	SetProposerFunctionName                = "setProposer"
+	SetPayerFunctionName                   = "setPayer"
+	SetSigningKeysFunctionName             = "setSigningKeys"
+	UpdateDeployedContractFunctionName     = "updateDeployedContract"
+	RemoveDeployedContractFunctionName     = "removeDeployedContract"
+	SetBlockTimestampFunctionName          = "setBlockTimestamp"
+	SetBlockIntervalFunctionName           = "setBlockInterval"
+	SetRandomValuesFunctionName            = "setRandomValues"
+	QueryEventsFunctionName                = "queryEvents"
+)
+
+// transactionIDParameters are the parameters of the native functions
+// which return the details of an executed transaction.
+var transactionIDParameters = []sema.Parameter{
+	{
+		Label:          sema.ArgumentLabelNotRequired,
+		Identifier:     "id",
+		TypeAnnotation: sema.NewTypeAnnotation(sema.StringType),
+	},
+}
+
+// TransactionIDsFunctionType is the type of the native function
+// which returns the IDs of all executed transactions, in execution order.
+var TransactionIDsFunctionType = &sema.FunctionType{
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.VariableSizedType{
+			Type: sema.StringType,
+		},
+	),
+}
+
+// TransactionErrorFunctionType is the type of the native function
+// which returns the error message of an executed transaction, if it failed.
+var TransactionErrorFunctionType = &sema.FunctionType{
+	Parameters: transactionIDParameters,
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.OptionalType{
+			Type: sema.StringType,
+		},
+	),
+}
+
+// TransactionComputationUsedFunctionType is the type of the native function
+// which returns the computation used by an executed transaction.
+var TransactionComputationUsedFunctionType = &sema.FunctionType{
+	Parameters:           transactionIDParameters,
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.UInt64Type),
+}
+
+// TransactionMemoryEstimateFunctionType is the type of the native function
+// which returns the memory estimate of an executed transaction.
+var TransactionMemoryEstimateFunctionType = &sema.FunctionType{
+	Parameters:           transactionIDParameters,
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.UInt64Type),
+}
+
+// TransactionEventsFunctionType is the type of the native function
+// which returns the events emitted by an executed transaction.
+var TransactionEventsFunctionType = &sema.FunctionType{
+	Parameters: transactionIDParameters,
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.VariableSizedType{
+			Type: sema.AnyStructType,
+		},
+	),
+}
+
+// TransactionLogsFunctionType is the type of the native function
+// which returns the log messages of an executed transaction.
+var TransactionLogsFunctionType = &sema.FunctionType{
+	Parameters: transactionIDParameters,
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.VariableSizedType{
+			Type: sema.StringType,
+		},
+	),
+}
+
+// NewAccountFunctionType is the type of the native function
+// which creates an account with a key for each of the given weights,
+// signature algorithms and hash algorithms, and returns its address.
+var NewAccountFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "weights",
+			TypeAnnotation: sema.NewTypeAnnotation(&sema.VariableSizedType{Type: sema.UFix64Type}),
+		},
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "signatureAlgorithms",
+			TypeAnnotation: sema.NewTypeAnnotation(&sema.VariableSizedType{Type: sema.UInt8Type}),
+		},
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "hashAlgorithms",
+			TypeAnnotation: sema.NewTypeAnnotation(&sema.VariableSizedType{Type: sema.UInt8Type}),
+		},
+	},
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.TheAddressType),
+}
+
+// SetProposerFunctionType is the type of the native function
+// which sets the proposal key of the next transaction.
+var SetProposerFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "address",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.TheAddressType),
+		},
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "keyIndex",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.IntType),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// SetPayerFunctionType is the type of the native function
+// which sets the payer of the next transaction.
+var SetPayerFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "address",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.TheAddressType),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// SetSigningKeysFunctionType is the type of the native function
+// which sets the keys an account signs the next transaction with.
+var SetSigningKeysFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "address",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.TheAddressType),
+		},
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "keyIndices",
+			TypeAnnotation: sema.NewTypeAnnotation(&sema.VariableSizedType{Type: sema.IntType}),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// UpdateDeployedContractFunctionType is the type of the native function
+// which updates a deployed contract, and returns the error message, if it failed.
+var UpdateDeployedContractFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "name",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.StringType),
+		},
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "path",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.StringType),
+		},
+	},
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.OptionalType{
+			Type: sema.StringType,
+		},
+	),
+}
+
+// RemoveDeployedContractFunctionType is the type of the native function
+// which removes a deployed contract, and returns the error message, if it failed.
+var RemoveDeployedContractFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "name",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.StringType),
+		},
+	},
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.OptionalType{
+			Type: sema.StringType,
+		},
+	),
+}
+
+// SetBlockTimestampFunctionType is the type of the native function
+// which sets the timestamp of the next block, in seconds since the Unix epoch.
+var SetBlockTimestampFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "timestamp",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.UFix64Type),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// SetBlockIntervalFunctionType is the type of the native function
+// which sets the time between blocks, in seconds.
+var SetBlockIntervalFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:          sema.ArgumentLabelNotRequired,
+			Identifier:     "interval",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.UFix64Type),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// SetRandomValuesFunctionType is the type of the native function
+// which injects the random values of the next transaction.
+var SetRandomValuesFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Label:      sema.ArgumentLabelNotRequired,
+			Identifier: "values",
+			TypeAnnotation: sema.NewTypeAnnotation(
+				&sema.VariableSizedType{
+					Type: sema.UInt64Type,
+				},
+			),
+		},
+	},
+	ReturnTypeAnnotation: sema.VoidTypeAnnotation,
+}
+
+// QueryEventsFunctionType is the type of the native function
+// which returns the events of the committed blocks matching the given filters.
+// Each event is returned as an array of its block height, transaction ID,
+// transaction index, event index and value.
+var QueryEventsFunctionType = &sema.FunctionType{
+	Parameters: []sema.Parameter{
+		{
+			Identifier: "type",
+			TypeAnnotation: sema.NewTypeAnnotation(
+				&sema.OptionalType{
+					Type: sema.StringType,
+				},
+			),
+		},
+		{
+			Identifier:     "startHeight",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.UInt64Type),
+		},
+		{
+			Identifier:     "endHeight",
+			TypeAnnotation: sema.NewTypeAnnotation(sema.UInt64Type),
+		},
+		{
+			Identifier: "transactionID",
+			TypeAnnotation: sema.NewTypeAnnotation(
+				&sema.OptionalType{
+					Type: sema.StringType,
+				},
+			),
+		},
+	},
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(
+		&sema.VariableSizedType{
+			Type: &sema.VariableSizedType{
+				Type: sema.AnyStructType,
+			},
+		},
+	),
+}
+
+// NativeFunctions are the functions available to the BlockchainHelpers,
+// which are implemented by the test runner, and must be declared
+// for the BlockchainHelpersLocation when interpreting it.
+var NativeFunctions = map[string]*sema.FunctionType{
+	TransactionIDsFunctionName:             TransactionIDsFunctionType,
+	TransactionErrorFunctionName:           TransactionErrorFunctionType,
+	TransactionComputationUsedFunctionName: TransactionComputationUsedFunctionType,
+	TransactionMemoryEstimateFunctionName:  TransactionMemoryEstimateFunctionType,
+	TransactionEventsFunctionName:          TransactionEventsFunctionType,
+	TransactionLogsFunctionName:            TransactionLogsFunctionType,
+	NewAccountFunctionName:                 NewAccountFunctionType,
+	SetProposerFunctionName:                SetProposerFunctionType,
+	SetPayerFunctionName:                   SetPayerFunctionType,
+	SetSigningKeysFunctionName:             SetSigningKeysFunctionType,
+	UpdateDeployedContractFunctionName:     UpdateDeployedContractFunctionType,
+	RemoveDeployedContractFunctionName:     RemoveDeployedContractFunctionType,
+	SetBlockTimestampFunctionName:          SetBlockTimestampFunctionType,
+	SetBlockIntervalFunctionName:           SetBlockIntervalFunctionType,
+	SetRandomValuesFunctionName:            SetRandomValuesFunctionType,
+	QueryEventsFunctionName:                QueryEventsFunctionType,
+}
+
 func BlockchainHelpersChecker() *sema.Checker {
 	program, err := parser.ParseProgram(
 		nil,
@@ -66,6 +369,14 @@ func BlockchainHelpersChecker() *sema.Checker {
 	activation := sema.NewVariableActivation(sema.BaseValueActivation)
 	activation.DeclareValue(stdlib.AssertFunction)
 	activation.DeclareValue(stdlib.PanicFunction)
+	for name, functionType := range NativeFunctions {
+		activation.DeclareValue(stdlib.NewStandardLibraryFunction(
+			name,
+			functionType,
+			"",
+			nil,
+		))
+	}
 
 	checker, err := sema.NewChecker(
 		program,
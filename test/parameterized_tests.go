@@ -0,0 +1,393 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// Test functions may declare parameters. The argument sets of such a
+// parameterized test function are declared using `pragma arguments` doc-comment lines,
+// and/or returned by a companion provider function, which has the name of the
+// test function followed by `Arguments`, and returns an array of argument lists, e.g.:
+//
+//	/// pragma arguments (amount: 10.0)
+//	/// pragma arguments (amount: 20.0)
+//	access(all) fun testTransfer(amount: UFix64) {}
+//
+//	access(all) fun testTransferArguments(): [[AnyStruct]] {
+//	    return [[30.0], [40.0]]
+//	}
+//
+// The arguments are evaluated after `setup()`, so providers may use its state.
+// Each argument set is run as a separate test case, named after the test function
+// and the arguments, e.g. `testTransfer[amount=10.0]`. Argument sets must be distinct.
+const argumentsProviderSuffix = "Arguments"
+
+// testCase is a single invocation of a test function.
+type testCase struct {
+	// name is the name of the test case, which is the name of the test function,
+	// followed by the arguments, if the test function is parameterized.
+	name      string
+	funcName  string
+	arguments []interpreter.Value
+	// fuzz is true if the test function is a fuzz test function,
+	// for which the arguments are generated.
+	fuzz bool
+}
+
+// exportedTestCase is a test case with exported arguments,
+// so it can be run by another interpreter than the one which evaluated the arguments.
+type exportedTestCase struct {
+	name      string
+	funcName  string
+	arguments []cadence.Value
+	fuzz      bool
+}
+
+func exportTestCase(inter *interpreter.Interpreter, testCase testCase) (exportedTestCase, error) {
+	arguments := make([]cadence.Value, 0, len(testCase.arguments))
+	for _, argument := range testCase.arguments {
+		exportedArgument, err := runtime.ExportValue(argument, inter, interpreter.EmptyLocationRange)
+		if err != nil {
+			return exportedTestCase{}, err
+		}
+		arguments = append(arguments, exportedArgument)
+	}
+
+	return exportedTestCase{
+		name:      testCase.name,
+		funcName:  testCase.funcName,
+		arguments: arguments,
+		fuzz:      testCase.fuzz,
+	}, nil
+}
+
+// importTestCase returns the given exported test case,
+// with the arguments imported into the given interpreter.
+func (r *TestRunner) importTestCase(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+	exported exportedTestCase,
+) (testCase, error) {
+	testCase := testCase{
+		name:     exported.name,
+		funcName: exported.funcName,
+		fuzz:     exported.fuzz,
+	}
+
+	if len(exported.arguments) == 0 {
+		return testCase, nil
+	}
+
+	for _, funcDecl := range program.Program.FunctionDeclarations() {
+		if funcDecl.Identifier.Identifier != exported.funcName {
+			continue
+		}
+
+		functionType := program.Elaboration.FunctionDeclarationFunctionType(funcDecl)
+
+		for i, argument := range exported.arguments {
+			value, err := runtime.ImportValue(
+				inter,
+				interpreter.EmptyLocationRange,
+				r.backend.stdlibHandler,
+				argument,
+				functionType.Parameters[i].TypeAnnotation.Type,
+			)
+			if err != nil {
+				return testCase, err
+			}
+			testCase.arguments = append(testCase.arguments, value)
+		}
+
+		return testCase, nil
+	}
+
+	return testCase, fmt.Errorf("unknown test function `%s`", exported.funcName)
+}
+
+func isParameterized(funcDecl *ast.FunctionDeclaration) bool {
+	return !funcDecl.ParameterList.IsEmpty()
+}
+
+// isArgumentsProvider returns true if the given function declaration
+// is the provider of the arguments of a parameterized test function.
+func isArgumentsProvider(program *ast.Program, funcDecl *ast.FunctionDeclaration) bool {
+	funcName := funcDecl.Identifier.Identifier
+
+	testFuncName := strings.TrimSuffix(funcName, argumentsProviderSuffix)
+	if testFuncName == funcName {
+		return false
+	}
+
+	if !strings.HasPrefix(testFuncName, testFunctionPrefix) {
+		return false
+	}
+
+	for _, otherFuncDecl := range program.FunctionDeclarations() {
+		if otherFuncDecl.Identifier.Identifier == testFuncName {
+			return isParameterized(otherFuncDecl)
+		}
+	}
+
+	return false
+}
+
+// hasArguments returns true if argument sets are declared
+// for the given parameterized test function.
+func hasArguments(program *ast.Program, funcDecl *ast.FunctionDeclaration) bool {
+	if len(parser.ParseDocstringPragmaArguments(funcDecl.DocString)) > 0 {
+		return true
+	}
+
+	providerName := funcDecl.Identifier.Identifier + argumentsProviderSuffix
+	for _, otherFuncDecl := range program.FunctionDeclarations() {
+		if otherFuncDecl.Identifier.Identifier == providerName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// testCases returns the test cases of the given test function:
+// A single test case without arguments for a test function without parameters,
+// or a test case for each argument set of a parameterized test function.
+func (r *TestRunner) testCases(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+	funcDecl *ast.FunctionDeclaration,
+) ([]testCase, error) {
+	funcName := funcDecl.Identifier.Identifier
+
+	if isFuzzFunction(funcName) || !isParameterized(funcDecl) {
+		return []testCase{
+			{
+				name:     funcName,
+				funcName: funcName,
+				fuzz:     isFuzzFunction(funcName),
+			},
+		}, nil
+	}
+
+	pragmaTestCases, err := r.pragmaArgumentsTestCases(program, inter, funcDecl)
+	if err != nil {
+		return nil, err
+	}
+
+	providerTestCases, err := r.providedArgumentsTestCases(program, inter, funcDecl)
+	if err != nil {
+		return nil, err
+	}
+
+	testCases := append(pragmaTestCases, providerTestCases...)
+
+	// Test cases are identified by their name, e.g. in the results,
+	// and when running each test case concurrently.
+	names := make(map[string]struct{}, len(testCases))
+	for _, testCase := range testCases {
+		if _, ok := names[testCase.name]; ok {
+			return nil, fmt.Errorf(
+				"duplicate test case `%s` of parameterized test function `%s`",
+				testCase.name,
+				funcName,
+			)
+		}
+		names[testCase.name] = struct{}{}
+	}
+
+	return testCases, nil
+}
+
+func (r *TestRunner) pragmaArgumentsTestCases(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+	funcDecl *ast.FunctionDeclaration,
+) ([]testCase, error) {
+	funcName := funcDecl.Identifier.Identifier
+	functionType := program.Elaboration.FunctionDeclarationFunctionType(funcDecl)
+
+	var testCases []testCase
+
+	for _, pragmaArguments := range parser.ParseDocstringPragmaArguments(funcDecl.DocString) {
+		argumentList, errs := parser.ParseArgumentList(nil, []byte(pragmaArguments), parser.Config{})
+		if len(errs) > 0 {
+			return nil, fmt.Errorf(
+				"invalid pragma arguments of test function `%s`: %w",
+				funcName,
+				parser.Error{Errors: errs},
+			)
+		}
+
+		if len(argumentList) != len(functionType.Parameters) {
+			return nil, fmt.Errorf(
+				"invalid pragma arguments of test function `%s`: expected %d arguments, got %d",
+				funcName,
+				len(functionType.Parameters),
+				len(argumentList),
+			)
+		}
+
+		arguments := make([]interpreter.Value, 0, len(argumentList))
+		labels := make([]string, 0, len(argumentList))
+
+		for i, argument := range argumentList {
+			parameterType := functionType.Parameters[i].TypeAnnotation.Type
+
+			value, err := runtime.LiteralValue(inter, argument.Expression, parameterType)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"invalid pragma arguments of test function `%s`: invalid argument at index %d: %w",
+					funcName,
+					i,
+					err,
+				)
+			}
+
+			argumentValue, err := runtime.ImportValue(
+				inter,
+				interpreter.EmptyLocationRange,
+				r.backend.stdlibHandler,
+				value,
+				parameterType,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			arguments = append(arguments, argumentValue)
+			labels = append(labels, argument.Expression.String())
+		}
+
+		testCases = append(testCases, newTestCase(funcDecl, arguments, labels))
+	}
+
+	return testCases, nil
+}
+
+func (r *TestRunner) providedArgumentsTestCases(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+	funcDecl *ast.FunctionDeclaration,
+) ([]testCase, error) {
+	funcName := funcDecl.Identifier.Identifier
+	providerName := funcName + argumentsProviderSuffix
+
+	if inter.Globals.Get(providerName) == nil {
+		return nil, nil
+	}
+
+	result, err := inter.Invoke(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	argumentLists, ok := result.(*interpreter.ArrayValue)
+	if !ok {
+		return nil, fmt.Errorf(
+			"invalid arguments of test function `%s`: `%s` must return an array of argument lists",
+			funcName,
+			providerName,
+		)
+	}
+
+	functionType := program.Elaboration.FunctionDeclarationFunctionType(funcDecl)
+
+	testCases := make([]testCase, 0, argumentLists.Count())
+
+	for i := 0; i < argumentLists.Count(); i++ {
+		argumentList, ok := argumentLists.Get(inter, interpreter.EmptyLocationRange, i).(*interpreter.ArrayValue)
+		if !ok || argumentList.Count() != len(functionType.Parameters) {
+			return nil, fmt.Errorf(
+				"invalid arguments of test function `%s`: argument list at index %d must be an array of %d arguments",
+				funcName,
+				i,
+				len(functionType.Parameters),
+			)
+		}
+
+		arguments := make([]interpreter.Value, 0, argumentList.Count())
+		labels := make([]string, 0, argumentList.Count())
+
+		for j, parameter := range functionType.Parameters {
+			argument := argumentList.Get(inter, interpreter.EmptyLocationRange, j)
+
+			parameterType := parameter.TypeAnnotation.Type
+			if !inter.IsSubTypeOfSemaType(argument.StaticType(inter), parameterType) {
+				return nil, fmt.Errorf(
+					"invalid arguments of test function `%s`: argument list at index %d: "+
+						"expected argument of type `%s` for parameter `%s`, got `%s`",
+					funcName,
+					i,
+					parameterType.QualifiedString(),
+					parameter.Identifier,
+					argument.StaticType(inter),
+				)
+			}
+
+			arguments = append(arguments, argument)
+			labels = append(labels, argument.String())
+		}
+
+		testCases = append(testCases, newTestCase(funcDecl, arguments, labels))
+	}
+
+	return testCases, nil
+}
+
+func newTestCase(
+	funcDecl *ast.FunctionDeclaration,
+	arguments []interpreter.Value,
+	labels []string,
+) testCase {
+	funcName := funcDecl.Identifier.Identifier
+
+	var sb strings.Builder
+	sb.WriteString(funcName)
+	sb.WriteRune('[')
+	for i, parameter := range funcDecl.ParameterList.Parameters {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(parameter.Identifier.Identifier)
+		sb.WriteRune('=')
+		sb.WriteString(labels[i])
+	}
+	sb.WriteRune(']')
+
+	return testCase{
+		name:      sb.String(),
+		funcName:  funcName,
+		arguments: arguments,
+	}
+}
+
+// testCaseFunctionName returns the name of the test function of the test case with the given name.
+func testCaseFunctionName(testCaseName string) string {
+	funcName, _, _ := strings.Cut(testCaseName, "[")
+	return funcName
+}
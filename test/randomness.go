@@ -0,0 +1,249 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/onflow/cadence"
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+// randomSourceHistoryFunctionName is the name of the function which provides
+// the source of randomness of a block to the system transaction,
+// which records it in the `RandomBeaconHistory` contract.
+const randomSourceHistoryFunctionName = "randomSourceHistory"
+
+var randomSourceHistoryFunctionType = &sema.FunctionType{
+	ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.ByteArrayType),
+}
+
+// randomSourceHistoryProvider is implemented by the environment
+// of the system transaction, which provides the source of randomness.
+type randomSourceHistoryProvider interface {
+	RandomSourceHistory() ([]byte, error)
+}
+
+// randomSource is the source of randomness of the blockchain,
+// if it is controlled by the test, i.e. if it is seeded,
+// or if random values are injected.
+type randomSource struct {
+	seeded bool
+	seed   int64
+	// counter is the number of blocks of random bytes generated from the seed.
+	// As the generated bytes only depend on the seed and the counter,
+	// the generator can be rolled back by restoring the counter.
+	counter uint64
+
+	// nextValues are the values injected for the next transaction.
+	nextValues []uint64
+	// values are the injected values which are not yet read
+	// by the executing transaction.
+	values []uint64
+}
+
+// read reads random bytes into the given buffer: If there are injected values left,
+// the next one is read, i.e. `revertibleRandom` returns it, if it fits the requested
+// type and modulo. Otherwise, the bytes are generated from the seed, if any,
+// or read using the given fallback.
+func (s *randomSource) read(buffer []byte, fallback func([]byte) error) error {
+	if len(s.values) > 0 {
+		value := s.values[0]
+		s.values = s.values[1:]
+
+		var encoded [8]byte
+		binary.BigEndian.PutUint64(encoded[:], value)
+
+		for i := range buffer {
+			buffer[i] = 0
+		}
+		size := len(buffer)
+		if size > len(encoded) {
+			size = len(encoded)
+		}
+		copy(buffer[len(buffer)-size:], encoded[len(encoded)-size:])
+
+		return nil
+	}
+
+	if s.seeded {
+		s.generate(buffer)
+		return nil
+	}
+
+	return fallback(buffer)
+}
+
+// generate fills the given buffer with bytes generated from the seed.
+func (s *randomSource) generate(buffer []byte) {
+	for len(buffer) > 0 {
+		var input [16]byte
+		binary.BigEndian.PutUint64(input[:8], uint64(s.seed))
+		binary.BigEndian.PutUint64(input[8:], s.counter)
+		s.counter++
+
+		block := sha256.Sum256(input[:])
+		n := copy(buffer, block[:])
+		buffer = buffer[n:]
+	}
+}
+
+// randomSourceInterface is a runtime interface
+// which reads random bytes from the controlled source of randomness.
+type randomSourceInterface struct {
+	runtime.Interface
+	source *randomSource
+}
+
+func (i randomSourceInterface) ReadRandom(buffer []byte) error {
+	return i.source.read(buffer, i.Interface.ReadRandom)
+}
+
+// randomSourceRuntime is a runtime which executes transactions and scripts
+// with the controlled source of randomness. If the source is seeded,
+// the source of randomness history, e.g. of the `RandomBeaconHistory` contract,
+// is generated from the seed as well.
+type randomSourceRuntime struct {
+	runtime.Runtime
+	source *randomSource
+}
+
+var _ runtime.Runtime = &randomSourceRuntime{}
+
+func (rt *randomSourceRuntime) withRandomSource(context runtime.Context) runtime.Context {
+	context.Interface = randomSourceInterface{
+		Interface: context.Interface,
+		source:    rt.source,
+	}
+	return context
+}
+
+// declareRandomSourceHistory declares the function which provides the source of
+// randomness history, generated from the seed, for the transaction of the given context.
+// The environment of the context is reused for all transactions, so the function
+// is only declared for the location of a transaction which calls it, i.e. the system
+// transaction, as it reads the source of the environment of the transaction.
+// The function is not available to the other transactions and scripts.
+func (rt *randomSourceRuntime) declareRandomSourceHistory(script runtime.Script, context runtime.Context) {
+	if !rt.source.seeded ||
+		context.Environment == nil ||
+		context.Location == nil ||
+		!bytes.Contains(script.Source, []byte(randomSourceHistoryFunctionName)) {
+
+		return
+	}
+
+	provider, ok := context.Interface.(randomSourceHistoryProvider)
+	if !ok {
+		return
+	}
+
+	context.Environment.DeclareValue(
+		stdlib.StandardLibraryValue{
+			Name: randomSourceHistoryFunctionName,
+			Type: randomSourceHistoryFunctionType,
+			Kind: common.DeclarationKindFunction,
+			Value: interpreter.NewUnmeteredHostFunctionValue(
+				randomSourceHistoryFunctionType,
+				func(invocation interpreter.Invocation) interpreter.Value {
+					source, err := provider.RandomSourceHistory()
+					if err != nil {
+						panic(err)
+					}
+
+					rt.source.generate(source)
+
+					return interpreter.ByteSliceToByteArrayValue(
+						invocation.Interpreter,
+						source,
+					)
+				},
+			),
+		},
+		context.Location,
+	)
+}
+
+func (rt *randomSourceRuntime) NewTransactionExecutor(
+	script runtime.Script,
+	context runtime.Context,
+) runtime.Executor {
+	rt.declareRandomSourceHistory(script, context)
+	return rt.Runtime.NewTransactionExecutor(script, rt.withRandomSource(context))
+}
+
+func (rt *randomSourceRuntime) ExecuteTransaction(
+	script runtime.Script,
+	context runtime.Context,
+) error {
+	rt.declareRandomSourceHistory(script, context)
+	return rt.Runtime.ExecuteTransaction(script, rt.withRandomSource(context))
+}
+
+func (rt *randomSourceRuntime) NewScriptExecutor(
+	script runtime.Script,
+	context runtime.Context,
+) runtime.Executor {
+	return rt.Runtime.NewScriptExecutor(script, rt.withRandomSource(context))
+}
+
+func (rt *randomSourceRuntime) ExecuteScript(
+	script runtime.Script,
+	context runtime.Context,
+) (cadence.Value, error) {
+	return rt.Runtime.ExecuteScript(script, rt.withRandomSource(context))
+}
+
+func (rt *randomSourceRuntime) NewContractFunctionExecutor(
+	contractLocation common.AddressLocation,
+	functionName string,
+	arguments []cadence.Value,
+	argumentTypes []sema.Type,
+	context runtime.Context,
+) runtime.Executor {
+	return rt.Runtime.NewContractFunctionExecutor(
+		contractLocation,
+		functionName,
+		arguments,
+		argumentTypes,
+		rt.withRandomSource(context),
+	)
+}
+
+func (rt *randomSourceRuntime) InvokeContractFunction(
+	contractLocation common.AddressLocation,
+	functionName string,
+	arguments []cadence.Value,
+	argumentTypes []sema.Type,
+	context runtime.Context,
+) (cadence.Value, error) {
+	return rt.Runtime.InvokeContractFunction(
+		contractLocation,
+		functionName,
+		arguments,
+		argumentTypes,
+		rt.withRandomSource(context),
+	)
+}
@@ -0,0 +1,451 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	goErrors "errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SuiteResult is the result of running a single test script.
+type SuiteResult struct {
+	// Name is the name of the suite, i.e. the path of the test script.
+	Name    string
+	Results Results
+
+	// Error is the error of the test script as a whole, if any,
+	// e.g. a failure of `setup()` or `tearDown()`, or a checking error.
+	// Results may still be present, e.g. if `tearDown()` failed.
+	Error error
+}
+
+// NewSuiteResult returns the result of a test script,
+// given the results and the error returned by TestRunner.RunTests.
+func NewSuiteResult(scriptPath string, results Results, err error) SuiteResult {
+	return SuiteResult{
+		Name:    scriptPath,
+		Results: results,
+		Error:   err,
+	}
+}
+
+// Reporter reports the results of multiple test scripts.
+type Reporter interface {
+	Report(writer io.Writer, suites []SuiteResult) error
+}
+
+// NewReporter returns the reporter for the given format,
+// which is one of "text", "junit", "json" and "tap".
+func NewReporter(format string) (Reporter, error) {
+	switch format {
+	case "text":
+		return TextReporter{}, nil
+	case "junit":
+		return JUnitReporter{}, nil
+	case "json":
+		return JSONReporter{}, nil
+	case "tap":
+		return TAPReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// suiteErrorName returns the name for the error of a suite,
+// i.e. the name of the failed setup function, if any.
+func suiteErrorName(err error) string {
+	var setupErr *SetupError
+	if goErrors.As(err, &setupErr) {
+		return setupErr.FunctionName
+	}
+	return "error"
+}
+
+// failureLocationString returns the location of a failure, e.g. `tests/foo_test.cdc:12:4`.
+// The location of the test script is replaced with the path of the test script.
+func failureLocationString(suiteName string, location *FailureLocation) string {
+	if location == nil {
+		return ""
+	}
+
+	var locationString string
+	switch location.Location {
+	case nil:
+		return ""
+	case testScriptLocation:
+		locationString = suiteName
+	default:
+		locationString = location.Location.String()
+	}
+
+	if location.Line == 0 {
+		return locationString
+	}
+
+	return fmt.Sprintf("%s:%d:%d", locationString, location.Line, location.Column)
+}
+
+// TextReporter reports the results in a human-readable format,
+// like PrettyPrintResults.
+type TextReporter struct{}
+
+var _ Reporter = TextReporter{}
+
+func (TextReporter) Report(writer io.Writer, suites []SuiteResult) error {
+	for _, suite := range suites {
+		_, err := io.WriteString(writer, PrettyPrintResults(suite.Results, suite.Name))
+		if err != nil {
+			return err
+		}
+
+		if suite.Error != nil {
+			errString := strings.ReplaceAll(suite.Error.Error(), "\n", "\n\t\t")
+			_, err = fmt.Fprintf(writer, "- ERROR: %s\n\t\t%s\n", suiteErrorName(suite.Error), errString)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// JUnitReporter reports the results in the JUnit XML format.
+// Each test script is reported as a test suite.
+// Errors of the test scripts (e.g. a failed `setup()`)
+// are reported as test cases with an error.
+type JUnitReporter struct{}
+
+var _ Reporter = JUnitReporter{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+func (JUnitReporter) Report(writer io.Writer, suites []SuiteResult) error {
+	report := junitTestSuites{}
+
+	var totalDuration time.Duration
+
+	for _, suite := range suites {
+		junitSuite := junitTestSuite{
+			Name: suite.Name,
+		}
+
+		var suiteDuration time.Duration
+
+		for _, result := range suite.Results {
+			testCase := junitTestCase{
+				Name:      result.TestName,
+				ClassName: suite.Name,
+				Time:      junitTime(result.Duration),
+				SystemOut: strings.Join(result.Logs, "\n"),
+			}
+
+			suiteDuration += result.Duration
+			junitSuite.Tests++
+
+			switch {
+			case result.Skipped:
+				testCase.Skipped = &junitSkipped{Message: result.SkipReason}
+				junitSuite.Skipped++
+
+			case result.Error != nil:
+				failure := NewFailure(result.Error)
+				junitFailure := &junitFailure{
+					Message: failure.Message,
+					Type:    string(failure.Kind),
+					Content: PrettyPrintResult(suite.Name, result.TestName, result.Error),
+				}
+
+				if location := failureLocationString(suite.Name, failure.Location); location != "" {
+					junitFailure.Message = fmt.Sprintf("%s: %s", location, failure.Message)
+				}
+
+				if failure.Kind == FailureKindInternal {
+					testCase.Error = junitFailure
+					junitSuite.Errors++
+				} else {
+					testCase.Failure = junitFailure
+					junitSuite.Failures++
+				}
+			}
+
+			junitSuite.TestCases = append(junitSuite.TestCases, testCase)
+		}
+
+		if suite.Error != nil {
+			failure := NewFailure(suite.Error)
+			junitSuite.TestCases = append(junitSuite.TestCases, junitTestCase{
+				Name:      suiteErrorName(suite.Error),
+				ClassName: suite.Name,
+				Time:      junitTime(0),
+				Error: &junitFailure{
+					Message: failure.Message,
+					Type:    string(failure.Kind),
+					Content: suite.Error.Error(),
+				},
+			})
+			junitSuite.Tests++
+			junitSuite.Errors++
+		}
+
+		junitSuite.Time = junitTime(suiteDuration)
+		totalDuration += suiteDuration
+
+		report.Tests += junitSuite.Tests
+		report.Failures += junitSuite.Failures
+		report.Errors += junitSuite.Errors
+		report.Skipped += junitSuite.Skipped
+		report.Suites = append(report.Suites, junitSuite)
+	}
+
+	report.Time = junitTime(totalDuration)
+
+	_, err := io.WriteString(writer, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, "\n")
+	return err
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// JSONReporter reports the results in the JSON lines format,
+// i.e. one JSON object per line, for each test and for each error of a test script.
+type JSONReporter struct{}
+
+var _ Reporter = JSONReporter{}
+
+const (
+	statusPass  = "pass"
+	statusFail  = "fail"
+	statusSkip  = "skip"
+	statusError = "error"
+)
+
+type jsonTestReport struct {
+	Suite      string             `json:"suite"`
+	Test       string             `json:"test"`
+	Status     string             `json:"status"`
+	SkipReason string             `json:"skipReason,omitempty"`
+	Duration   float64            `json:"duration"`
+	Logs       []string           `json:"logs,omitempty"`
+	Failure    *jsonFailureReport `json:"failure,omitempty"`
+}
+
+type jsonFailureReport struct {
+	Kind      FailureKind `json:"kind"`
+	Message   string      `json:"message"`
+	Location  string      `json:"location,omitempty"`
+	Line      int         `json:"line,omitempty"`
+	Column    int         `json:"column,omitempty"`
+	Expected  string      `json:"expected,omitempty"`
+	Actual    string      `json:"actual,omitempty"`
+	FuzzInput string      `json:"fuzzInput,omitempty"`
+}
+
+func newJSONFailureReport(suiteName string, err error) *jsonFailureReport {
+	failure := NewFailure(err)
+	report := &jsonFailureReport{
+		Kind:      failure.Kind,
+		Message:   failure.Message,
+		Location:  failureLocationString(suiteName, failure.Location),
+		Expected:  failure.Expected,
+		Actual:    failure.Actual,
+		FuzzInput: failure.FuzzInput,
+	}
+	if failure.Location != nil {
+		report.Line = failure.Location.Line
+		report.Column = failure.Location.Column
+	}
+	return report
+}
+
+func (JSONReporter) Report(writer io.Writer, suites []SuiteResult) error {
+	encoder := json.NewEncoder(writer)
+
+	for _, suite := range suites {
+		for _, result := range suite.Results {
+			report := jsonTestReport{
+				Suite:    suite.Name,
+				Test:     result.TestName,
+				Status:   statusPass,
+				Duration: result.Duration.Seconds(),
+				Logs:     result.Logs,
+			}
+
+			switch {
+			case result.Skipped:
+				report.Status = statusSkip
+				report.SkipReason = result.SkipReason
+			case result.Error != nil:
+				report.Status = statusFail
+				report.Failure = newJSONFailureReport(suite.Name, result.Error)
+			}
+
+			err := encoder.Encode(report)
+			if err != nil {
+				return err
+			}
+		}
+
+		if suite.Error != nil {
+			err := encoder.Encode(jsonTestReport{
+				Suite:   suite.Name,
+				Test:    suiteErrorName(suite.Error),
+				Status:  statusError,
+				Failure: newJSONFailureReport(suite.Name, suite.Error),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// TAPReporter reports the results in the Test Anything Protocol (TAP) version 13 format.
+// Errors of the test scripts (e.g. a failed `setup()`) are reported as failed tests.
+type TAPReporter struct{}
+
+var _ Reporter = TAPReporter{}
+
+func (TAPReporter) Report(writer io.Writer, suites []SuiteResult) error {
+	var sb strings.Builder
+
+	sb.WriteString("TAP version 13\n")
+
+	count := 0
+
+	writeYAMLBlock := func(suiteName string, err error) {
+		failure := NewFailure(err)
+		sb.WriteString("  ---\n")
+		fmt.Fprintf(&sb, "  kind: %s\n", failure.Kind)
+		fmt.Fprintf(&sb, "  message: %s\n", yamlString(failure.Message))
+		if location := failureLocationString(suiteName, failure.Location); location != "" {
+			fmt.Fprintf(&sb, "  at: %s\n", yamlString(location))
+		}
+		if failure.Expected != "" {
+			fmt.Fprintf(&sb, "  expected: %s\n", yamlString(failure.Expected))
+		}
+		if failure.Actual != "" {
+			fmt.Fprintf(&sb, "  actual: %s\n", yamlString(failure.Actual))
+		}
+		if failure.FuzzInput != "" {
+			fmt.Fprintf(&sb, "  fuzzInput: %s\n", yamlString(failure.FuzzInput))
+		}
+		sb.WriteString("  ...\n")
+	}
+
+	for _, suite := range suites {
+		fmt.Fprintf(&sb, "# %s\n", suite.Name)
+
+		for _, result := range suite.Results {
+			count++
+
+			switch {
+			case result.Skipped:
+				fmt.Fprintf(&sb, "ok %d - %s: %s # SKIP", count, suite.Name, result.TestName)
+				if result.SkipReason != "" {
+					fmt.Fprintf(&sb, " %s", result.SkipReason)
+				}
+				sb.WriteRune('\n')
+			case result.Error != nil:
+				fmt.Fprintf(&sb, "not ok %d - %s: %s\n", count, suite.Name, result.TestName)
+				writeYAMLBlock(suite.Name, result.Error)
+			default:
+				fmt.Fprintf(&sb, "ok %d - %s: %s\n", count, suite.Name, result.TestName)
+			}
+		}
+
+		if suite.Error != nil {
+			count++
+			fmt.Fprintf(&sb, "not ok %d - %s: %s\n", count, suite.Name, suiteErrorName(suite.Error))
+			writeYAMLBlock(suite.Name, suite.Error)
+		}
+	}
+
+	fmt.Fprintf(&sb, "1..%d\n", count)
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
+
+// yamlString quotes the given string, so it can be used as a YAML scalar.
+func yamlString(s string) string {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(encoded)
+}
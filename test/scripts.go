@@ -27,3 +27,12 @@ var GetFlowBalance []byte
 
 //go:embed scripts/get_current_block_height.cdc
 var GetCurrentBlockHeight []byte
+
+//go:embed scripts/get_storage_used.cdc
+var GetStorageUsed []byte
+
+//go:embed scripts/get_storage_capacity.cdc
+var GetStorageCapacity []byte
+
+//go:embed scripts/get_available_balance.cdc
+var GetAvailableBalance []byte
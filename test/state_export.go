@@ -0,0 +1,327 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/onflow/cadence/runtime"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/stdlib"
+	"github.com/onflow/flow-emulator/storage"
+	"github.com/onflow/flow-go/fvm/environment"
+	"github.com/onflow/flow-go/fvm/storage/snapshot"
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// StateExportVersion is the version of the format of state exports.
+const StateExportVersion = 1
+
+// StateExport is the state of a set of accounts, i.e. their keys,
+// contracts and storage, e.g. exported once from a network,
+// which the EmulatorBackend can be seeded with, to run tests offline.
+//
+// EmulatorBackend.ExportState only exports the state of the emulator.
+// The Access API of a real network does not provide the storage registers of accounts,
+// so its state must be exported from an execution state checkpoint instead,
+// by encoding the registers of the accounts in this format.
+type StateExport struct {
+	Version int `json:"version"`
+	// Accounts are the states of the accounts, by hex-encoded address.
+	Accounts map[string]AccountState `json:"accounts"`
+}
+
+// AccountState is the state of an account.
+type AccountState struct {
+	// Registers are the hex-encoded values of the registers
+	// of the account, by hex-encoded register key.
+	Registers map[string]string `json:"registers"`
+}
+
+// accountStorageDomains are the domains of the storage maps of an account.
+var accountStorageDomains = []string{
+	common.PathDomainStorage.Identifier(),
+	common.PathDomainPrivate.Identifier(),
+	common.PathDomainPublic.Identifier(),
+	runtime.StorageDomainContract,
+	stdlib.InboxStorageDomain,
+	stdlib.CapabilityControllerStorageDomain,
+	stdlib.CapabilityControllerTagStorageDomain,
+	stdlib.PathCapabilityStorageDomain,
+	stdlib.AccountCapabilityStorageDomain,
+}
+
+// ReadStateExport reads a state export in the JSON format.
+func ReadStateExport(reader io.Reader) (*StateExport, error) {
+	var export StateExport
+	err := json.NewDecoder(reader).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Version != StateExportVersion {
+		return nil, fmt.Errorf(
+			"unsupported state export version: expected %d, got %d",
+			StateExportVersion,
+			export.Version,
+		)
+	}
+
+	return &export, nil
+}
+
+// ReadStateExportFile reads a state export from the JSON file with the given path.
+func ReadStateExportFile(path string) (*StateExport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadStateExport(file)
+}
+
+// Write writes the state export in the JSON format.
+func (s *StateExport) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// ExportState exports the state of the given accounts, as of the latest block of the emulator.
+// See StateExport for exporting the state of a real network.
+func (e *EmulatorBackend) ExportState(addresses ...common.Address) (*StateExport, error) {
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := e.store.LedgerByHeight(context.Background(), latestBlock.Header.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &StateExport{
+		Version:  StateExportVersion,
+		Accounts: make(map[string]AccountState, len(addresses)),
+	}
+
+	for _, address := range addresses {
+		registers, err := e.accountRegisters(ledger, flow.Address(address))
+		if err != nil {
+			return nil, err
+		}
+
+		export.Accounts[address.Hex()] = AccountState{
+			Registers: registers,
+		}
+	}
+
+	return export, nil
+}
+
+// accountRegisters returns the registers of the given account of the latest block,
+// which must be the block of the given ledger.
+func (e *EmulatorBackend) accountRegisters(
+	ledger snapshot.StorageSnapshot,
+	address flow.Address,
+) (map[string]string, error) {
+	account, err := e.blockchain.GetAccount(address)
+	if err != nil {
+		return nil, err
+	}
+
+	contractNames := make([]string, 0, len(account.Contracts))
+	for contractName := range account.Contracts {
+		contractNames = append(contractNames, contractName)
+	}
+
+	return accountRegisters(ledger, address, contractNames)
+}
+
+// accountRegisters returns the registers of the given account,
+// i.e. its status, keys, contracts, storage maps and storage slabs.
+func accountRegisters(
+	ledger snapshot.StorageSnapshot,
+	address flow.Address,
+	contractNames []string,
+) (map[string]string, error) {
+
+	statusValue, err := ledger.Get(flow.AccountStatusRegisterID(address))
+	if err != nil {
+		return nil, err
+	}
+	if len(statusValue) == 0 {
+		return nil, fmt.Errorf("account with address: %s not found", address.HexWithPrefix())
+	}
+
+	status, err := environment.AccountStatusFromBytes(statusValue)
+	if err != nil {
+		return nil, err
+	}
+
+	registerIDs := []flow.RegisterID{
+		flow.AccountStatusRegisterID(address),
+		flow.ContractNamesRegisterID(address),
+	}
+
+	for index := uint64(0); index < status.PublicKeyCount(); index++ {
+		registerIDs = append(registerIDs, flow.PublicKeyRegisterID(address, index))
+	}
+
+	for _, contractName := range contractNames {
+		registerIDs = append(registerIDs, flow.ContractRegisterID(address, contractName))
+	}
+
+	for _, domain := range accountStorageDomains {
+		registerIDs = append(registerIDs, flow.NewRegisterID(address, domain))
+	}
+
+	// Storage slabs are allocated with consecutive indices, starting at 1.
+	storageIndex := status.StorageIndex()
+	nextSlabIndex := binary.BigEndian.Uint64(storageIndex[:])
+	for index := uint64(1); index < nextSlabIndex; index++ {
+		var key [9]byte
+		key[0] = '$'
+		binary.BigEndian.PutUint64(key[1:], index)
+		registerIDs = append(registerIDs, flow.NewRegisterID(address, string(key[:])))
+	}
+
+	registers := map[string]string{}
+
+	for _, registerID := range registerIDs {
+		value, err := ledger.Get(registerID)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) == 0 {
+			continue
+		}
+
+		registers[hex.EncodeToString([]byte(registerID.Key))] = hex.EncodeToString(value)
+	}
+
+	return registers, nil
+}
+
+// ImportState seeds the blockchain with the given state, e.g. to run tests against a fork of a network.
+// The state is imported as a new block, so the state of the previous blocks is unchanged,
+// e.g. rolling back to a previous block also rolls back the import.
+// The accounts of the state are added, or replaced if they exist,
+// i.e. the registers of an existing account which are not in the state are removed.
+// Pending transactions are discarded.
+func (e *EmulatorBackend) ImportState(export *StateExport) error {
+	// Discard the pending block, which must not be committed with the imported state.
+	latestBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+
+	err = e.blockchain.ReloadBlockchain()
+	if err != nil {
+		return err
+	}
+	e.blockOffset = 0
+	e.sequenceNumberOffsets = map[accountKeyID]uint64{}
+	e.revokedKeys = revokedKeysCache{}
+	e.truncateTransactions(latestBlock.Header.Height)
+
+	err = e.CommitBlock()
+	if err != nil {
+		return err
+	}
+
+	importBlock, err := e.blockchain.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	storeName := e.store.Storage(storage.LedgerStoreName)
+
+	ledger, err := e.store.LedgerByHeight(ctx, importBlock.Header.Height)
+	if err != nil {
+		return err
+	}
+
+	for hexAddress, account := range export.Accounts {
+		address, err := common.HexToAddress(hexAddress)
+		if err != nil {
+			return err
+		}
+
+		registers := make(map[string]string, len(account.Registers))
+		for hexKey, hexValue := range account.Registers {
+			registers[hexKey] = hexValue
+		}
+
+		// Remove the registers of the existing account which are not in the state,
+		// e.g. its storage and contracts, by setting them to an empty value
+		statusValue, err := ledger.Get(flow.AccountStatusRegisterID(flow.Address(address)))
+		if err != nil {
+			return err
+		}
+		if len(statusValue) > 0 {
+			existingRegisters, err := e.accountRegisters(ledger, flow.Address(address))
+			if err != nil {
+				return err
+			}
+
+			for hexKey := range existingRegisters {
+				if _, ok := registers[hexKey]; !ok {
+					registers[hexKey] = ""
+				}
+			}
+		}
+
+		for hexKey, hexValue := range registers {
+			key, err := hex.DecodeString(hexKey)
+			if err != nil {
+				return fmt.Errorf("invalid register key of account %s: %w", hexAddress, err)
+			}
+
+			value, err := hex.DecodeString(hexValue)
+			if err != nil {
+				return fmt.Errorf("invalid register value of account %s: %w", hexAddress, err)
+			}
+
+			registerID := flow.NewRegisterID(flow.Address(address), string(key))
+
+			err = e.store.SetBytesWithVersion(
+				ctx,
+				storeName,
+				[]byte(registerID.String()),
+				value,
+				importBlock.Header.Height,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Reload the pending block, which was created before the import.
+	return e.blockchain.ReloadBlockchain()
+}
@@ -0,0 +1,179 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// TestFilter decides whether the test function with the given name is run.
+type TestFilter func(testName string) bool
+
+// NewRegexpTestFilter returns a filter which selects the test functions
+// whose name matches the given regular expression.
+func NewRegexpTestFilter(pattern string) (TestFilter, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return regex.MatchString, nil
+}
+
+// NewGlobTestFilter returns a filter which selects the test functions
+// whose name matches the given glob pattern, e.g. `testTransfer*`.
+func NewGlobTestFilter(pattern string) (TestFilter, error) {
+	// Validate the pattern
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(testName string) bool {
+		matched, _ := path.Match(pattern, testName)
+		return matched
+	}, nil
+}
+
+const (
+	skipMarker      = "skip"
+	onlyMarker      = "only"
+	markerTagPrefix = "@"
+)
+
+// testMarkers are the `skip` and `only` markers of the test functions of a test script.
+//
+// Test functions can be marked to be skipped, or to be the only ones run,
+// using doc-comment tags, e.g.:
+//
+//	/// @skip: not implemented yet
+//	access(all) fun testFoo() {}
+//
+// or using pragmas, e.g.:
+//
+//	#skip("testFoo", "not implemented yet")
+//	#only("testBar")
+//
+// If any test function is marked with `only`, all other test functions are skipped.
+type testMarkers struct {
+	// skipReasons maps the names of the test functions marked
+	// with `skip` to the optional reason.
+	skipReasons map[string]string
+	only        map[string]struct{}
+}
+
+func newTestMarkers(program *ast.Program) (*testMarkers, error) {
+	markers := &testMarkers{
+		skipReasons: map[string]string{},
+		only:        map[string]struct{}{},
+	}
+
+	for _, funcDecl := range program.FunctionDeclarations() {
+		funcName := funcDecl.Identifier.Identifier
+		if !isTestFunction(funcName) {
+			continue
+		}
+
+		for _, line := range strings.Split(funcDecl.DocString, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, markerTagPrefix) {
+				continue
+			}
+
+			marker, info, _ := strings.Cut(strings.TrimPrefix(line, markerTagPrefix), ":")
+			switch strings.TrimSpace(marker) {
+			case skipMarker:
+				markers.skipReasons[funcName] = strings.TrimSpace(info)
+			case onlyMarker:
+				markers.only[funcName] = struct{}{}
+			}
+		}
+	}
+
+	for _, pragmaDecl := range program.PragmaDeclarations() {
+		invocation, ok := pragmaDecl.Expression.(*ast.InvocationExpression)
+		if !ok {
+			continue
+		}
+
+		identifier, ok := invocation.InvokedExpression.(*ast.IdentifierExpression)
+		if !ok {
+			continue
+		}
+
+		marker := identifier.Identifier.Identifier
+		if marker != skipMarker && marker != onlyMarker {
+			continue
+		}
+
+		arguments := make([]string, 0, len(invocation.Arguments))
+		for _, argument := range invocation.Arguments {
+			stringExpr, ok := argument.Expression.(*ast.StringExpression)
+			if !ok {
+				return nil, fmt.Errorf(
+					"invalid #%s pragma: expected string arguments, found %s",
+					marker,
+					argument.Expression,
+				)
+			}
+			arguments = append(arguments, stringExpr.Value)
+		}
+
+		switch {
+		case marker == skipMarker && (len(arguments) == 1 || len(arguments) == 2):
+			var reason string
+			if len(arguments) == 2 {
+				reason = arguments[1]
+			}
+			markers.skipReasons[arguments[0]] = reason
+
+		case marker == onlyMarker && len(arguments) == 1:
+			markers.only[arguments[0]] = struct{}{}
+
+		default:
+			return nil, fmt.Errorf(
+				"invalid #%s pragma: unexpected number of arguments: %d",
+				marker,
+				len(arguments),
+			)
+		}
+	}
+
+	return markers, nil
+}
+
+// skipped returns true if the test function with the given name must be skipped,
+// and the reason of the `skip` marker, if any.
+func (m *testMarkers) skipped(testName string) (reason string, skipped bool) {
+	if reason, ok := m.skipReasons[testName]; ok {
+		return reason, true
+	}
+
+	if len(m.only) == 0 {
+		return "", false
+	}
+
+	_, ok := m.only[testName]
+	return "", !ok
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/onflow/cadence/runtime"
 	"github.com/onflow/cadence/runtime/stdlib"
+	"github.com/onflow/flow-emulator/emulator"
 	"github.com/rs/zerolog"
 )
 
@@ -51,8 +52,16 @@ func (tf *TestFrameworkProvider) ReadFile(path string) (string, error) {
 			return string(GetFlowBalance), nil
 		case "get_current_block_height.cdc":
 			return string(GetCurrentBlockHeight), nil
+		case "get_storage_used.cdc":
+			return string(GetStorageUsed), nil
+		case "get_storage_capacity.cdc":
+			return string(GetStorageCapacity), nil
+		case "get_available_balance.cdc":
+			return string(GetAvailableBalance), nil
 		case "burn_flow.cdc":
 			return string(BurnFlow), nil
+		case "revoke_account_key.cdc":
+			return string(RevokeAccountKeyTransaction), nil
 		}
 	}
 
@@ -72,15 +81,38 @@ func NewTestFrameworkProvider(
 	fileResolver FileResolver,
 	stdlibHandler stdlib.StandardLibraryHandler,
 	coverageReport *runtime.CoverageReport,
+	opts ...emulator.Option,
 ) stdlib.TestFramework {
+	return newTestFrameworkProvider(
+		logger,
+		fileResolver,
+		stdlibHandler,
+		coverageReport,
+		newSystemClock(),
+		opts...,
+	)
+}
+
+// newTestFrameworkProvider returns a new TestFrameworkProvider,
+// with a blockchain which uses the given clock.
+func newTestFrameworkProvider(
+	logger zerolog.Logger,
+	fileResolver FileResolver,
+	stdlibHandler stdlib.StandardLibraryHandler,
+	coverageReport *runtime.CoverageReport,
+	clock *systemClock,
+	opts ...emulator.Option,
+) *TestFrameworkProvider {
 	return &TestFrameworkProvider{
 		fileResolver:   fileResolver,
 		stdlibHandler:  stdlibHandler,
 		coverageReport: coverageReport,
-		emulatorBackend: NewEmulatorBackend(
+		emulatorBackend: newEmulatorBackend(
 			logger,
 			stdlibHandler,
 			coverageReport,
+			clock,
+			opts...,
 		),
 	}
 }
@@ -20,14 +20,28 @@ package test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	sdk "github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/crypto"
+	"github.com/onflow/flow-go/model/flow"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
 	"github.com/onflow/cadence/runtime"
 	"github.com/onflow/cadence/runtime/common"
 	"github.com/onflow/cadence/runtime/interpreter"
@@ -2184,6 +2198,294 @@ func TestPrettyPrintTestResults(t *testing.T) {
 	assert.Equal(t, expected, resultsStr)
 }
 
+func TestTestResults(t *testing.T) {
+	t.Parallel()
+
+	t.Run("failure details", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testPass() {
+                log("first")
+                Test.assert(true)
+            }
+
+            access(all) fun testAssertEqual() {
+                log("second")
+                Test.assertEqual(1, 2)
+            }
+
+            access(all) fun testExpect() {
+                Test.expect("hello", Test.equal("world"))
+            }
+
+            access(all) fun testPanic() {
+                panic("runtime error")
+            }
+		`
+
+		runner := NewTestRunner()
+		results, err := runner.RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		passResult := results[0]
+		assert.Equal(t, "testPass", passResult.TestName)
+		assert.NoError(t, passResult.Error)
+		assert.Nil(t, passResult.Failure)
+		assert.Equal(t, []string{"first"}, passResult.Logs)
+		assert.Greater(t, passResult.Duration, time.Duration(0))
+
+		assertEqualResult := results[1]
+		assert.Equal(t, []string{"second"}, assertEqualResult.Logs)
+		require.NotNil(t, assertEqualResult.Failure)
+		assert.Equal(
+			t,
+			&Failure{
+				Kind:    FailureKindAssertion,
+				Message: "assertion failed: not equal: expected: 1, actual: 2",
+				Location: &FailureLocation{
+					Location: testScriptLocation,
+					Line:     11,
+					Column:   16,
+				},
+				Expected: "1",
+				Actual:   "2",
+			},
+			assertEqualResult.Failure,
+		)
+
+		expectResult := results[2]
+		require.NotNil(t, expectResult.Failure)
+		assert.Equal(t, FailureKindAssertion, expectResult.Failure.Kind)
+		assert.Equal(t, "", expectResult.Failure.Expected)
+		assert.Equal(t, `"hello"`, expectResult.Failure.Actual)
+		assert.Empty(t, expectResult.Logs)
+
+		panicResult := results[3]
+		require.NotNil(t, panicResult.Failure)
+		assert.Equal(t, FailureKindPanic, panicResult.Failure.Kind)
+		assert.Equal(t, "panic: runtime error", panicResult.Failure.Message)
+		require.NotNil(t, panicResult.Failure.Location)
+		assert.Equal(t, 19, panicResult.Failure.Location.Line)
+	})
+
+	t.Run("setup failure", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun beforeEach() {
+                panic("error occurred")
+            }
+
+            access(all) fun testFunc() {
+                Test.assert(true)
+            }
+		`
+
+		runner := NewTestRunner()
+		_, err := runner.RunTests(code)
+		require.Error(t, err)
+
+		var setupErr *SetupError
+		require.ErrorAs(t, err, &setupErr)
+		assert.Equal(t, "beforeEach", setupErr.FunctionName)
+
+		failure := NewFailure(err)
+		assert.Equal(t, FailureKindSetup, failure.Kind)
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("unexpected")
+
+		failure := NewFailure(err)
+		assert.Equal(t, FailureKindInternal, failure.Kind)
+		assert.Nil(t, failure.Location)
+
+		// Errors other than interpreter errors can be printed
+		assert.Equal(
+			t,
+			"- FAIL: testFunc\n\t\tunexpected",
+			PrettyPrintResult("test.cdc", "testFunc", err),
+		)
+	})
+}
+
+func TestReporters(t *testing.T) {
+	t.Parallel()
+
+	const code = `
+        import Test
+
+        access(all) fun testPass() {
+            log("passed")
+        }
+
+        access(all) fun testFail() {
+            Test.assertEqual(1, 2)
+        }
+
+        access(all) fun tearDown() {
+            panic("tearDown error")
+        }
+	`
+
+	runner := NewTestRunner()
+	results, err := runner.RunTests(code)
+	require.Error(t, err)
+	require.Len(t, results, 2)
+
+	// Skipping is not decided by the reporters,
+	// so simply mark an additional test as skipped.
+	results = append(results, Result{
+		TestName: "testSkipped",
+		Skipped:  true,
+	})
+
+	suites := []SuiteResult{
+		NewSuiteResult("tests/foo_test.cdc", results, err),
+		NewSuiteResult("tests/bar_test.cdc", nil, fmt.Errorf("checking failed")),
+	}
+
+	report := func(t *testing.T, format string) string {
+		reporter, err := NewReporter(format)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = reporter.Report(&buffer, suites)
+		require.NoError(t, err)
+
+		return buffer.String()
+	}
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		output := report(t, "text")
+
+		assert.Contains(t, output, "Test results: \"tests/foo_test.cdc\"\n- PASS: testPass\n- FAIL: testFail\n")
+		assert.Contains(t, output, "- SKIP: testSkipped\n- ERROR: tearDown\n")
+		assert.Contains(t, output, "Test results: \"tests/bar_test.cdc\"\n- ERROR: error\n\t\tchecking failed\n")
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		t.Parallel()
+
+		output := report(t, "junit")
+
+		var testSuites junitTestSuites
+		err := xml.Unmarshal([]byte(output), &testSuites)
+		require.NoError(t, err)
+
+		assert.Equal(t, 5, testSuites.Tests)
+		assert.Equal(t, 1, testSuites.Failures)
+		assert.Equal(t, 2, testSuites.Errors)
+		assert.Equal(t, 1, testSuites.Skipped)
+
+		require.Len(t, testSuites.Suites, 2)
+
+		fooSuite := testSuites.Suites[0]
+		assert.Equal(t, "tests/foo_test.cdc", fooSuite.Name)
+		require.Len(t, fooSuite.TestCases, 4)
+
+		assert.Equal(t, "testPass", fooSuite.TestCases[0].Name)
+		assert.Equal(t, "tests/foo_test.cdc", fooSuite.TestCases[0].ClassName)
+		assert.Equal(t, "passed", fooSuite.TestCases[0].SystemOut)
+		assert.Nil(t, fooSuite.TestCases[0].Failure)
+
+		require.NotNil(t, fooSuite.TestCases[1].Failure)
+		assert.Equal(
+			t,
+			"tests/foo_test.cdc:9:12: assertion failed: not equal: expected: 1, actual: 2",
+			fooSuite.TestCases[1].Failure.Message,
+		)
+		assert.Equal(t, "assertion", fooSuite.TestCases[1].Failure.Type)
+
+		assert.NotNil(t, fooSuite.TestCases[2].Skipped)
+
+		assert.Equal(t, "tearDown", fooSuite.TestCases[3].Name)
+		require.NotNil(t, fooSuite.TestCases[3].Error)
+		assert.Equal(t, "setup", fooSuite.TestCases[3].Error.Type)
+
+		barSuite := testSuites.Suites[1]
+		require.Len(t, barSuite.TestCases, 1)
+		require.NotNil(t, barSuite.TestCases[0].Error)
+		assert.Equal(t, "checking failed", barSuite.TestCases[0].Error.Message)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		output := report(t, "json")
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 5)
+
+		var reports []jsonTestReport
+		for _, line := range lines {
+			var report jsonTestReport
+			err := json.Unmarshal([]byte(line), &report)
+			require.NoError(t, err)
+			reports = append(reports, report)
+		}
+
+		assert.Equal(t, statusPass, reports[0].Status)
+		assert.Equal(t, []string{"passed"}, reports[0].Logs)
+
+		assert.Equal(t, statusFail, reports[1].Status)
+		assert.Equal(
+			t,
+			&jsonFailureReport{
+				Kind:     FailureKindAssertion,
+				Message:  "assertion failed: not equal: expected: 1, actual: 2",
+				Location: "tests/foo_test.cdc:9:12",
+				Line:     9,
+				Column:   12,
+				Expected: "1",
+				Actual:   "2",
+			},
+			reports[1].Failure,
+		)
+
+		assert.Equal(t, statusSkip, reports[2].Status)
+
+		assert.Equal(t, "tearDown", reports[3].Test)
+		assert.Equal(t, statusError, reports[3].Status)
+		assert.Equal(t, FailureKindSetup, reports[3].Failure.Kind)
+
+		assert.Equal(t, "tests/bar_test.cdc", reports[4].Suite)
+		assert.Equal(t, statusError, reports[4].Status)
+	})
+
+	t.Run("tap", func(t *testing.T) {
+		t.Parallel()
+
+		output := report(t, "tap")
+
+		assert.True(t, strings.HasPrefix(output, "TAP version 13\n# tests/foo_test.cdc\n"))
+		assert.Contains(t, output, "ok 1 - tests/foo_test.cdc: testPass\n")
+		assert.Contains(t, output, "not ok 2 - tests/foo_test.cdc: testFail\n  ---\n  kind: assertion\n")
+		assert.Contains(t, output, "  at: \"tests/foo_test.cdc:9:12\"\n  expected: \"1\"\n  actual: \"2\"\n  ...\n")
+		assert.Contains(t, output, "ok 3 - tests/foo_test.cdc: testSkipped # SKIP\n")
+		assert.Contains(t, output, "not ok 4 - tests/foo_test.cdc: tearDown\n")
+		assert.Contains(t, output, "not ok 5 - tests/bar_test.cdc: error\n")
+		assert.True(t, strings.HasSuffix(output, "1..5\n"))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewReporter("html")
+		require.Error(t, err)
+	})
+}
+
 func TestLoadingProgramsFromLocalFile(t *testing.T) {
 	t.Parallel()
 
@@ -4852,6 +5154,74 @@ func TestWithLogger(t *testing.T) {
 	assert.Equal(t, []string{"Hello, world!"}, runner.Logs())
 }
 
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type countingHook struct {
+	count atomic.Int64
+}
+
+func (h *countingHook) Run(_ *zerolog.Event, _ zerolog.Level, _ string) {
+	h.count.Add(1)
+}
+
+func TestWithLoggerConcurrently(t *testing.T) {
+	t.Parallel()
+
+	// The hooks of the logger have spare capacity,
+	// so the loggers derived from it share the backing array of the hooks.
+
+	var buf lockedBuffer
+	hook := &countingHook{}
+	logger := zerolog.New(&buf).Hook(hook).Hook(hook).Hook(hook).Hook(hook).Hook(hook)
+
+	scripts := make([]TestScript, 4)
+	for i := range scripts {
+		scripts[i] = TestScript{
+			Path: fmt.Sprintf("%d_test.cdc", i),
+			Code: fmt.Sprintf(
+				`
+                  access(all) fun testLog() {
+                      log("script %d")
+                  }
+                `,
+				i,
+			),
+		}
+	}
+
+	runner := NewTestRunner().
+		WithLogger(logger).
+		WithParallelism(len(scripts))
+
+	suites := runner.RunScripts(scripts)
+
+	require.Len(t, suites, len(scripts))
+	for i, suite := range suites {
+		require.NoError(t, suite.Error)
+		require.Len(t, suite.Results, 1)
+		require.NoError(t, suite.Results[0].Error)
+
+		assert.Contains(t, buf.String(), fmt.Sprintf("script %d", i))
+	}
+
+	assert.Greater(t, hook.count.Load(), int64(0))
+}
+
 func TestGetEventsFromIntegrationTests(t *testing.T) {
 	t.Parallel()
 
@@ -5011,60 +5381,182 @@ func TestGetEventsFromIntegrationTests(t *testing.T) {
 	}
 }
 
-func TestImportingHelperFile(t *testing.T) {
+func TestEventStore(t *testing.T) {
 	t.Parallel()
 
-	const helpersCode = `
-        import Test
+	newEvent := func(txID sdk.Identifier, eventIndex int, typ string) sdk.Event {
+		return sdk.Event{
+			Type:          typ,
+			TransactionID: txID,
+			EventIndex:    eventIndex,
+		}
+	}
 
-        access(all) fun createTransaction(
-            _ path: String,
-            account: Test.TestAccount,
-            args: [AnyStruct]
-        ): Test.Transaction {
-            return Test.Transaction(
-                code: Test.readFile(path),
-                authorizers: [account.address],
-                signers: [account],
-                arguments: args
-            )
+	tx1 := sdk.Identifier{1}
+	tx2 := sdk.Identifier{2}
+	tx3 := sdk.Identifier{3}
+
+	store := NewEventStore()
+	store.AddBlock(0, nil)
+	store.AddBlock(1, []sdk.Event{
+		newEvent(tx1, 0, "A.1.Foo.A"),
+		newEvent(tx1, 1, "A.1.Foo.B"),
+	})
+	store.AddBlock(2, []sdk.Event{
+		newEvent(tx2, 0, "A.1.Foo.B"),
+	})
+	store.AddBlock(3, []sdk.Event{
+		newEvent(tx3, 0, "A.1.Foo.A"),
+		newEvent(tx3, 1, "A.1.Foo.A"),
+	})
+
+	eventSummary := func(events []IndexedEvent) []string {
+		summary := make([]string, 0, len(events))
+		for _, event := range events {
+			summary = append(
+				summary,
+				fmt.Sprintf("%d:%x:%d:%s", event.BlockHeight, event.TransactionID[0], event.EventIndex, event.Type),
+			)
+		}
+		return summary
+	}
+
+	assert.Equal(t, uint64(4), store.NextHeight())
+
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:0:A.1.Foo.A",
+			"1:1:1:A.1.Foo.B",
+			"2:2:0:A.1.Foo.B",
+			"3:3:0:A.1.Foo.A",
+			"3:3:1:A.1.Foo.A",
+		},
+		eventSummary(store.Query(EventFilter{})),
+	)
+
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:0:A.1.Foo.A",
+			"3:3:0:A.1.Foo.A",
+			"3:3:1:A.1.Foo.A",
+		},
+		eventSummary(store.Query(EventFilter{Type: "A.1.Foo.A"})),
+	)
+
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:1:A.1.Foo.B",
+			"2:2:0:A.1.Foo.B",
+		},
+		eventSummary(store.Query(EventFilter{StartHeight: 1, EndHeight: 2, Type: "A.1.Foo.B"})),
+	)
+
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:1:A.1.Foo.B",
+		},
+		eventSummary(store.Query(EventFilter{TransactionID: tx1, Type: "A.1.Foo.B"})),
+	)
+
+	assert.Empty(t, store.Query(EventFilter{StartHeight: 4}))
+
+	store.Truncate(1)
+
+	assert.Equal(t, uint64(2), store.NextHeight())
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:0:A.1.Foo.A",
+			"1:1:1:A.1.Foo.B",
+		},
+		eventSummary(store.Query(EventFilter{})),
+	)
+	assert.Empty(t, store.Query(EventFilter{TransactionID: tx3}))
+	assert.Len(t, store.Query(EventFilter{Type: "A.1.Foo.A"}), 1)
+
+	// Index the rolled back heights again
+	store.AddBlock(2, []sdk.Event{
+		newEvent(tx3, 0, "A.1.Foo.A"),
+	})
+	assert.Equal(
+		t,
+		[]string{
+			"1:1:0:A.1.Foo.A",
+			"2:3:0:A.1.Foo.A",
+		},
+		eventSummary(store.Query(EventFilter{Type: "A.1.Foo.A"})),
+	)
+}
+
+func TestQueryEvents(t *testing.T) {
+	t.Parallel()
+
+	const contractCode = `
+        access(all) contract FooContract {
+
+            access(all) event Started(n: Int)
+            access(all) event Finished(n: Int)
+
+            access(all) fun run(_ n: Int) {
+                emit Started(n: n)
+                emit Finished(n: n)
+            }
         }
 	`
 
 	const transactionCode = `
-        transaction() {
+        import FooContract from "../contracts/FooContract.cdc"
+
+        transaction(n: Int) {
             prepare(acct: &Account) {}
 
             execute {
-                assert(true)
+                FooContract.run(n)
             }
         }
 	`
 
 	const testCode = `
         import Test
-        import "test_helpers.cdc"
+        import FooContract from "../contracts/FooContract.cdc"
 
         access(all)
-        let account = Test.createAccount()
+        let account = Test.getAccount(0x0000000000000005)
 
         access(all)
-        fun testRunTransaction() {
-            let tx = createTransaction(
-                "../transactions/add_special_number.cdc",
-                account: account,
-                args: []
+        fun test() {
+            let err = Test.deployContract(
+                name: "FooContract",
+                path: "../contracts/FooContract.cdc",
+                arguments: []
             )
+            Test.expect(err, Test.beNil())
 
-            let result = Test.executeTransaction(tx)
-            Test.expect(result, Test.beSucceeded())
+            for n in [1, 2] {
+                let tx = Test.Transaction(
+                    code: Test.readFile("../transactions/run.cdc"),
+                    authorizers: [account.address],
+                    signers: [account],
+                    arguments: [n]
+                )
+                let result = Test.executeTransaction(tx)
+                Test.expect(result, Test.beSucceeded())
+            }
+
+            Test.assertEqual(2, Test.eventsOfType(Type<FooContract.Started>()).length)
         }
 	`
 
 	fileResolver := func(path string) (string, error) {
 		switch path {
-		case "../transactions/add_special_number.cdc":
-			return transactionCode, nil
+		case "../contracts/FooContract.cdc":
+			return contractCode, nil
+		case "../transactions/run.cdc":
+			return transactionCode, nil
 		default:
 			return "", fmt.Errorf("cannot find file path: %s", path)
 		}
@@ -5072,88 +5564,3210 @@ func TestImportingHelperFile(t *testing.T) {
 
 	importResolver := func(location common.Location) (string, error) {
 		switch location := location.(type) {
+		case common.AddressLocation:
+			if location.Name == "FooContract" {
+				return contractCode, nil
+			}
 		case common.StringLocation:
-			if location == "test_helpers.cdc" {
-				return helpersCode, nil
+			if location == "../contracts/FooContract.cdc" {
+				return contractCode, nil
 			}
 		}
 
-		return "", fmt.Errorf("unsupported import %s", location)
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
 	}
 
 	runner := NewTestRunner().
 		WithFileResolver(fileResolver).
-		WithImportResolver(importResolver)
+		WithImportResolver(importResolver).
+		WithContracts(map[string]common.Address{
+			"FooContract": {0, 0, 0, 0, 0, 0, 0, 5},
+		})
+
+	result, err := runner.RunTest(testCode, "test")
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+
+	const startedType = "A.0000000000000005.FooContract.Started"
+	const finishedType = "A.0000000000000005.FooContract.Finished"
+
+	startedEvents, err := runner.backend.QueryEvents(EventFilter{Type: startedType})
+	require.NoError(t, err)
+	require.Len(t, startedEvents, 2)
+
+	// The second transaction emitted exactly the `Started` and `Finished` events, in this order
+	txID := startedEvents[1].TransactionID
+	assert.NotEqual(t, startedEvents[0].TransactionID, txID)
+
+	txEvents, err := runner.backend.QueryEvents(EventFilter{TransactionID: txID})
+	require.NoError(t, err)
+
+	var fooEvents []IndexedEvent
+	for _, event := range txEvents {
+		if event.Type == startedType || event.Type == finishedType {
+			fooEvents = append(fooEvents, event)
+		}
+	}
+	require.Len(t, fooEvents, 2)
+
+	assert.Equal(t, startedType, fooEvents[0].Type)
+	assert.Equal(t, finishedType, fooEvents[1].Type)
+	assert.Less(t, fooEvents[0].EventIndex, fooEvents[1].EventIndex)
+	assert.Equal(t, startedEvents[1].BlockHeight, fooEvents[1].BlockHeight)
+	assert.Equal(t, "A.0000000000000005.FooContract.Finished(n: 2)", fooEvents[1].Value.String())
+
+	// Only the events of the blocks before the second transaction
+	rangeEvents, err := runner.backend.QueryEvents(EventFilter{
+		EndHeight: startedEvents[1].BlockHeight - 1,
+		Type:      finishedType,
+	})
+	require.NoError(t, err)
+	require.Len(t, rangeEvents, 1)
+	assert.Equal(t, startedEvents[0].TransactionID, rangeEvents[0].TransactionID)
+
+	// Rolling back the blockchain also rolls back the events
+	runner.backend.Reset(startedEvents[1].BlockHeight - 1)
+
+	startedEvents, err = runner.backend.QueryEvents(EventFilter{Type: startedType})
+	require.NoError(t, err)
+	require.Len(t, startedEvents, 1)
+}
+
+func TestGetEvents(t *testing.T) {
+	t.Parallel()
+
+	const contractCode = `
+        access(all) contract FooContract {
+
+            access(all) event Started(n: Int)
+            access(all) event Finished(n: Int)
+
+            access(all) fun run(_ n: Int) {
+                emit Started(n: n)
+                emit Finished(n: n)
+            }
+        }
+	`
+
+	const transactionCode = `
+        import FooContract from "../contracts/FooContract.cdc"
+
+        transaction(n: Int) {
+            prepare(acct: &Account) {}
+
+            execute {
+                FooContract.run(n)
+            }
+        }
+	`
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+        import FooContract from "../contracts/FooContract.cdc"
+
+        access(all)
+        let account = Test.getAccount(0x0000000000000005)
+
+        access(all)
+        fun setup() {
+            let err = Test.deployContract(
+                name: "FooContract",
+                path: "../contracts/FooContract.cdc",
+                arguments: []
+            )
+            Test.expect(err, Test.beNil())
+        }
+
+        access(all)
+        fun testFilters() {
+            executeTransaction("../transactions/run.cdc", [1], account)
+            let first = getLastTransactionDetails()!
+            executeTransaction("../transactions/run.cdc", [2], account)
+            let second = getLastTransactionDetails()!
+
+            // By type
+            let started = getEvents(
+                ofType: Type<FooContract.Started>(),
+                fromHeight: 0,
+                toHeight: 0,
+                transactionID: nil
+            )
+            Test.assertEqual(2, started.length)
+            Test.assertEqual(first.id, started[0].transactionID)
+            Test.assertEqual(second.id, started[1].transactionID)
+            Test.assertEqual(1, (started[0].value as! FooContract.Started).n)
+            Test.assertEqual(2, (started[1].value as! FooContract.Started).n)
+            Test.assert(started[0].blockHeight < started[1].blockHeight)
+
+            // By height range
+            let later = getEvents(
+                ofType: Type<FooContract.Started>(),
+                fromHeight: started[1].blockHeight,
+                toHeight: 0,
+                transactionID: nil
+            )
+            Test.assertEqual(1, later.length)
+            Test.assertEqual(second.id, later[0].transactionID)
+
+            let earlier = getEvents(
+                ofType: Type<FooContract.Started>(),
+                fromHeight: 0,
+                toHeight: started[0].blockHeight,
+                transactionID: nil
+            )
+            Test.assertEqual(1, earlier.length)
+            Test.assertEqual(first.id, earlier[0].transactionID)
+
+            // By transaction and type
+            let finished = getEvents(
+                ofType: Type<FooContract.Finished>(),
+                fromHeight: 0,
+                toHeight: 0,
+                transactionID: first.id
+            )
+            Test.assertEqual(1, finished.length)
+            Test.assertEqual(1, (finished[0].value as! FooContract.Finished).n)
+
+            // By transaction, in emission order
+            let fooEvents: [IndexedEvent] = []
+            for event in getTransactionEvents(second.id) {
+                let eventType = event.value.getType()
+                if eventType == Type<FooContract.Started>() || eventType == Type<FooContract.Finished>() {
+                    fooEvents.append(event)
+                }
+            }
+            Test.assertEqual(2, fooEvents.length)
+            Test.assertEqual(Type<FooContract.Started>(), fooEvents[0].value.getType())
+            Test.assertEqual(Type<FooContract.Finished>(), fooEvents[1].value.getType())
+            Test.assert(fooEvents[0].eventIndex < fooEvents[1].eventIndex)
+            Test.assertEqual(fooEvents[0].transactionIndex, fooEvents[1].transactionIndex)
+            Test.assertEqual(started[1].blockHeight, fooEvents[1].blockHeight)
+        }
+
+        access(all)
+        fun testNoMatches() {
+            let events = getEvents(
+                ofType: Type<FooContract.Started>(),
+                fromHeight: getCurrentBlockHeight() + 1,
+                toHeight: 0,
+                transactionID: nil
+            )
+            Test.assertEqual(0, events.length)
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../contracts/FooContract.cdc":
+			return contractCode, nil
+		case "../transactions/run.cdc":
+			return transactionCode, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	importResolver := func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			if location.Name == "FooContract" {
+				return contractCode, nil
+			}
+		case common.StringLocation:
+			if location == "../contracts/FooContract.cdc" {
+				return contractCode, nil
+			}
+		}
+
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
+	}
+
+	runner := NewTestRunner().
+		WithFileResolver(fileResolver).
+		WithImportResolver(importResolver).
+		WithContracts(map[string]common.Address{
+			"FooContract": {0, 0, 0, 0, 0, 0, 0, 5},
+		})
 
 	results, err := runner.RunTests(testCode)
 	require.NoError(t, err)
+	require.Len(t, results, 2)
 	for _, result := range results {
-		require.NoError(t, result.Error)
+		assert.NoError(t, result.Error, result.TestName)
 	}
 }
 
-func TestBlockchainReset(t *testing.T) {
+func TestTransactionDetails(t *testing.T) {
 	t.Parallel()
 
+	const contractCode = `
+        access(all) contract FooContract {
+
+            access(all) event Started(n: Int)
+            access(all) event Finished(n: Int)
+
+            access(all) fun run(_ n: Int) {
+                emit Started(n: n)
+                var i = 0
+                while i < n {
+                    i = i + 1
+                }
+                emit Finished(n: n)
+            }
+        }
+	`
+
+	const transactionCode = `
+        import FooContract from "../contracts/FooContract.cdc"
+
+        transaction(n: Int) {
+            prepare(acct: &Account) {}
+
+            execute {
+                log(n)
+                FooContract.run(n)
+            }
+        }
+	`
+
+	const failingTransactionCode = `
+        transaction {
+            prepare(acct: &Account) {}
+
+            execute {
+                panic("boom")
+            }
+        }
+	`
+
 	const testCode = `
         import Test
         import BlockchainHelpers
+        import FooContract from "../contracts/FooContract.cdc"
 
         access(all)
-        fun testBlockchainReset() {
-            // Arrange
-            let account = Test.createAccount()
-            var balance = getFlowBalance(account: account)
-            Test.assertEqual(0.0, balance)
+        let account = Test.getAccount(0x0000000000000005)
 
-            let height = getCurrentBlockHeight()
+        access(all)
+        fun setup() {
+            let err = Test.deployContract(
+                name: "FooContract",
+                path: "../contracts/FooContract.cdc",
+                arguments: []
+            )
+            Test.expect(err, Test.beNil())
+        }
 
-            mintFlow(to: account, amount: 1500.0)
+        access(all)
+        fun testDetails() {
+            let result = executeTransaction("../transactions/run.cdc", [2], account)
+            Test.expect(result, Test.beSucceeded())
 
-            balance = getFlowBalance(account: account)
-            Test.assertEqual(1500.0, balance)
-            Test.assertEqual(getCurrentBlockHeight(), height + 1)
+            let details = getLastTransactionDetails()!
+            Test.assertEqual(Test.ResultStatus.succeeded, details.status)
+            Test.assert(details.error == nil)
+            Test.assert(details.computationUsed > 0)
+            Test.assert(details.memoryEstimate > 0)
+            Test.assertEqual(["2"], details.logs)
 
-            // Act
-            Test.reset(to: height)
+            let started = details.eventsOfType(Type<FooContract.Started>())
+            Test.assertEqual(1, started.length)
+            Test.assertEqual(2, (started[0] as! FooContract.Started).n)
+            Test.assertEqual(1, details.eventsOfType(Type<FooContract.Finished>()).length)
 
-            // Assert
-            balance = getFlowBalance(account: account)
-            Test.assertEqual(0.0, balance)
-            Test.assertEqual(getCurrentBlockHeight(), height)
+            Test.assertEqual(details.id, getTransactionDetails(details.id).id)
+        }
+
+        access(all)
+        fun testComputationBudget() {
+            executeTransaction("../transactions/run.cdc", [1], account)
+            let small = getLastTransactionDetails()!
+
+            executeTransaction("../transactions/run.cdc", [100], account)
+            let large = getLastTransactionDetails()!
+
+            Test.assert(large.computationUsed > small.computationUsed)
+
+            let transactions = getExecutedTransactions()
+            Test.assertEqual(large.id, transactions[transactions.length - 1].id)
+            Test.assertEqual(small.id, transactions[transactions.length - 2].id)
+        }
+
+        access(all)
+        fun testFailedTransaction() {
+            let result = executeTransaction("../transactions/fail.cdc", [], account)
+            Test.expect(result, Test.beFailed())
+
+            let details = getLastTransactionDetails()!
+            Test.assertEqual(Test.ResultStatus.failed, details.status)
+            Test.assertError(result, errorMessage: "boom")
+            Test.assertEqual(result.error!.message, details.error!.message)
+        }
+
+        access(all)
+        fun testUnknownTransaction() {
+            getTransactionDetails("0000000000000000000000000000000000000000000000000000000000000000")
         }
 	`
 
-	runner := NewTestRunner()
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../contracts/FooContract.cdc":
+			return contractCode, nil
+		case "../transactions/run.cdc":
+			return transactionCode, nil
+		case "../transactions/fail.cdc":
+			return failingTransactionCode, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
 
-	result, err := runner.RunTest(testCode, "testBlockchainReset")
+	importResolver := func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			if location.Name == "FooContract" {
+				return contractCode, nil
+			}
+		case common.StringLocation:
+			if location == "../contracts/FooContract.cdc" {
+				return contractCode, nil
+			}
+		}
+
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
+	}
+
+	runner := NewTestRunner().
+		WithFileResolver(fileResolver).
+		WithImportResolver(importResolver).
+		WithContracts(map[string]common.Address{
+			"FooContract": {0, 0, 0, 0, 0, 0, 0, 5},
+		})
+
+	results, err := runner.RunTests(testCode)
 	require.NoError(t, err)
-	require.NoError(t, result.Error)
+	require.Len(t, results, 4)
+
+	for _, result := range results[:3] {
+		assert.NoError(t, result.Error, result.TestName)
+	}
+
+	require.Error(t, results[3].Error)
+	assert.ErrorContains(t, results[3].Error, "transaction not found")
+
+	// The details are also available through the Go API
+	last := runner.backend.LastTransaction()
+	require.NotNil(t, last)
+	assert.False(t, last.Succeeded())
+	assert.ErrorContains(t, last.Error, "boom")
+
+	transactions := runner.backend.ExecutedTransactions()
+	require.GreaterOrEqual(t, len(transactions), 4)
+
+	details, err := runner.backend.TransactionDetails(transactions[len(transactions)-2].ID)
+	require.NoError(t, err)
+	assert.True(t, details.Succeeded())
+	assert.Equal(t, []string{"100"}, details.Logs)
+	assert.NotZero(t, details.ComputationUsed)
+
+	events, err := runner.backend.QueryEvents(EventFilter{TransactionID: details.ID})
+	require.NoError(t, err)
+	assert.Len(t, events, len(details.Events))
+
+	// Rolling back the blockchain also discards the details of the rolled back transactions
+	runner.backend.Reset(details.BlockHeight - 1)
+
+	_, err = runner.backend.TransactionDetails(details.ID)
+	require.ErrorAs(t, err, &TransactionNotFoundError{})
 }
 
-func TestTestFunctionValidSignature(t *testing.T) {
+func TestExecuteNextTransactionDetails(t *testing.T) {
 	t.Parallel()
 
-	t.Run("with argument", func(t *testing.T) {
-		t.Parallel()
+	backend := NewEmulatorBackend(zerolog.Nop(), nil, nil)
 
-		const testCode = `
-            import Test
+	serviceAccount, err := backend.ServiceAccount()
+	require.NoError(t, err)
 
-            access(all) fun testValidSignature() {
-                Test.assertEqual(2, 5 - 3)
-            }
+	addTransaction := func(code string) {
+		err := backend.AddTransaction(
+			nil,
+			code,
+			[]common.Address{serviceAccount.Address},
+			[]*stdlib.Account{serviceAccount},
+			nil,
+		)
+		require.NoError(t, err)
+	}
 
-            access(all) fun testInvalidSignature(prefix: String) {
-                Test.assertEqual(2, 5 - 3)
+	addTransaction(`
+        transaction {
+            prepare(acct: &Account) {
+                log("first")
             }
-		`
+        }
+	`)
+	addTransaction(`
+        transaction {
+            prepare(acct: &Account) {
+                panic("boom")
+            }
+        }
+	`)
 
-		runner := NewTestRunner()
+	details, err := backend.ExecuteNextTransactionDetails()
+	require.NoError(t, err)
+	require.NotNil(t, details)
+	assert.True(t, details.Succeeded())
+	assert.Equal(t, []string{`"first"`}, details.Logs)
+	assert.Same(t, backend.LastTransaction(), details)
+
+	// The result of a failed transaction carries its error
+	result := backend.ExecuteNextTransaction()
+	require.NotNil(t, result)
+	assert.ErrorContains(t, result.Error, "boom")
+	assert.Equal(t, backend.LastTransaction().Error, result.Error)
+
+	// There are no more transactions to execute
+	details, err = backend.ExecuteNextTransactionDetails()
+	require.NoError(t, err)
+	assert.Nil(t, details)
+	assert.Nil(t, backend.ExecuteNextTransaction())
 
-		_, err := runner.RunTests(testCode)
-		require.Error(t, err)
-		assert.ErrorContains(t, err, "test functions should have no arguments")
+	require.NoError(t, backend.CommitBlock())
+}
+
+func TestMultiKeyAccounts(t *testing.T) {
+	t.Parallel()
+
+	const transactionCode = `
+        transaction {
+            prepare(acct: &Account) {}
+        }
+	`
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all)
+        let account = createAccountWithKeys([
+            AccountKeyOptions(
+                weight: 500.0,
+                signatureAlgorithm: SignatureAlgorithm.ECDSA_P256,
+                hashAlgorithm: HashAlgorithm.SHA3_256
+            ),
+            AccountKeyOptions(
+                weight: 500.0,
+                signatureAlgorithm: SignatureAlgorithm.ECDSA_secp256k1,
+                hashAlgorithm: HashAlgorithm.SHA2_256
+            ),
+            AccountKeyOptions(
+                weight: 1000.0,
+                signatureAlgorithm: SignatureAlgorithm.ECDSA_P256,
+                hashAlgorithm: HashAlgorithm.SHA3_256
+            )
+        ])
+
+        access(all)
+        fun testAllKeysSign() {
+            let result = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(result, Test.beSucceeded())
+        }
+
+        access(all)
+        fun testMultiSig() {
+            setNextTransactionSigningKeys(account, keyIndices: [0, 1])
+            let result = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(result, Test.beSucceeded())
+        }
+
+        access(all)
+        fun testRestrictedProposer() {
+            // The proposer also signs with the proposal key
+            setNextTransactionProposer(account, keyIndex: 2)
+            setNextTransactionSigningKeys(account, keyIndices: [0, 1])
+            let result = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(result, Test.beSucceeded())
+        }
+
+        access(all)
+        fun testInsufficientWeight() {
+            setNextTransactionSigningKeys(account, keyIndices: [1])
+            let result = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(result, Test.beFailed())
+
+            // The signing keys only apply to the next transaction
+            let nextResult = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(nextResult, Test.beSucceeded())
+        }
+
+        access(all)
+        fun testRevokeKey() {
+            let result = revokeAccountKey(account, keyIndex: 2)
+            Test.expect(result, Test.beSucceeded())
+
+            let scriptResult = Test.executeScript(
+                "access(all) fun main(address: Address): Bool { return getAccount(address).keys.get(keyIndex: 2)!.isRevoked }",
+                [account.address]
+            )
+            Test.assertEqual(true, scriptResult.returnValue! as! Bool)
+
+            // The revoked key no longer signs
+            setNextTransactionSigningKeys(account, keyIndices: [2])
+            Test.expect(
+                executeTransaction("../transactions/noop.cdc", [], account),
+                Test.beFailed()
+            )
+
+            // The remaining keys still have the full weight
+            Test.expect(
+                executeTransaction("../transactions/noop.cdc", [], account),
+                Test.beSucceeded()
+            )
+        }
+
+        access(all)
+        fun testProposerAndPayer() {
+            let sponsor = Test.createAccount()
+            let proposer = Test.createAccount()
+
+            setNextTransactionProposer(proposer, keyIndex: 0)
+            setNextTransactionPayer(sponsor)
+            setNextTransactionSigningKeys(account, keyIndices: [0, 1])
+            let result = executeTransaction("../transactions/noop.cdc", [], account)
+            Test.expect(result, Test.beSucceeded())
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../transactions/noop.cdc":
+			return transactionCode, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	runner := NewTestRunner().WithFileResolver(fileResolver)
+
+	results, err := runner.RunTests(testCode)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+
+	for _, result := range results {
+		assert.NoError(t, result.Error, result.TestName)
+	}
+
+	// The proposal key of the proposer was used
+	last := runner.backend.LastTransaction()
+	require.NotNil(t, last)
+	require.True(t, last.Succeeded())
+
+	tx, err := runner.backend.blockchain.GetTransaction(flow.Identifier(last.ID))
+	require.NoError(t, err)
+	assert.NotEqual(t, tx.Payer, tx.ProposalKey.Address)
+	assert.NotEqual(t, runner.backend.blockchain.ServiceKey().Address, sdk.Address(tx.Payer))
+
+	proposalKey, err := runner.backend.onChainAccountKey(common.Address(tx.ProposalKey.Address), 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), proposalKey.SeqNumber)
+}
+
+func TestCreateAccountWithFractionalKeyWeight(t *testing.T) {
+	t.Parallel()
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all)
+        fun test() {
+            createAccountWithKeys([
+                AccountKeyOptions(
+                    weight: 999.5,
+                    signatureAlgorithm: SignatureAlgorithm.ECDSA_P256,
+                    hashAlgorithm: HashAlgorithm.SHA3_256
+                )
+            ])
+        }
+	`
+
+	runner := NewTestRunner()
+
+	result, err := runner.RunTest(testCode, "test")
+	require.NoError(t, err)
+	require.Error(t, result.Error)
+	assert.ErrorContains(
+		t,
+		result.Error,
+		"invalid weight of key 0: expected a whole number, got 999.50000000",
+	)
+}
+
+func TestCreateAccountWithKeys(t *testing.T) {
+	t.Parallel()
+
+	backend := NewEmulatorBackend(zerolog.Nop(), nil, nil)
+
+	_, err := backend.CreateAccountWithKeys(nil)
+	require.ErrorContains(t, err, "account must have at least one key")
+
+	_, err = backend.CreateAccountWithKeys([]AccountKeyOptions{
+		{
+			Weight:             1001,
+			SignatureAlgorithm: crypto.ECDSA_P256,
+			HashAlgorithm:      crypto.SHA3_256,
+		},
+	})
+	require.ErrorContains(t, err, "invalid weight of key 0")
+
+	account, err := backend.CreateAccountWithKeys([]AccountKeyOptions{
+		{
+			Weight:             250,
+			SignatureAlgorithm: crypto.ECDSA_P256,
+			HashAlgorithm:      crypto.SHA3_256,
+		},
+		{
+			Weight:             750,
+			SignatureAlgorithm: crypto.ECDSA_secp256k1,
+			HashAlgorithm:      crypto.SHA2_256,
+		},
+	})
+	require.NoError(t, err)
+
+	flowAccount, err := backend.blockchain.GetAccount(flow.Address(account.Address))
+	require.NoError(t, err)
+	require.Len(t, flowAccount.Keys, 2)
+
+	assert.Equal(t, 250, flowAccount.Keys[0].Weight)
+	assert.Equal(t, 750, flowAccount.Keys[1].Weight)
+	assert.Equal(t, crypto.ECDSA_secp256k1, flowAccount.Keys[1].SignAlgo)
+	assert.Equal(t, crypto.SHA2_256, flowAccount.Keys[1].HashAlgo)
+	assert.Equal(t, flowAccount.Keys[0].PublicKey.Encode(), account.PublicKey.PublicKey)
+
+	keys, err := backend.signingKeys(account.Address, TransactionOptions{})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, 0, keys[0].accountKey.Index)
+	assert.Equal(t, 1, keys[1].accountKey.Index)
+
+	_, err = backend.signingKeys(account.Address, TransactionOptions{
+		SigningKeys: map[common.Address][]int{
+			account.Address: {2},
+		},
+	})
+	require.ErrorContains(t, err, "has no key with index 2")
+
+	// A key revoked by a transaction of the pending block no longer signs
+	tx, err := backend.newTransaction(
+		"transaction { prepare(acct: auth(RevokeKey) &Account) { acct.keys.revoke(keyIndex: 0) } }",
+		[]common.Address{account.Address},
+		TransactionOptions{},
+	)
+	require.NoError(t, err)
+	err = backend.signTransaction(tx, []*stdlib.Account{account}, TransactionOptions{})
+	require.NoError(t, err)
+	err = backend.submitTransaction(tx)
+	require.NoError(t, err)
+
+	details, err := backend.ExecuteNextTransactionDetails()
+	require.NoError(t, err)
+	require.NoError(t, details.Error)
+
+	keys, err = backend.signingKeys(account.Address, TransactionOptions{})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, 1, keys[0].accountKey.Index)
+}
+
+func TestStorageLimitAndFees(t *testing.T) {
+	t.Parallel()
+
+	const transactionCode = `
+        transaction(size: Int) {
+            prepare(acct: auth(SaveValue) &Account) {
+                var data: [UInt64] = []
+                var i = 0
+                while i < size {
+                    data.append(UInt64(i))
+                    i = i + 1
+                }
+                acct.storage.save(data, to: /storage/data)
+            }
+        }
+	`
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all)
+        fun testStorage() {
+            let account = Test.createAccount()
+            Test.assert(getStorageUsed(account) > 0)
+
+            let result = executeTransaction("../transactions/store.cdc", [20000], account)
+            Test.expect(result, %s)
+
+            if result.status == Test.ResultStatus.succeeded {
+                Test.assert(getStorageUsed(account) > 100_000)
+            } else {
+                Test.assertEqual(100_000 as UInt64, getStorageCapacity(account))
+                Test.assert(getStorageUsed(account) < getStorageCapacity(account))
+            }
+        }
+
+        access(all)
+        fun testFees() {
+            let account = Test.createAccount()
+            mintFlow(to: account, amount: 10.0)
+
+            let balance = getAvailableBalance(account)
+            Test.assert(balance > 0.0)
+            Test.assert(balance <= getFlowBalance(account: account))
+
+            setNextTransactionPayer(account)
+            let result = executeTransaction("../transactions/store.cdc", [1], account)
+            Test.expect(result, Test.beSucceeded())
+
+            Test.assert(getAvailableBalance(account) %s balance)
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../transactions/store.cdc":
+			return transactionCode, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		runner := NewTestRunner().WithFileResolver(fileResolver)
+
+		results, err := runner.RunTests(fmt.Sprintf(testCode, "Test.beSucceeded()", "=="))
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.NoError(t, result.Error, result.TestName)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		runner := NewTestRunner().
+			WithFileResolver(fileResolver).
+			WithStorageLimit(true).
+			WithTransactionFees(true)
+
+		results, err := runner.RunTests(fmt.Sprintf(testCode, "Test.beFailed()", "<"))
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.NoError(t, result.Error, result.TestName)
+		}
+	})
+}
+
+func TestCoverageExport(t *testing.T) {
+	t.Parallel()
+
+	fooLocation := common.AddressLocation{
+		Address: common.Address{0, 0, 0, 0, 0, 0, 0, 9},
+		Name:    "FooContract",
+	}
+	barLocation := common.StringLocation("./contracts/../contracts/BarContract.cdc")
+
+	const fooContract = `access(all) contract FooContract {
+    access(all) fun foo(): Int {
+        let a = 1
+        return a
+    }
+}`
+
+	newCoverageReport := func() *runtime.CoverageReport {
+		coverageReport := runtime.NewCoverageReport()
+		coverageReport.Coverage[fooLocation] = &runtime.LocationCoverage{
+			LineHits:   map[int]int{3: 2, 4: 2},
+			Statements: 2,
+		}
+		coverageReport.Coverage[barLocation] = &runtime.LocationCoverage{
+			LineHits:   map[int]int{2: 1, 5: 0},
+			Statements: 2,
+		}
+		return coverageReport
+	}
+
+	contracts := map[string]common.Address{
+		"FooContract": fooLocation.Address,
+	}
+	paths := NewCoveragePathMapper(contracts, map[string]string{
+		"FooContract": "contracts/FooContract.cdc",
+	})
+
+	fileResolver := func(path string) (string, error) {
+		if path == "contracts/FooContract.cdc" {
+			return fooContract, nil
+		}
+		return "", fmt.Errorf("cannot find file path: %s", path)
+	}
+
+	t.Run("paths", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "contracts/FooContract.cdc", paths(fooLocation))
+		assert.Equal(t, "contracts/BarContract.cdc", paths(barLocation))
+
+		// The contract is not deployed at the address of the contracts mapping
+		otherLocation := common.AddressLocation{
+			Address: common.Address{0, 0, 0, 0, 0, 0, 0, 1},
+			Name:    "FooContract",
+		}
+		assert.Equal(t, "A.0000000000000001.FooContract", paths(otherLocation))
+	})
+
+	t.Run("lcov", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("lcov", paths, nil)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newCoverageReport())
+		require.NoError(t, err)
+
+		assert.Equal(
+			t,
+			"TN:\nSF:contracts/BarContract.cdc\nDA:2,1\nDA:5,0\nLF:2\nLH:1\nend_of_record\n"+
+				"TN:\nSF:contracts/FooContract.cdc\nDA:3,2\nDA:4,2\nLF:2\nLH:2\nend_of_record\n",
+			buffer.String(),
+		)
+	})
+
+	// The covered lines exceed the inspected statements
+	newInconsistentCoverageReport := func() *runtime.CoverageReport {
+		coverageReport := runtime.NewCoverageReport()
+		coverageReport.Coverage[fooLocation] = &runtime.LocationCoverage{
+			LineHits:   map[int]int{2: 1, 3: 1, 4: 0},
+			Statements: 1,
+		}
+		return coverageReport
+	}
+
+	t.Run("lcov, more covered lines than statements", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("lcov", paths, nil)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newInconsistentCoverageReport())
+		require.NoError(t, err)
+
+		assert.Equal(
+			t,
+			"TN:\nSF:contracts/FooContract.cdc\nDA:2,1\nDA:3,1\nDA:4,0\nLF:3\nLH:2\nend_of_record\n",
+			buffer.String(),
+		)
+	})
+
+	t.Run("html, more covered lines than statements", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("html", paths, fileResolver)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newInconsistentCoverageReport())
+		require.NoError(t, err)
+
+		assert.Contains(
+			t,
+			buffer.String(),
+			`<tr><td><a href="#file-0">contracts/FooContract.cdc</a></td><td>1</td><td>1</td><td>100.0%</td></tr>`,
+		)
+	})
+
+	t.Run("cobertura", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("cobertura", paths, nil)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newCoverageReport())
+		require.NoError(t, err)
+
+		assert.Contains(t, buffer.String(), "<!DOCTYPE coverage")
+
+		var coverage coberturaCoverage
+		err = xml.Unmarshal(buffer.Bytes(), &coverage)
+		require.NoError(t, err)
+
+		assert.Equal(t, "0.7500", coverage.LineRate)
+		assert.Equal(t, 3, coverage.LinesCovered)
+		assert.Equal(t, 4, coverage.LinesValid)
+		require.Len(t, coverage.Packages, 1)
+
+		pkg := coverage.Packages[0]
+		assert.Equal(t, "contracts", pkg.Name)
+		require.Len(t, pkg.Classes, 2)
+
+		assert.Equal(t, "BarContract", pkg.Classes[0].Name)
+		assert.Equal(t, "0.5000", pkg.Classes[0].LineRate)
+		assert.Equal(t, "FooContract", pkg.Classes[1].Name)
+		assert.Equal(t, "contracts/FooContract.cdc", pkg.Classes[1].Filename)
+		assert.Equal(
+			t,
+			[]coberturaLine{{Number: 3, Hits: 2}, {Number: 4, Hits: 2}},
+			pkg.Classes[1].Lines,
+		)
+	})
+
+	t.Run("cobertura, more covered lines than statements", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("cobertura", paths, nil)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newInconsistentCoverageReport())
+		require.NoError(t, err)
+
+		var coverage coberturaCoverage
+		err = xml.Unmarshal(buffer.Bytes(), &coverage)
+		require.NoError(t, err)
+
+		require.Len(t, coverage.Packages, 1)
+		pkg := coverage.Packages[0]
+		require.Len(t, pkg.Classes, 1)
+
+		// The totals and rates match the line entries
+		lines := pkg.Classes[0].Lines
+		hitLines := 0
+		for _, line := range lines {
+			if line.Hits > 0 {
+				hitLines++
+			}
+		}
+
+		assert.Equal(t, 3, len(lines))
+		assert.Equal(t, 2, hitLines)
+		assert.Equal(t, hitLines, coverage.LinesCovered)
+		assert.Equal(t, len(lines), coverage.LinesValid)
+		assert.Equal(t, "0.6667", coverage.LineRate)
+		assert.Equal(t, "0.6667", pkg.LineRate)
+		assert.Equal(t, "0.6667", pkg.Classes[0].LineRate)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		t.Parallel()
+
+		exporter, err := NewCoverageExporter("html", paths, fileResolver)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		err = exporter.Export(&buffer, newCoverageReport())
+		require.NoError(t, err)
+
+		output := buffer.String()
+		assert.Contains(t, output, "<h1>Coverage report: 75.0%</h1>")
+		assert.Contains(t, output, `<a href="#file-1">contracts/FooContract.cdc</a>`)
+		assert.Contains(
+			t,
+			output,
+			`<tr class="covered"><td class="number">3</td><td class="hits">2x</td><td>        let a = 1</td></tr>`,
+		)
+		assert.Contains(
+			t,
+			output,
+			`<tr class=""><td class="number">1</td><td class="hits"></td><td>access(all) contract FooContract {</td></tr>`,
+		)
+		// The source of BarContract is not available, only its lines are shown
+		assert.Contains(
+			t,
+			output,
+			`<tr class="missed"><td class="number">5</td><td class="hits">0x</td><td></td></tr>`,
+		)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewCoverageExporter("xml", paths, nil)
+		require.ErrorContains(t, err, "unsupported coverage format: xml")
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		t.Parallel()
+
+		coverageReport := newCoverageReport()
+		assert.Equal(t, 75.0, TotalCoverage(coverageReport))
+
+		require.NoError(t, CheckCoverageThreshold(coverageReport, 75))
+
+		err := CheckCoverageThreshold(coverageReport, 80)
+		var thresholdErr *CoverageThresholdError
+		require.ErrorAs(t, err, &thresholdErr)
+		assert.Equal(t, "coverage of 75.0% is below the minimum coverage of 80.0%", err.Error())
+	})
+}
+
+func TestStateExport(t *testing.T) {
+	t.Parallel()
+
+	const counterContract = `
+        access(all)
+        contract Counter {
+
+            access(all)
+            var count: Int
+
+            init(count: Int) {
+                self.count = count
+                self.account.storage.save("hello from the fork", to: /storage/greeting)
+            }
+
+            access(all)
+            fun greeting(): String {
+                return *self.account.storage.borrow<&String>(from: /storage/greeting)!
+            }
+        }
+	`
+
+	const greetingScript = `
+        import Counter from "Counter"
+
+        access(all)
+        fun main(): String {
+            return Counter.greeting()
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../contracts/Counter.cdc":
+			return counterContract, nil
+		case "../scripts/greeting.cdc":
+			return greetingScript, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	importResolver := func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			if location.Name == "Counter" {
+				return counterContract, nil
+			}
+		case common.StringLocation:
+			if location == "Counter" {
+				return counterContract, nil
+			}
+		}
+
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
+	}
+
+	counterAddress := common.Address{0, 0, 0, 0, 0, 0, 0, 7}
+
+	contracts := map[string]common.Address{
+		"Counter": counterAddress,
+	}
+
+	const deployCode = `
+        import Test
+
+        access(all)
+        fun testDeploy() {
+            let err = Test.deployContract(
+                name: "Counter",
+                path: "../contracts/Counter.cdc",
+                arguments: [42]
+            )
+            Test.expect(err, Test.beNil())
+        }
+	`
+
+	runner := NewTestRunner().
+		WithImportResolver(importResolver).
+		WithFileResolver(fileResolver).
+		WithContracts(contracts)
+
+	result, err := runner.RunTest(deployCode, "testDeploy")
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+
+	export, err := runner.backend.ExportState(counterAddress)
+	require.NoError(t, err)
+	require.Contains(t, export.Accounts, counterAddress.Hex())
+
+	var buffer bytes.Buffer
+	err = export.Write(&buffer)
+	require.NoError(t, err)
+
+	export, err = ReadStateExport(&buffer)
+	require.NoError(t, err)
+
+	const forkCode = `
+        import Test
+        import Counter from "Counter"
+
+        access(all)
+        fun testFork() {
+            Test.assertEqual(42, Counter.count)
+
+            let result = Test.executeScript(
+                Test.readFile("../scripts/greeting.cdc"),
+                []
+            )
+            Test.expect(result, Test.beSucceeded())
+            Test.assertEqual("hello from the fork", result.returnValue! as! String)
+        }
+	`
+
+	forkRunner := NewTestRunner().
+		WithImportResolver(importResolver).
+		WithFileResolver(fileResolver).
+		WithContracts(contracts).
+		WithState(export)
+
+	result, err = forkRunner.RunTest(forkCode, "testFork")
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+
+	// The state is imported as a new block, the state of the previous blocks is unchanged
+	backend := NewEmulatorBackend(zerolog.Nop(), nil, nil)
+
+	previousBlock, err := backend.blockchain.GetLatestBlock()
+	require.NoError(t, err)
+
+	err = backend.ImportState(export)
+	require.NoError(t, err)
+
+	importBlock, err := backend.blockchain.GetLatestBlock()
+	require.NoError(t, err)
+	require.Equal(t, previousBlock.Header.Height+1, importBlock.Header.Height)
+
+	contractRegisterID := flow.ContractRegisterID(flow.Address(counterAddress), "Counter")
+
+	previousLedger, err := backend.store.LedgerByHeight(context.Background(), previousBlock.Header.Height)
+	require.NoError(t, err)
+	value, err := previousLedger.Get(contractRegisterID)
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	importLedger, err := backend.store.LedgerByHeight(context.Background(), importBlock.Header.Height)
+	require.NoError(t, err)
+	value, err = importLedger.Get(contractRegisterID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, value)
+
+	// Rolling back the blockchain also rolls back the import
+	backend.Reset(previousBlock.Header.Height)
+
+	account, err := backend.blockchain.GetAccount(flow.Address(counterAddress))
+	require.NoError(t, err)
+	assert.NotContains(t, account.Contracts, "Counter")
+
+	// The registers of an existing account which are not in the state are removed
+	staleRegisterID := flow.NewRegisterID(flow.Address(counterAddress), stdlib.InboxStorageDomain)
+	staleRegisterKey := fmt.Sprintf("%x", staleRegisterID.Key)
+
+	staleRegisters := map[string]string{staleRegisterKey: "01"}
+	for key, value := range export.Accounts[counterAddress.Hex()].Registers {
+		staleRegisters[key] = value
+	}
+	staleExport := &StateExport{
+		Version: StateExportVersion,
+		Accounts: map[string]AccountState{
+			counterAddress.Hex(): {
+				Registers: staleRegisters,
+			},
+		},
+	}
+
+	latestRegister := func(registerID flow.RegisterID) []byte {
+		latestBlock, err := backend.blockchain.GetLatestBlock()
+		require.NoError(t, err)
+		ledger, err := backend.store.LedgerByHeight(context.Background(), latestBlock.Header.Height)
+		require.NoError(t, err)
+		value, err := ledger.Get(registerID)
+		require.NoError(t, err)
+		return value
+	}
+
+	err = backend.ImportState(staleExport)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, latestRegister(staleRegisterID))
+
+	err = backend.ImportState(export)
+	require.NoError(t, err)
+	assert.Empty(t, latestRegister(staleRegisterID))
+	assert.NotEmpty(t, latestRegister(contractRegisterID))
+
+	_, err = runner.backend.ExportState(common.Address{0, 0, 0, 0, 0, 0, 0xff, 0xff})
+	require.Error(t, err)
+
+	_, err = ReadStateExport(strings.NewReader(`{"version": 2, "accounts": {}}`))
+	require.ErrorContains(t, err, "unsupported state export version: expected 1, got 2")
+
+	invalidExport := &StateExport{
+		Version: StateExportVersion,
+		Accounts: map[string]AccountState{
+			counterAddress.Hex(): {
+				Registers: map[string]string{"zz": ""},
+			},
+		},
+	}
+
+	result, err = NewTestRunner().
+		WithState(invalidExport).
+		RunTest(forkCode, "testFork")
+	require.ErrorContains(t, err, "invalid register key of account")
+	require.Nil(t, result)
+}
+
+func TestContractUpdateAndRemoval(t *testing.T) {
+	t.Parallel()
+
+	const counterContract = `
+        access(all)
+        contract Counter {
+
+            access(all)
+            var count: Int
+
+            init() {
+                self.count = 1
+            }
+
+            access(all)
+            fun increment() {
+                self.count = self.count + 1
+            }
+
+            access(all)
+            fun version(): Int {
+                return 1
+            }
+        }
+	`
+
+	const updatedCounterContract = `
+        access(all)
+        contract Counter {
+
+            access(all)
+            var count: Int
+
+            init() {
+                self.count = 1
+            }
+
+            access(all)
+            fun increment() {
+                self.count = self.count + 10
+            }
+
+            access(all)
+            fun version(): Int {
+                return 2
+            }
+        }
+	`
+
+	const incompatibleCounterContract = `
+        access(all)
+        contract Counter {
+
+            access(all)
+            var count: String
+
+            init() {
+                self.count = "one"
+            }
+        }
+	`
+
+	const versionScript = `
+        import Counter from "Counter"
+
+        access(all)
+        fun main(): Int {
+            return Counter.version()
+        }
+	`
+
+	const incrementTransaction = `
+        import Counter from "Counter"
+
+        transaction {
+            prepare(acct: &Account) {}
+
+            execute {
+                Counter.increment()
+            }
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../contracts/Counter.cdc":
+			return counterContract, nil
+		case "../contracts/UpdatedCounter.cdc":
+			return updatedCounterContract, nil
+		case "../contracts/IncompatibleCounter.cdc":
+			return incompatibleCounterContract, nil
+		case "../scripts/version.cdc":
+			return versionScript, nil
+		case "../transactions/increment.cdc":
+			return incrementTransaction, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	importResolver := func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.AddressLocation:
+			if location.Name == "Counter" {
+				return counterContract, nil
+			}
+		case common.StringLocation:
+			if location == "Counter" {
+				return counterContract, nil
+			}
+		}
+
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
+	}
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+        import Counter from "Counter"
+
+        access(all)
+        fun setup() {
+            let err = Test.deployContract(
+                name: "Counter",
+                path: "../contracts/Counter.cdc",
+                arguments: []
+            )
+            Test.expect(err, Test.beNil())
+        }
+
+        access(all)
+        fun version(): Int {
+            let scriptResult = executeScript("../scripts/version.cdc", [])
+            Test.expect(scriptResult, Test.beSucceeded())
+            return scriptResult.returnValue! as! Int
+        }
+
+        access(all)
+        fun testUpdate() {
+            Test.assertEqual(1, version())
+            Test.assertEqual(1, Counter.count)
+
+            let result = executeTransaction("../transactions/increment.cdc", [], Test.serviceAccount())
+            Test.expect(result, Test.beSucceeded())
+
+            let err = updateContract(name: "Counter", path: "../contracts/UpdatedCounter.cdc")
+            Test.expect(err, Test.beNil())
+            Test.assertEqual(2, version())
+
+            // The value of the contract is reloaded after the update.
+            Test.assertEqual(2, Counter.count)
+        }
+
+        access(all)
+        fun testIncompatibleUpdate() {
+            let err = updateContract(name: "Counter", path: "../contracts/IncompatibleCounter.cdc")
+            Test.expect(err, Test.not(Test.beNil()))
+            Test.assert(err!.message.slice(from: 0, upTo: 33) == "failed to update contract Counter")
+            Test.assertEqual(1, version())
+        }
+
+        access(all)
+        fun testRemove() {
+            var err = removeContract(name: "Counter")
+            Test.expect(err, Test.beNil())
+
+            let result = executeScript("../scripts/version.cdc", [])
+            Test.expect(result, Test.beFailed())
+
+            err = removeContract(name: "Counter")
+            Test.expect(err, Test.not(Test.beNil()))
+        }
+
+        access(all)
+        fun testRemoveDisabled() {
+            let err = removeContract(name: "Counter")
+            Test.expect(err, Test.not(Test.beNil()))
+            Test.assertEqual(1, version())
+        }
+	`
+
+	contracts := map[string]common.Address{
+		"Counter": {0, 0, 0, 0, 0, 0, 0, 7},
+	}
+
+	for _, testName := range []string{"testUpdate", "testIncompatibleUpdate", "testRemove", "testRemoveDisabled"} {
+		// Contract removal is disabled by default, like on a real network
+		runner := NewTestRunner().
+			WithImportResolver(importResolver).
+			WithFileResolver(fileResolver).
+			WithContracts(contracts).
+			WithContractRemoval(testName != "testRemoveDisabled")
+
+		result, err := runner.RunTest(testCode, testName)
+		require.NoError(t, err)
+		require.NoError(t, result.Error, testName)
+	}
+
+	backend := NewEmulatorBackend(zerolog.Nop(), nil, nil)
+	backend.fileResolver = fileResolver
+	backend.contracts = contracts
+
+	err := backend.DeployContract(nil, "Counter", "../contracts/Counter.cdc", nil)
+	require.NoError(t, err)
+
+	err = backend.UpdateContract("Counter", "../contracts/IncompatibleCounter.cdc")
+	var updateErr *ContractUpdateError
+	require.ErrorAs(t, err, &updateErr)
+	assert.Equal(t, "Counter", updateErr.Name)
+	assert.NotEmpty(t, updateErr.ValidationErrors())
+
+	err = backend.RemoveContract("Unknown")
+	var removalErr *ContractRemovalError
+	require.ErrorAs(t, err, &removalErr)
+	assert.Equal(t, "Unknown", removalErr.Name)
+}
+
+func TestContractMocks(t *testing.T) {
+	t.Parallel()
+
+	const oracleContract = `
+        access(all)
+        contract Oracle {
+
+            access(all)
+            event PriceUpdated(price: UFix64)
+
+            access(all)
+            struct interface Priced {
+                access(all)
+                let price: UFix64
+            }
+
+            access(all)
+            struct Quote: Priced {
+                access(all)
+                let price: UFix64
+
+                init(price: UFix64) {
+                    self.price = price
+                }
+            }
+
+            access(self)
+            let feed: String
+
+            init(feed: String) {
+                self.feed = feed
+            }
+
+            access(all)
+            fun quote(_ symbol: String): Quote {
+                panic("cannot fetch a quote from the feed: ".concat(self.feed))
+            }
+        }
+	`
+
+	const oracleMock = `
+        access(all)
+        contract Oracle {
+
+            access(all)
+            event PriceUpdated(price: UFix64)
+
+            access(all)
+            struct interface Priced {
+                access(all)
+                let price: UFix64
+            }
+
+            access(all)
+            struct Quote: Priced {
+                access(all)
+                let price: UFix64
+
+                init(price: UFix64) {
+                    self.price = price
+                }
+            }
+
+            access(all)
+            fun quote(_ symbol: String): Quote {
+                return Quote(price: 21.0)
+            }
+        }
+	`
+
+	// The nested type of the mock does not conform to the interface
+	const nonConformingOracleMock = `
+        access(all)
+        contract Oracle {
+
+            access(all)
+            event PriceUpdated(price: UFix64)
+
+            access(all)
+            struct interface Priced {
+                access(all)
+                let price: UFix64
+            }
+
+            access(all)
+            struct Quote {
+                access(all)
+                let price: UFix64
+
+                init(price: UFix64) {
+                    self.price = price
+                }
+            }
+
+            access(all)
+            fun quote(_ symbol: String): Quote {
+                return Quote(price: 21.0)
+            }
+        }
+	`
+
+	const invalidOracleMock = `
+        access(all)
+        contract Oracle {
+
+            access(all)
+            struct Quote {
+                access(all)
+                let price: UInt64
+
+                init(price: UInt64) {
+                    self.price = price
+                }
+            }
+        }
+	`
+
+	const marketContract = `
+        import Oracle from "Oracle"
+
+        access(all)
+        contract Market {
+
+            access(all)
+            fun price(_ symbol: String, amount: UFix64): UFix64 {
+                return Oracle.quote(symbol).price * amount
+            }
+        }
+	`
+
+	const priceScript = `
+        import Market from "Market"
+
+        access(all)
+        fun main(): UFix64 {
+            return Market.price("FLOW", amount: 2.0)
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../contracts/Oracle.cdc":
+			return oracleContract, nil
+		case "../contracts/Market.cdc":
+			return marketContract, nil
+		case "../scripts/price.cdc":
+			return priceScript, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	importResolver := func(location common.Location) (string, error) {
+		var name string
+		switch location := location.(type) {
+		case common.AddressLocation:
+			name = location.Name
+		case common.StringLocation:
+			name = location.String()
+		}
+
+		switch name {
+		case "Oracle":
+			return oracleContract, nil
+		case "Market":
+			return marketContract, nil
+		}
+
+		return "", fmt.Errorf("cannot find import location: %s", location.ID())
+	}
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+        import Market from "Market"
+
+        access(all)
+        fun setup() {
+            var err = Test.deployContract(
+                name: "Oracle",
+                path: "../contracts/Oracle.cdc",
+                arguments: []
+            )
+            Test.expect(err, Test.beNil())
+
+            err = Test.deployContract(
+                name: "Market",
+                path: "../contracts/Market.cdc",
+                arguments: []
+            )
+            Test.expect(err, Test.beNil())
+        }
+
+        access(all)
+        fun testPrice() {
+            Test.assertEqual(42.0, Market.price("FLOW", amount: 2.0))
+
+            let result = executeScript("../scripts/price.cdc", [])
+            Test.expect(result, Test.beSucceeded())
+            Test.assertEqual(42.0, result.returnValue! as! UFix64)
+        }
+	`
+
+	oracleLocation := common.AddressLocation{
+		Address: common.Address{0, 0, 0, 0, 0, 0, 0, 7},
+		Name:    "Oracle",
+	}
+
+	contracts := map[string]common.Address{
+		"Oracle": oracleLocation.Address,
+		"Market": {0, 0, 0, 0, 0, 0, 0, 8},
+	}
+
+	newRunner := func() *TestRunner {
+		return NewTestRunner().
+			WithImportResolver(importResolver).
+			WithFileResolver(fileResolver).
+			WithContracts(contracts)
+	}
+
+	t.Run("by name", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := newRunner().
+			WithContractMock("Oracle", oracleMock).
+			RunTest(testCode, "testPrice")
+		require.NoError(t, err)
+		require.NoError(t, result.Error)
+	})
+
+	t.Run("by location", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := newRunner().
+			WithContractMockAt(oracleLocation, oracleMock).
+			RunTest(testCode, "testPrice")
+		require.NoError(t, err)
+		require.NoError(t, result.Error)
+	})
+
+	t.Run("not conforming", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newRunner().
+			WithContractMock("Oracle", invalidOracleMock).
+			RunTest(testCode, "testPrice")
+		require.ErrorContains(
+			t,
+			err,
+			"mock of contract 0000000000000007.Oracle does not conform to the contract:\n"+
+				"\t- missing function `Oracle.quote`\n"+
+				"\t- missing type `Oracle.Priced`\n"+
+				"\t- missing type `Oracle.PriceUpdated`\n"+
+				"\t- `Oracle.Quote` does not conform to `Oracle.Priced`\n"+
+				"\t- `Oracle.Quote.price` has type `UInt64`, expected `UFix64`",
+		)
+	})
+
+	t.Run("missing interface conformance", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newRunner().
+			WithContractMock("Oracle", nonConformingOracleMock).
+			RunTest(testCode, "testPrice")
+		require.ErrorContains(
+			t,
+			err,
+			"mock of contract 0000000000000007.Oracle does not conform to the contract:\n"+
+				"\t- `Oracle.Quote` does not conform to `Oracle.Priced`",
+		)
+	})
+
+	t.Run("not conforming, deployed without import", func(t *testing.T) {
+		t.Parallel()
+
+		const deployCode = `
+            import Test
+
+            access(all)
+            fun testDeploy() {
+                let err = Test.deployContract(
+                    name: "Oracle",
+                    path: "../contracts/Oracle.cdc",
+                    arguments: []
+                )
+                Test.expect(err, Test.beNil())
+            }
+		`
+
+		result, err := newRunner().
+			WithContractMock("Oracle", invalidOracleMock).
+			RunTest(deployCode, "testDeploy")
+		require.NoError(t, err)
+		require.ErrorContains(
+			t,
+			result.Error,
+			"mock of contract 0000000000000007.Oracle does not conform to the contract",
+		)
+	})
+
+	t.Run("not conforming to the updated contract", func(t *testing.T) {
+		t.Parallel()
+
+		const updatedOracleContract = `
+            access(all)
+            contract Oracle {
+
+                access(all)
+                event PriceUpdated(price: UFix64)
+
+                access(all)
+                struct Quote {
+                    access(all)
+                    let price: UFix64
+
+                    init(price: UFix64) {
+                        self.price = price
+                    }
+                }
+
+                access(self)
+                let feed: String
+
+                init(feed: String) {
+                    self.feed = feed
+                }
+
+                access(all)
+                fun quote(_ symbol: String): Quote {
+                    panic("cannot fetch a quote from the feed: ".concat(self.feed))
+                }
+
+                access(all)
+                fun symbols(): [String] {
+                    return []
+                }
+            }
+		`
+
+		const updateCode = `
+            import Test
+            import BlockchainHelpers
+
+            access(all)
+            fun testUpdate() {
+                let err = Test.deployContract(
+                    name: "Oracle",
+                    path: "../contracts/Oracle.cdc",
+                    arguments: []
+                )
+                Test.expect(err, Test.beNil())
+
+                // The mock is still deployed for the same contract
+                Test.expect(updateContract(name: "Oracle", path: "../contracts/Oracle.cdc"), Test.beNil())
+
+                let updateErr = updateContract(name: "Oracle", path: "../contracts/OracleV2.cdc")
+                panic(updateErr!.message)
+            }
+		`
+
+		result, err := newRunner().
+			WithFileResolver(func(path string) (string, error) {
+				if path == "../contracts/OracleV2.cdc" {
+					return updatedOracleContract, nil
+				}
+				return fileResolver(path)
+			}).
+			WithContractMock("Oracle", oracleMock).
+			RunTest(updateCode, "testUpdate")
+		require.NoError(t, err)
+		require.ErrorContains(t, result.Error, "- missing function `Oracle.symbols`")
+	})
+
+	t.Run("unknown contract", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := newRunner().
+			WithContractMock("Unknown", oracleMock).
+			RunTest(testCode, "testPrice")
+		require.ErrorContains(t, err, "could not find the address of contract: Unknown")
+	})
+}
+
+func TestDeterministicClock(t *testing.T) {
+	t.Parallel()
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all)
+        fun timestamp(): UFix64 {
+            let scriptResult = Test.executeScript(
+                "access(all) fun main(): UFix64 { return getCurrentBlock().timestamp }",
+                []
+            )
+            Test.expect(scriptResult, Test.beSucceeded())
+            return scriptResult.returnValue! as! UFix64
+        }
+
+        access(all)
+        fun testClock() {
+            // The blocks before the tests are produced at the start
+            Test.assertEqual(1704067200.0, timestamp())
+
+            Test.commitBlock()
+            Test.assertEqual(1704067200.0, timestamp())
+            let height = getCurrentBlockHeight()
+            let start = timestamp()
+
+            advanceBlocks(3)
+            Test.assertEqual(height + 3, getCurrentBlockHeight())
+            Test.assertEqual(start + 30.0, timestamp())
+
+            setTimestamp(2000000000.0)
+            Test.commitBlock()
+            Test.assertEqual(2000000000.0, timestamp())
+            Test.commitBlock()
+            Test.assertEqual(2000000010.0, timestamp())
+
+            setBlockTime(0.0)
+            advanceBlocks(2)
+            Test.assertEqual(2000000020.0, timestamp())
+
+            Test.moveTime(by: 100.0)
+            Test.commitBlock()
+            Test.assertEqual(2000000120.0, timestamp())
+        }
+	`
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	runner := NewTestRunner().
+		WithDeterministicClock(start, 10*time.Second)
+
+	result, err := runner.RunTest(testCode, "testClock")
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+
+	_, err = NewTestRunner().
+		WithDeterministicClock(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), time.Second).
+		RunTest(testCode, "testClock")
+	require.ErrorContains(
+		t,
+		err,
+		"timestamp 2000-01-01T00:00:00Z is before the timestamp of the genesis block",
+	)
+
+	_, err = NewTestRunner().
+		WithDeterministicClock(start, -time.Second).
+		RunTest(testCode, "testClock")
+	require.ErrorContains(t, err, "block interval must not be negative")
+
+	// The blocks of the predefined accounts already have wall clock timestamps
+	err = NewEmulatorBackend(zerolog.Nop(), nil, nil).UseDeterministicClock(start, time.Minute)
+	require.ErrorContains(t, err, "timestamp 2024-01-01T00:00:00Z is before the timestamp of the latest block")
+
+	backend := newEmulatorBackend(zerolog.Nop(), nil, nil, newDeterministicClock(start, time.Minute))
+
+	for i := 0; i < 3; i++ {
+		err = backend.CommitBlock()
+		require.NoError(t, err)
+
+		latestBlock, err := backend.blockchain.GetLatestBlock()
+		require.NoError(t, err)
+		assert.Equal(t, start.Add(time.Duration(i)*time.Minute), latestBlock.Header.Timestamp.UTC())
+	}
+
+	err = backend.SetTimestamp(start)
+	require.ErrorContains(
+		t,
+		err,
+		"timestamp 2024-01-01T00:00:00Z is before the timestamp of the latest block 2024-01-01T00:02:00Z",
+	)
+
+	err = backend.SetBlockInterval(-time.Second)
+	require.ErrorContains(t, err, "block interval must not be negative")
+}
+
+func TestRandomness(t *testing.T) {
+	t.Parallel()
+
+	t.Run("seeded", func(t *testing.T) {
+		t.Parallel()
+
+		const testCode = `
+            import Test
+
+            access(all)
+            fun testRandomness() {
+                let scriptResult = Test.executeScript(
+                    "access(all) fun main(): UInt64 { return revertibleRandom<UInt64>() }",
+                    []
+                )
+                Test.expect(scriptResult, Test.beSucceeded())
+                log(scriptResult.returnValue!)
+
+                let tx = Test.Transaction(
+                    code: "transaction { execute { log(revertibleRandom<UInt64>(modulo: 1000)) } }",
+                    authorizers: [],
+                    signers: [],
+                    arguments: []
+                )
+                Test.expect(Test.executeTransaction(tx), Test.beSucceeded())
+                Test.commitBlock()
+
+                let sourceResult = Test.executeScript(
+                    "import \"RandomBeaconHistory\" \n access(all) fun main(): [UInt8] { return RandomBeaconHistory.sourceOfRandomness(atBlockHeight: getCurrentBlock().height - 1).value }",
+                    []
+                )
+                Test.expect(sourceResult, Test.beSucceeded())
+                log(sourceResult.returnValue!)
+            }
+		`
+
+		runTest := func(seed int64) []string {
+			runner := NewTestRunner().WithRandomnessSeed(seed)
+
+			result, err := runner.RunTest(testCode, "testRandomness")
+			require.NoError(t, err)
+			require.NoError(t, result.Error)
+
+			return runner.Logs()
+		}
+
+		logs := runTest(42)
+		require.Len(t, logs, 3)
+		assert.Equal(t, logs, runTest(42))
+		assert.NotEqual(t, logs, runTest(43))
+	})
+
+	t.Run("seeded, history only available to the system transaction", func(t *testing.T) {
+		t.Parallel()
+
+		const testCode = `
+            import Test
+
+            access(all)
+            fun testRandomSourceHistory() {
+                // The system transaction of the committed block reads the source of randomness history
+                Test.commitBlock()
+
+                let tx = Test.Transaction(
+                    code: "transaction { execute { log(randomSourceHistory()) } }",
+                    authorizers: [],
+                    signers: [],
+                    arguments: []
+                )
+                Test.expect(Test.executeTransaction(tx), Test.beFailed())
+                Test.commitBlock()
+
+                let scriptResult = Test.executeScript(
+                    "access(all) fun main(): [UInt8] { return randomSourceHistory() }",
+                    []
+                )
+                Test.expect(scriptResult, Test.beFailed())
+            }
+		`
+
+		runner := NewTestRunner().WithRandomnessSeed(42)
+
+		result, err := runner.RunTest(testCode, "testRandomSourceHistory")
+		require.NoError(t, err)
+		require.NoError(t, result.Error)
+		assert.Empty(t, runner.Logs())
+	})
+
+	t.Run("injected values", func(t *testing.T) {
+		t.Parallel()
+
+		const testCode = `
+            import Test
+            import BlockchainHelpers
+
+            access(all)
+            fun testRandomness() {
+                setNextRandomValues([7, 300, 5])
+
+                let tx = Test.Transaction(
+                    code: "transaction { execute { assert(revertibleRandom<UInt64>() == 7); assert(revertibleRandom<UInt16>() == 300); assert(revertibleRandom<UInt8>(modulo: 10) == 5) } }",
+                    authorizers: [],
+                    signers: [],
+                    arguments: []
+                )
+                Test.expect(Test.executeTransaction(tx), Test.beSucceeded())
+
+                let nextTx = Test.Transaction(
+                    code: "transaction { execute { assert(revertibleRandom<UInt64>() != 7) } }",
+                    authorizers: [],
+                    signers: [],
+                    arguments: []
+                )
+                Test.expect(Test.executeTransaction(nextTx), Test.beSucceeded())
+            }
+		`
+
+		runner := NewTestRunner()
+
+		result, err := runner.RunTest(testCode, "testRandomness")
+		require.NoError(t, err)
+		require.NoError(t, result.Error)
+	})
+}
+
+func TestImportingHelperFile(t *testing.T) {
+	t.Parallel()
+
+	const helpersCode = `
+        import Test
+
+        access(all) fun createTransaction(
+            _ path: String,
+            account: Test.TestAccount,
+            args: [AnyStruct]
+        ): Test.Transaction {
+            return Test.Transaction(
+                code: Test.readFile(path),
+                authorizers: [account.address],
+                signers: [account],
+                arguments: args
+            )
+        }
+	`
+
+	const transactionCode = `
+        transaction() {
+            prepare(acct: &Account) {}
+
+            execute {
+                assert(true)
+            }
+        }
+	`
+
+	const testCode = `
+        import Test
+        import "test_helpers.cdc"
+
+        access(all)
+        let account = Test.createAccount()
+
+        access(all)
+        fun testRunTransaction() {
+            let tx = createTransaction(
+                "../transactions/add_special_number.cdc",
+                account: account,
+                args: []
+            )
+
+            let result = Test.executeTransaction(tx)
+            Test.expect(result, Test.beSucceeded())
+        }
+	`
+
+	fileResolver := func(path string) (string, error) {
+		switch path {
+		case "../transactions/add_special_number.cdc":
+			return transactionCode, nil
+		default:
+			return "", fmt.Errorf("cannot find file path: %s", path)
+		}
+	}
+
+	importResolver := func(location common.Location) (string, error) {
+		switch location := location.(type) {
+		case common.StringLocation:
+			if location == "test_helpers.cdc" {
+				return helpersCode, nil
+			}
+		}
+
+		return "", fmt.Errorf("unsupported import %s", location)
+	}
+
+	runner := NewTestRunner().
+		WithFileResolver(fileResolver).
+		WithImportResolver(importResolver)
+
+	results, err := runner.RunTests(testCode)
+	require.NoError(t, err)
+	for _, result := range results {
+		require.NoError(t, result.Error)
+	}
+}
+
+func TestBlockchainReset(t *testing.T) {
+	t.Parallel()
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all)
+        fun testBlockchainReset() {
+            // Arrange
+            let account = Test.createAccount()
+            var balance = getFlowBalance(account: account)
+            Test.assertEqual(0.0, balance)
+
+            let height = getCurrentBlockHeight()
+
+            mintFlow(to: account, amount: 1500.0)
+
+            balance = getFlowBalance(account: account)
+            Test.assertEqual(1500.0, balance)
+            Test.assertEqual(getCurrentBlockHeight(), height + 1)
+
+            // Act
+            Test.reset(to: height)
+
+            // Assert
+            balance = getFlowBalance(account: account)
+            Test.assertEqual(0.0, balance)
+            Test.assertEqual(getCurrentBlockHeight(), height)
+        }
+	`
+
+	runner := NewTestRunner()
+
+	result, err := runner.RunTest(testCode, "testBlockchainReset")
+	require.NoError(t, err)
+	require.NoError(t, result.Error)
+}
+
+func TestTestIsolation(t *testing.T) {
+	t.Parallel()
+
+	const testCode = `
+        import Test
+        import BlockchainHelpers
+
+        access(all) let account = Test.createAccount()
+
+        access(all) var counter = 0
+
+        access(all) var names: [String] = []
+
+        access(all) fun setup() {
+            mintFlow(to: account, amount: 100.0)
+            counter = 1
+            names.append("setup")
+        }
+
+        access(all) fun testFirst() {
+            Test.assertEqual(100.0, getFlowBalance(account: account))
+            Test.assertEqual(1, counter)
+            Test.assertEqual(["setup"], names)
+
+            mintFlow(to: account, amount: 50.0)
+            counter = counter + 1
+            names.append("first")
+
+            let newAccount = Test.createAccount()
+            Test.moveTime(by: 3600.0)
+        }
+
+        access(all) fun testSecond() {
+            Test.assertEqual(100.0, getFlowBalance(account: account))
+            Test.assertEqual(1, counter)
+            Test.assertEqual(["setup"], names)
+        }
+	`
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		runner := NewTestRunner().WithIsolation(true)
+		results, err := runner.RunTests(testCode)
+		require.NoError(t, err)
+
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.NoError(t, result.Error, result.TestName)
+		}
+	})
+
+	t.Run("enabled, concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		runner := NewTestRunner().
+			WithIsolation(true).
+			WithParallelism(2)
+		results, err := runner.RunTests(testCode)
+		require.NoError(t, err)
+
+		require.Len(t, results, 2)
+		assert.Equal(t, "testFirst", results[0].TestName)
+		assert.Equal(t, "testSecond", results[1].TestName)
+		for _, result := range results {
+			assert.NoError(t, result.Error, result.TestName)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		runner := NewTestRunner()
+		results, err := runner.RunTests(testCode)
+		require.NoError(t, err)
+
+		require.Len(t, results, 2)
+		assert.NoError(t, results[0].Error)
+		assert.Error(t, results[1].Error)
+	})
+}
+
+func TestRunScripts(t *testing.T) {
+	t.Parallel()
+
+	scripts := []TestScript{
+		{
+			Path: "a_test.cdc",
+			Code: `
+                import Test
+
+                access(all) fun testA() {
+                    Test.assert(true)
+                }
+            `,
+		},
+		{
+			Path: "b_test.cdc",
+			Code: `
+                import Test
+
+                access(all) fun testB1() {
+                    Test.assert(true)
+                }
+
+                access(all) fun testB2() {
+                    Test.assert(false)
+                }
+            `,
+		},
+		{
+			Path: "c_test.cdc",
+			Code: `
+                import Test
+
+                access(all) fun testC() {
+                    let x: Int = "invalid"
+                }
+            `,
+		},
+		{
+			Path: "d_test.cdc",
+			Code: `
+                import Test
+
+                access(all) fun testD() {
+                    Test.readFile("d.txt")
+                }
+            `,
+			FileResolver: func(path string) (string, error) {
+				return "", fmt.Errorf("cannot read %s", path)
+			},
+		},
+	}
+
+	runner := NewTestRunner().WithParallelism(3)
+	suites := runner.RunScripts(scripts)
+
+	require.Len(t, suites, 4)
+
+	assert.Equal(t, "a_test.cdc", suites[0].Name)
+	assert.NoError(t, suites[0].Error)
+	require.Len(t, suites[0].Results, 1)
+	assert.NoError(t, suites[0].Results[0].Error)
+
+	assert.Equal(t, "b_test.cdc", suites[1].Name)
+	assert.NoError(t, suites[1].Error)
+	require.Len(t, suites[1].Results, 2)
+	assert.Equal(t, "testB1", suites[1].Results[0].TestName)
+	assert.NoError(t, suites[1].Results[0].Error)
+	assert.Equal(t, "testB2", suites[1].Results[1].TestName)
+	assert.Error(t, suites[1].Results[1].Error)
+
+	assert.Equal(t, "c_test.cdc", suites[2].Name)
+	assert.Error(t, suites[2].Error)
+	assert.Empty(t, suites[2].Results)
+
+	assert.Equal(t, "d_test.cdc", suites[3].Name)
+	require.Len(t, suites[3].Results, 1)
+	require.Error(t, suites[3].Results[0].Error)
+	assert.ErrorContains(t, suites[3].Results[0].Error, "cannot read d.txt")
+}
+
+func TestTestFilters(t *testing.T) {
+	t.Parallel()
+
+	const code = `
+        import Test
+
+        access(all) fun testTransferA() {
+            Test.assert(true)
+        }
+
+        access(all) fun testTransferB() {
+            Test.assert(true)
+        }
+
+        access(all) fun testMint() {
+            Test.assert(true)
+        }
+    `
+
+	t.Run("regexp", func(t *testing.T) {
+		t.Parallel()
+
+		filter, err := NewRegexpTestFilter("^testTransfer[AB]$")
+		require.NoError(t, err)
+
+		runner := NewTestRunner().WithTestFilter(filter)
+
+		results, err := runner.RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, "testTransferA", results[0].TestName)
+		assert.Equal(t, "testTransferB", results[1].TestName)
+
+		tests, err := runner.GetTests(code)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"testTransferA", "testTransferB"}, tests)
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		t.Parallel()
+
+		filter, err := NewGlobTestFilter("*Mint")
+		require.NoError(t, err)
+
+		runner := NewTestRunner().WithTestFilter(filter)
+
+		results, err := runner.RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "testMint", results[0].TestName)
+	})
+
+	t.Run("invalid patterns", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewRegexpTestFilter("testTransfer(")
+		require.Error(t, err)
+
+		_, err = NewGlobTestFilter("testTransfer[")
+		require.Error(t, err)
+	})
+}
+
+func TestSkipAndOnlyMarkers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("skip doc-comment", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA() {
+                Test.assert(true)
+            }
+
+            /// Fails, but is skipped.
+            ///
+            /// @skip: not implemented yet
+            access(all) fun testB() {
+                Test.assert(false)
+            }
+        `
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, "testA", results[0].TestName)
+		assert.False(t, results[0].Skipped)
+		assert.NoError(t, results[0].Error)
+
+		assert.Equal(t, "testB", results[1].TestName)
+		assert.True(t, results[1].Skipped)
+		assert.Equal(t, "not implemented yet", results[1].SkipReason)
+		assert.NoError(t, results[1].Error)
+
+		output := PrettyPrintResults(results, "test_script.cdc")
+		assert.Contains(t, output, "- SKIP: testB (not implemented yet)\n")
+	})
+
+	t.Run("only doc-comment", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA() {
+                Test.assert(false)
+            }
+
+            /// @only
+            access(all) fun testB() {
+                Test.assert(true)
+            }
+        `
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.True(t, results[0].Skipped)
+		assert.NoError(t, results[0].Error)
+		assert.False(t, results[1].Skipped)
+	})
+
+	t.Run("pragmas", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            #skip("testA", "flaky")
+            #only("testA")
+            #only("testB")
+
+            import Test
+
+            access(all) fun testA() {
+                Test.assert(false)
+            }
+
+            access(all) fun testB() {
+                Test.assert(true)
+            }
+
+            access(all) fun testC() {
+                Test.assert(false)
+            }
+        `
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		assert.True(t, results[0].Skipped)
+		assert.Equal(t, "flaky", results[0].SkipReason)
+		assert.False(t, results[1].Skipped)
+		assert.NoError(t, results[1].Error)
+		assert.True(t, results[2].Skipped)
+		assert.Equal(t, "", results[2].SkipReason)
+	})
+
+	t.Run("invalid pragma", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            #skip()
+
+            import Test
+
+            access(all) fun testA() {}
+        `
+
+		_, err := NewTestRunner().RunTests(code)
+		require.ErrorContains(t, err, "invalid #skip pragma")
+	})
+
+	t.Run("isolated, concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) var counter = 0
+
+            access(all) fun testA() {
+                counter = counter + 1
+                Test.assertEqual(1, counter)
+            }
+
+            /// @skip
+            access(all) fun testB() {
+                Test.assert(false)
+            }
+
+            access(all) fun testC() {
+                counter = counter + 1
+                Test.assertEqual(1, counter)
+            }
+        `
+
+		for _, parallelism := range []int{1, 2} {
+			results, err := NewTestRunner().
+				WithIsolation(true).
+				WithParallelism(parallelism).
+				RunTests(code)
+			require.NoError(t, err)
+			require.Len(t, results, 3)
+
+			assert.Equal(t, "testA", results[0].TestName)
+			assert.NoError(t, results[0].Error)
+			assert.Equal(t, "testB", results[1].TestName)
+			assert.True(t, results[1].Skipped)
+			assert.Equal(t, "testC", results[2].TestName)
+			assert.NoError(t, results[2].Error)
+		}
+	})
+}
+
+func TestParameterizedTests(t *testing.T) {
+	t.Parallel()
+
+	const code = `
+        import Test
+
+        access(all) fun setup() {}
+
+        /// Transfers the given amount.
+        ///
+        /// pragma arguments (amount: 10.0, recipient: "alice")
+        /// pragma arguments (amount: 20.5, recipient: "bob")
+        access(all) fun testTransfer(amount: UFix64, recipient: String) {
+            Test.assert(amount < 20.0, message: recipient)
+        }
+
+        access(all) fun testDouble(value: Int, expected: Int) {
+            Test.assertEqual(expected, value * 2)
+        }
+
+        access(all) fun testDoubleArguments(): [[AnyStruct]] {
+            return [
+                [1, 2],
+                [3, 6],
+                [-4, -8]
+            ]
+        }
+    `
+
+	t.Run("run tests", func(t *testing.T) {
+		t.Parallel()
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+
+		assert.Equal(t, `testTransfer[amount=10.0, recipient="alice"]`, results[0].TestName)
+		assert.NoError(t, results[0].Error)
+
+		assert.Equal(t, `testTransfer[amount=20.5, recipient="bob"]`, results[1].TestName)
+		require.Error(t, results[1].Error)
+		assert.ErrorContains(t, results[1].Error, "bob")
+
+		assert.Equal(t, "testDouble[value=1, expected=2]", results[2].TestName)
+		assert.NoError(t, results[2].Error)
+		assert.Equal(t, "testDouble[value=3, expected=6]", results[3].TestName)
+		assert.NoError(t, results[3].Error)
+		assert.Equal(t, "testDouble[value=-4, expected=-8]", results[4].TestName)
+		assert.NoError(t, results[4].Error)
+	})
+
+	t.Run("run tests, isolated and concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		results, err := NewTestRunner().
+			WithIsolation(true).
+			WithParallelism(2).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 5)
+
+		assert.Equal(t, `testTransfer[amount=20.5, recipient="bob"]`, results[1].TestName)
+		require.Error(t, results[1].Error)
+		assert.Equal(t, "testDouble[value=-4, expected=-8]", results[4].TestName)
+		assert.NoError(t, results[4].Error)
+	})
+
+	t.Run("get tests", func(t *testing.T) {
+		t.Parallel()
+
+		tests, err := NewTestRunner().GetTests(code)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			[]string{
+				`testTransfer[amount=10.0, recipient="alice"]`,
+				`testTransfer[amount=20.5, recipient="bob"]`,
+				"testDouble[value=1, expected=2]",
+				"testDouble[value=3, expected=6]",
+				"testDouble[value=-4, expected=-8]",
+			},
+			tests,
+		)
+	})
+
+	t.Run("run single test case", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := NewTestRunner().RunTest(code, "testDouble[value=3, expected=6]")
+		require.NoError(t, err)
+		assert.Equal(t, "testDouble[value=3, expected=6]", result.TestName)
+		assert.NoError(t, result.Error)
+
+		_, err = NewTestRunner().RunTest(code, "testDouble")
+		require.ErrorContains(t, err, "unknown test case `testDouble`")
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA() {}
+
+            /// @skip
+            /// pragma arguments (value: 1)
+            /// pragma arguments (value: 2)
+            access(all) fun testB(value: Int) {
+                Test.assert(false)
+            }
+        `
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		assert.Equal(t, "testB[value=1]", results[1].TestName)
+		assert.True(t, results[1].Skipped)
+		assert.Equal(t, "testB[value=2]", results[2].TestName)
+		assert.True(t, results[2].Skipped)
+	})
+
+	t.Run("invalid pragma arguments", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA() {}
+
+            /// pragma arguments (value: "one")
+            access(all) fun testB(value: Int) {}
+        `
+
+		_, err := NewTestRunner().RunTests(code)
+		require.ErrorContains(t, err, "invalid pragma arguments of test function `testB`")
+	})
+
+	t.Run("invalid provided arguments", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA(value: Int) {}
+
+            access(all) fun testAArguments(): [[AnyStruct]] {
+                return [[1], ["two"]]
+            }
+        `
+
+		_, err := NewTestRunner().RunTests(code)
+		require.ErrorContains(
+			t,
+			err,
+			"argument list at index 1: expected argument of type `Int` for parameter `value`, got `String`",
+		)
+	})
+
+	t.Run("provided arguments after setup", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) var values: [Int] = []
+
+            access(all) fun setup() {
+                values = [1, 2]
+            }
+
+            access(all) fun testA(value: Int) {
+                Test.assert(value > 0)
+            }
+
+            access(all) fun testAArguments(): [[AnyStruct]] {
+                return [[values[0]], [values[1]]]
+            }
+
+            access(all) fun tearDown() {
+                log("tearDown")
+            }
+        `
+
+		expected := []string{"testA[value=1]", "testA[value=2]"}
+
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		for i, result := range results {
+			assert.Equal(t, expected[i], result.TestName)
+			assert.NoError(t, result.Error)
+		}
+
+		results, err = NewTestRunner().
+			WithIsolation(true).
+			WithParallelism(2).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		for i, result := range results {
+			assert.Equal(t, expected[i], result.TestName)
+			assert.NoError(t, result.Error)
+		}
+
+		runner := NewTestRunner()
+		tests, err := runner.GetTests(code)
+		require.NoError(t, err)
+		assert.Equal(t, expected, tests)
+		assert.Equal(t, []string{"tearDown"}, runner.Logs())
+
+		result, err := NewTestRunner().RunTest(code, "testA[value=2]")
+		require.NoError(t, err)
+		assert.NoError(t, result.Error)
+	})
+
+	t.Run("random arguments, isolated and concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		// The arguments are evaluated once, and passed to the test cases,
+		// and the tear down of the setup which they were evaluated after is run
+
+		const code = `
+            import Test
+
+            access(all) fun setup() {}
+
+            access(all) fun testA(value: UInt64) {}
+
+            access(all) fun testAArguments(): [[AnyStruct]] {
+                return [[revertibleRandom<UInt64>()], [revertibleRandom<UInt64>()]]
+            }
+
+            access(all) fun tearDown() {
+                log("tearDown")
+            }
+        `
+
+		runner := NewTestRunner().
+			WithIsolation(true).
+			WithParallelism(2)
+
+		results, err := runner.RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		for _, result := range results {
+			assert.NoError(t, result.Error)
+		}
+
+		assert.Equal(t, []string{"tearDown"}, runner.Logs())
+	})
+
+	t.Run("duplicate arguments", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun testA(value: Int) {}
+
+            access(all) fun testAArguments(): [[AnyStruct]] {
+                return [[1], [2], [1]]
+            }
+        `
+
+		_, err := NewTestRunner().RunTests(code)
+		require.ErrorContains(
+			t,
+			err,
+			"duplicate test case `testA[value=1]` of parameterized test function `testA`",
+		)
+
+		_, err = NewTestRunner().
+			WithIsolation(true).
+			WithParallelism(2).
+			RunTests(code)
+		require.ErrorContains(t, err, "duplicate test case `testA[value=1]`")
+	})
+}
+
+func TestFuzzTests(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passing", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) var count = 0
+
+            access(all) fun fuzzCommutative(a: Int8, b: Int8) {
+                // With isolation, each input is run against the state before the fuzz test
+                count = count + 1
+                Test.assertEqual(1, count)
+
+                Test.assertEqual(Int16(a) + Int16(b), Int16(b) + Int16(a))
+            }
+        `
+
+		results, err := NewTestRunner().
+			WithFuzzIterations(20).
+			WithIsolation(true).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		assert.Equal(t, "fuzzCommutative", results[0].TestName)
+		assert.NoError(t, results[0].Error)
+	})
+
+	t.Run("without isolation", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) var count = 0
+
+            access(all) fun fuzzCount(a: Int8) {
+                // Without isolation, the inputs are run one after another
+                count = count + 1
+            }
+
+            access(all) fun testCount() {
+                Test.assertEqual(20, count)
+            }
+        `
+
+		results, err := NewTestRunner().
+			WithFuzzIterations(20).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, "fuzzCount", results[0].TestName)
+		assert.NoError(t, results[0].Error)
+		assert.Equal(t, "testCount", results[1].TestName)
+		assert.NoError(t, results[1].Error)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzy(value: Int): Int {
+                return value
+            }
+
+            access(all) fun testFuzzy() {
+                Test.assertEqual(1, fuzzy(value: 1))
+            }
+        `
+
+		tests, err := NewTestRunner().GetTests(code)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"testFuzzy"}, tests)
+	})
+
+	t.Run("failing, shrunk", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzBound(value: UInt64, name: String) {
+                Test.assert(value < 1000)
+            }
+        `
+
+		results, err := NewTestRunner().
+			WithRandomSeed(42).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		assert.Equal(t, "fuzzBound", results[0].TestName)
+		require.Error(t, results[0].Error)
+
+		var fuzzErr *FuzzError
+		require.ErrorAs(t, results[0].Error, &fuzzErr)
+		assert.Equal(t, int64(42), fuzzErr.Seed)
+		assert.Equal(t, `value=1000, name=""`, fuzzErr.Input)
+
+		failure := NewFailure(results[0].Error)
+		assert.Equal(t, FailureKindAssertion, failure.Kind)
+		assert.Equal(t, `value=1000, name=""`, failure.FuzzInput)
+	})
+
+	t.Run("deterministic seed", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzBound(value: Int, values: [Int]) {
+                Test.assert(value < 1000)
+            }
+        `
+
+		runFuzz := func() *FuzzError {
+			results, err := NewTestRunner().
+				WithRandomSeed(7).
+				RunTests(code)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+
+			var fuzzErr *FuzzError
+			require.ErrorAs(t, results[0].Error, &fuzzErr)
+			return fuzzErr
+		}
+
+		first := runFuzz()
+		second := runFuzz()
+		assert.Equal(t, first.OriginalInput, second.OriginalInput)
+		assert.Equal(t, "value=1000, values=[]", first.Input)
+	})
+
+	t.Run("composite types", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) struct Transfer {
+                access(all) let amount: UFix64
+                access(all) let recipient: Address?
+                access(all) let tags: {String: Bool}
+
+                init(amount: UFix64, recipient: Address?, tags: {String: Bool}) {
+                    self.amount = amount
+                    self.recipient = recipient
+                    self.tags = tags
+                }
+            }
+
+            access(all) fun fuzzTransfers(transfers: [Transfer], fee: Fix64, symbol: Character) {
+                Test.assert(transfers.length < 3)
+            }
+        `
+
+		results, err := NewTestRunner().
+			WithRandomSeed(1).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		var fuzzErr *FuzzError
+		require.ErrorAs(t, results[0].Error, &fuzzErr)
+		assert.Contains(t, fuzzErr.Input, "transfers=[")
+		assert.Contains(t, fuzzErr.Input, "amount: 0.00000000, recipient: nil, tags: {}")
+		assert.Contains(t, fuzzErr.Input, `fee=0.00000000, symbol="a"`)
+	})
+
+	t.Run("corpus", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzRare(value: Int) {
+                Test.assert(value != 123456789)
+            }
+        `
+
+		corpusDir := t.TempDir()
+
+		// Without a failing input in the corpus, the rare value is not found
+		results, err := NewTestRunner().
+			WithFuzzCorpusDir(corpusDir).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.NoError(t, results[0].Error)
+
+		input, err := jsoncdc.Encode(cadence.NewArray([]cadence.Value{cadence.NewInt(123456789)}))
+		require.NoError(t, err)
+
+		err = os.MkdirAll(filepath.Join(corpusDir, "fuzzRare"), 0755)
+		require.NoError(t, err)
+		err = os.WriteFile(filepath.Join(corpusDir, "fuzzRare", "rare.json"), input, 0644)
+		require.NoError(t, err)
+
+		results, err = NewTestRunner().
+			WithFuzzCorpusDir(corpusDir).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		var fuzzErr *FuzzError
+		require.ErrorAs(t, results[0].Error, &fuzzErr)
+		assert.Equal(t, "value=123456789", fuzzErr.Input)
+	})
+
+	t.Run("failing input added to corpus", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzPositive(value: Int64) {
+                Test.assert(value >= 0)
+            }
+        `
+
+		corpusDir := t.TempDir()
+
+		results, err := NewTestRunner().
+			WithFuzzCorpusDir(corpusDir).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Error(t, results[0].Error)
+
+		entries, err := os.ReadDir(filepath.Join(corpusDir, "fuzzPositive"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		data, err := os.ReadFile(filepath.Join(corpusDir, "fuzzPositive", entries[0].Name()))
+		require.NoError(t, err)
+
+		value, err := jsoncdc.Decode(nil, data)
+		require.NoError(t, err)
+		assert.Equal(t, cadence.NewArray([]cadence.Value{cadence.Int64(-1)}), value)
+	})
+
+	t.Run("unsupported parameter type", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) fun fuzzTypes(value: Type) {}
+
+            access(all) fun testPassing() {}
+        `
+
+		// Only the fuzz test function fails
+		results, err := NewTestRunner().RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, "fuzzTypes", results[0].TestName)
+		require.ErrorContains(
+			t,
+			results[0].Error,
+			"cannot fuzz test function `fuzzTypes`: parameter `value`: unsupported type `Type`",
+		)
+		assert.Equal(t, "testPassing", results[1].TestName)
+		assert.NoError(t, results[1].Error)
+	})
+
+	t.Run("failing without isolation, shrunk against the state of the failure", func(t *testing.T) {
+		t.Parallel()
+
+		const code = `
+            import Test
+
+            access(all) var failed = false
+
+            access(all) fun fuzzState(value: UInt8) {
+                if value >= 100 {
+                    // Only fails until the state was changed
+                    let first = !failed
+                    failed = true
+                    Test.assert(!first)
+                }
+            }
+        `
+
+		results, err := NewTestRunner().
+			WithRandomSeed(42).
+			RunTests(code)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		var fuzzErr *FuzzError
+		require.ErrorAs(t, results[0].Error, &fuzzErr)
+		assert.Equal(t, "value=100", fuzzErr.Input)
+	})
+}
+
+func TestTestFunctionValidSignature(t *testing.T) {
+	t.Parallel()
+
+	t.Run("with parameter, without arguments", func(t *testing.T) {
+		t.Parallel()
+
+		const testCode = `
+            import Test
+
+            access(all) fun testValidSignature() {
+                Test.assertEqual(2, 5 - 3)
+            }
+
+            access(all) fun testInvalidSignature(prefix: String) {
+                Test.assertEqual(2, 5 - 3)
+            }
+		`
+
+		runner := NewTestRunner()
+
+		_, err := runner.RunTests(testCode)
+		require.Error(t, err)
+		assert.ErrorContains(
+			t,
+			err,
+			"parameterized test function `testInvalidSignature` should have arguments",
+		)
 	})
 
 	t.Run("with return value", func(t *testing.T) {
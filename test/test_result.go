@@ -0,0 +1,211 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package test
+
+import (
+	goErrors "errors"
+	"regexp"
+	"time"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/stdlib"
+)
+
+type Results []Result
+
+// Result is the result of running a single test function.
+type Result struct {
+	TestName string
+	Error    error
+
+	// Duration is the time taken to run the test function,
+	// including the `beforeEach` and `afterEach` functions.
+	Duration time.Duration
+
+	// Logs are the log messages emitted while running the test function,
+	// including the `beforeEach` and `afterEach` functions.
+	Logs []string
+
+	// Failure is the structured information about the error of
+	// the test function. It is nil if the test function passed.
+	Failure *Failure
+
+	// Skipped is true if the test function was not run.
+	Skipped bool
+
+	// SkipReason is the optional reason of the `skip` marker of the test function.
+	SkipReason string
+}
+
+func newResult(testName string, err error, duration time.Duration, logs []string) Result {
+	return Result{
+		TestName: testName,
+		Error:    err,
+		Duration: duration,
+		Logs:     logs,
+		Failure:  NewFailure(err),
+	}
+}
+
+func newSkippedResult(testName string, reason string) Result {
+	return Result{
+		TestName:   testName,
+		Skipped:    true,
+		SkipReason: reason,
+	}
+}
+
+// FailureKind is the kind of failure of a test.
+type FailureKind string
+
+const (
+	// FailureKindAssertion is a failed assertion, e.g. of `Test.assert`,
+	// `Test.assertEqual`, `Test.expect` or `Test.fail`.
+	FailureKindAssertion FailureKind = "assertion"
+
+	// FailureKindPanic is any other error of the test function,
+	// e.g. a call to `panic`, or a failed pre/post-condition.
+	FailureKindPanic FailureKind = "panic"
+
+	// FailureKindSetup is a failure of one of the `setup`, `tearDown`,
+	// `beforeEach` or `afterEach` functions.
+	FailureKindSetup FailureKind = "setup"
+
+	// FailureKindInternal is an internal error of the test runner or the runtime.
+	FailureKindInternal FailureKind = "internal"
+)
+
+// Failure is the structured information about the error of a test.
+type Failure struct {
+	Kind    FailureKind
+	Message string
+
+	// Location is the location of the error in the source code.
+	// It is nil if the location is not known.
+	Location *FailureLocation
+
+	// Expected and Actual are the values compared by `Test.assertEqual`.
+	// For `Test.expect`, only the actual value is known.
+	Expected string
+	Actual   string
+
+	// FuzzInput is the shrunk input of a failed fuzz test function.
+	FuzzInput string
+}
+
+// FailureLocation is the position of an error in a program.
+// The line is 1-based and the column is 0-based,
+// like in the error messages of Cadence.
+type FailureLocation struct {
+	Location common.Location
+	Line     int
+	Column   int
+}
+
+var assertEqualMessage = regexp.MustCompile(`^not equal(?: types)?: expected: (.*), actual: (.*)$`)
+
+var expectMessage = regexp.MustCompile(`^given value is: (.*)$`)
+
+// NewFailure returns the structured information about the given error of a test.
+// Returns nil if there is no error.
+func NewFailure(err error) *Failure {
+	if err == nil {
+		return nil
+	}
+
+	failure := &Failure{
+		Kind:     failureKind(err),
+		Message:  err.Error(),
+		Location: failureLocation(err),
+	}
+
+	// The message of interpreter errors contains the pretty-printed
+	// source code of the error, so only keep the underlying message.
+	var interErr interpreter.Error
+	if goErrors.As(err, &interErr) {
+		failure.Message = interErr.Err.Error()
+	}
+
+	var fuzzErr *FuzzError
+	if goErrors.As(err, &fuzzErr) {
+		failure.FuzzInput = fuzzErr.Input
+	}
+
+	var assertionErr stdlib.AssertionError
+	if goErrors.As(err, &assertionErr) {
+		if match := assertEqualMessage.FindStringSubmatch(assertionErr.Message); match != nil {
+			failure.Expected = match[1]
+			failure.Actual = match[2]
+		} else if match := expectMessage.FindStringSubmatch(assertionErr.Message); match != nil {
+			failure.Actual = match[1]
+		}
+	}
+
+	return failure
+}
+
+func failureKind(err error) FailureKind {
+	var setupErr *SetupError
+	if goErrors.As(err, &setupErr) {
+		return FailureKindSetup
+	}
+
+	var assertionErr stdlib.AssertionError
+	if goErrors.As(err, &assertionErr) {
+		return FailureKindAssertion
+	}
+
+	if errors.IsInternalError(err) {
+		return FailureKindInternal
+	}
+
+	// Errors of the test script are always reported as interpreter errors.
+	// All other errors were recovered from panics in the test runner.
+	var interErr interpreter.Error
+	if goErrors.As(err, &interErr) {
+		return FailureKindPanic
+	}
+
+	return FailureKindInternal
+}
+
+func failureLocation(err error) *FailureLocation {
+	var interErr interpreter.Error
+	if !goErrors.As(err, &interErr) {
+		return nil
+	}
+
+	location := &FailureLocation{
+		Location: interErr.Location,
+	}
+
+	var positioned ast.HasPosition
+	if !goErrors.As(interErr.Err, &positioned) {
+		return location
+	}
+
+	position := positioned.StartPosition()
+	location.Line = position.Line
+	location.Column = position.Column
+
+	return location
+}
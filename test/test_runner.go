@@ -19,14 +19,19 @@
 package test
 
 import (
+	"encoding/json"
+	goErrors "errors"
 	"fmt"
 	"math/rand"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/onflow/atree"
+	"github.com/onflow/flow-emulator/emulator"
 	"github.com/onflow/flow-go/model/flow"
 
 	"github.com/onflow/cadence/runtime"
@@ -52,6 +57,13 @@ import (
 
 const testFunctionPrefix = "test"
 
+// isTestFunction returns true if the function with the given name
+// is a test function, or a fuzz test function.
+func isTestFunction(funcName string) bool {
+	return strings.HasPrefix(funcName, testFunctionPrefix) ||
+		isFuzzFunction(funcName)
+}
+
 const setupFunctionName = "setup"
 
 const tearDownFunctionName = "tearDown"
@@ -66,13 +78,6 @@ var quotedLog = regexp.MustCompile("\"(.*)\"")
 
 var StorageIDUndefined = atree.StorageID{}
 
-type Results []Result
-
-type Result struct {
-	TestName string
-	Error    error
-}
-
 // logCollectionHook can be attached to zerolog.Logger objects, in order
 // to aggregate the log messages in a string slice, containing only the
 // string message.
@@ -110,6 +115,40 @@ func (h *logCollectionHook) Run(e *zerolog.Event, level zerolog.Level, msg strin
 	}
 }
 
+// loggerWriter is a zerolog.LevelWriter which writes the events of a logger
+// to another logger, e.g. to the logger given to the test runner.
+// Hooks are not attached to the given logger itself, as the loggers derived from it
+// share the backing array of their hooks, so blockchains created concurrently,
+// e.g. when running test scripts concurrently, would overwrite each other's hooks.
+type loggerWriter struct {
+	logger zerolog.Logger
+}
+
+var _ zerolog.LevelWriter = loggerWriter{}
+
+func (w loggerWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w loggerWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	var fields map[string]any
+	err := json.Unmarshal(p, &fields)
+	if err != nil {
+		return 0, err
+	}
+
+	message, _ := fields[zerolog.MessageFieldName].(string)
+	delete(fields, zerolog.MessageFieldName)
+	delete(fields, zerolog.LevelFieldName)
+	delete(fields, zerolog.TimestampFieldName)
+
+	// The timestamp is added to the event, as adding it to the logger,
+	// e.g. using Context.Timestamp, adds a hook to the hooks shared with the given logger.
+	w.logger.WithLevel(level).Timestamp().Fields(fields).Msg(message)
+
+	return len(p), nil
+}
+
 // ImportResolver is used to resolve and get the source code for imports.
 // Must be provided by the user of the TestRunner.
 type ImportResolver func(location common.Location) (string, error)
@@ -139,8 +178,68 @@ type TestRunner struct {
 	// randomSeed is used for randomized test case execution.
 	randomSeed int64
 
+	// isolation is used to run each test function against the state
+	// of the blockchain and of the global variables right after `setup()`.
+	isolation bool
+
+	// parallelism is the maximum number of test scripts,
+	// or isolated test functions, that are run concurrently.
+	parallelism int
+
+	// fuzzIterations is the number of generated inputs
+	// each fuzz test function is run with.
+	fuzzIterations int
+
+	// fuzzCorpusDir is the directory of the inputs
+	// that are replayed before generating inputs for fuzz test functions.
+	fuzzCorpusDir string
+
+	// testFilter selects the test functions to run.
+	// All test functions are run if it is nil.
+	testFilter TestFilter
+
+	// storageLimitEnabled is used to fail transactions which exceed
+	// the storage capacity of the accounts, as on a real network.
+	storageLimitEnabled bool
+
+	// transactionFeesEnabled is used to charge the payers of transactions
+	// with the transaction fees, as on a real network.
+	transactionFeesEnabled bool
+
+	// contractRemovalEnabled is used to allow removing deployed contracts,
+	// which is not allowed on a real network.
+	contractRemovalEnabled bool
+
+	// state is the state the blockchain is seeded with, if any.
+	state *StateExport
+
+	// deterministicClock is used to make the blockchain's clock
+	// independent of the wall clock, starting at clockStart,
+	// and advancing by blockInterval for each block.
+	deterministicClock bool
+	clockStart         time.Time
+	blockInterval      time.Duration
+
+	// randomnessSeeded is used to generate the blockchain's randomness
+	// from randomnessSeed, instead of the hashes of the blocks.
+	// Unlike randomSeed, it does not affect the order of the tests.
+	randomnessSeeded bool
+	randomnessSeed   int64
+
+	// contractMocks is a mapping of contract identifiers to the code
+	// of the mocks which substitute them.
+	contractMocks map[string]string
+
+	// contractLocationMocks is a mapping of contract locations to the code
+	// of the mocks which substitute them.
+	contractLocationMocks map[common.AddressLocation]string
+
 	contracts map[string]common.Address
 
+	// contractInterpreters are the interpreters of the contracts
+	// imported by the test script, by location.
+	contractInterpreters map[common.AddressLocation]*interpreter.Interpreter
+
 	testFramework stdlib.TestFramework
 
 	backend *EmulatorBackend
@@ -178,6 +277,48 @@ func (r *TestRunner) WithRandomSeed(seed int64) *TestRunner {
 	return r
 }
 
+// WithIsolation enables or disables the isolation of test functions.
+// If enabled, the blockchain state and the global variables of the test script
+// are snapshotted after `setup()`, and restored before each test function,
+// so that test functions cannot affect each other.
+func (r *TestRunner) WithIsolation(isolation bool) *TestRunner {
+	r.isolation = isolation
+	return r
+}
+
+// WithParallelism sets the maximum number of test scripts that are run concurrently
+// by RunScripts. If isolation is enabled, it is also the maximum number of test functions
+// of a test script that are run concurrently, each with its own blockchain.
+// Tests are run sequentially if a coverage report is collected,
+// as the coverage report does not support concurrent updates.
+func (r *TestRunner) WithParallelism(parallelism int) *TestRunner {
+	r.parallelism = parallelism
+	return r
+}
+
+// WithFuzzIterations sets the number of randomly generated inputs each fuzz test function is run with.
+// The inputs are generated using the random seed, if set.
+func (r *TestRunner) WithFuzzIterations(iterations int) *TestRunner {
+	r.fuzzIterations = iterations
+	return r
+}
+
+// WithFuzzCorpusDir sets the corpus directory of the fuzz test functions.
+// The inputs in the corpus are run before the randomly generated inputs,
+// and failing inputs are added to the corpus, so they are replayed by future runs.
+func (r *TestRunner) WithFuzzCorpusDir(dir string) *TestRunner {
+	r.fuzzCorpusDir = dir
+	return r
+}
+
+// WithTestFilter sets the filter that selects the test functions
+// to be run by RunTests, and returned by GetTests.
+// Test functions that are not selected are omitted from the results.
+func (r *TestRunner) WithTestFilter(testFilter TestFilter) *TestRunner {
+	r.testFilter = testFilter
+	return r
+}
+
 func (r *TestRunner) WithContracts(contracts map[string]common.Address) *TestRunner {
 	for contract, address := range contracts {
 		// We do not want to override the base configuration,
@@ -195,27 +336,57 @@ func (r *TestRunner) RunTest(script string, funcName string) (result *Result, er
 		})
 	}()
 
-	_, inter, err := r.parseCheckAndInterpret(script)
+	return r.runScriptTestCase(
+		script,
+		func(program *interpreter.Program, inter *interpreter.Interpreter) (testCase, error) {
+			return r.findTestCase(program, inter, funcName)
+		},
+	)
+}
+
+// runExportedTestCase runs the given test case of the given script,
+// which was evaluated by another interpreter.
+func (r *TestRunner) runExportedTestCase(script string, exported exportedTestCase) (result *Result, err error) {
+	defer func() {
+		recoverPanics(func(internalErr error) {
+			err = internalErr
+		})
+	}()
+
+	return r.runScriptTestCase(
+		script,
+		func(program *interpreter.Program, inter *interpreter.Interpreter) (testCase, error) {
+			return r.importTestCase(program, inter, exported)
+		},
+	)
+}
+
+// runScriptTestCase runs the test case returned by the given function
+// with a new blockchain and interpreter for the given script,
+// including `setup()` and `tearDown()`.
+func (r *TestRunner) runScriptTestCase(
+	script string,
+	getTestCase func(*interpreter.Program, *interpreter.Interpreter) (testCase, error),
+) (*Result, error) {
+	program, inter, err := r.parseCheckAndInterpret(script)
 	if err != nil {
 		return nil, err
 	}
 
-	// Run test `setup()` before running the test function.
+	// Run test `setup()` before running the test function,
+	// and before evaluating the arguments of parameterized test functions,
+	// which may depend on the state after setup.
 	err = r.runTestSetup(inter)
 	if err != nil {
 		return nil, err
 	}
 
-	// Run `beforeEach()` before running the test function.
-	err = r.runBeforeEach(inter)
+	testCase, err := getTestCase(program, inter)
 	if err != nil {
 		return nil, err
 	}
 
-	_, testResult := inter.Invoke(funcName)
-
-	// Run `afterEach()` after running the test function.
-	err = r.runAfterEach(inter)
+	testResult, err := r.runTestCase(inter, testCase)
 	if err != nil {
 		return nil, err
 	}
@@ -223,10 +394,7 @@ func (r *TestRunner) RunTest(script string, funcName string) (result *Result, er
 	// Run test `tearDown()` once running all test functions are completed.
 	err = r.runTestTearDown(inter)
 
-	return &Result{
-		TestName: funcName,
-		Error:    testResult,
-	}, err
+	return testResult, err
 }
 
 // RunTests runs all the tests in the provided test script.
@@ -244,49 +412,90 @@ func (r *TestRunner) RunTests(script string) (results Results, err error) {
 
 	results = make(Results, 0)
 
-	// Run test `setup()` before test functions
-	err = r.runTestSetup(inter)
+	testFunctions := r.testFunctions(program.Program)
+
+	markers, err := newTestMarkers(program.Program)
 	if err != nil {
 		return nil, err
 	}
 
-	testCases := make([]*ast.FunctionDeclaration, 0)
-
-	for _, funcDecl := range program.Program.FunctionDeclarations() {
-		funcName := funcDecl.Identifier.Identifier
-
-		if strings.HasPrefix(funcName, testFunctionPrefix) {
-			testCases = append(testCases, funcDecl)
-		}
-	}
 	if r.randomSeed > 0 {
 		rng := rand.New(rand.NewSource(r.randomSeed))
-		rng.Shuffle(len(testCases), func(i, j int) {
-			testCases[i], testCases[j] = testCases[j], testCases[i]
+		rng.Shuffle(len(testFunctions), func(i, j int) {
+			testFunctions[i], testFunctions[j] = testFunctions[j], testFunctions[i]
 		})
 	}
 
-	for _, funcDecl := range testCases {
-		funcName := funcDecl.Identifier.Identifier
+	// Run test `setup()` before test functions,
+	// and before evaluating the arguments of parameterized test functions,
+	// which may depend on the state after setup.
+	err = r.runTestSetup(inter)
+	if err != nil {
+		return nil, err
+	}
 
-		// Run `beforeEach()` before running the test function.
-		err = r.runBeforeEach(inter)
+	testCases := make([]testCase, 0, len(testFunctions))
+	for _, funcDecl := range testFunctions {
+		functionTestCases, err := r.testCases(program, inter, funcDecl)
 		if err != nil {
 			return nil, err
 		}
+		testCases = append(testCases, functionTestCases...)
+	}
 
-		testErr := r.invokeTestFunction(inter, funcName)
+	// When running concurrently, each test case runs its own `setup()` and `tearDown()`,
+	// so the state after the `setup()` above is only used for the arguments,
+	// which are passed to the test cases instead of being evaluated again.
+	if r.isolation && r.effectiveParallelism() > 1 {
+		exportedTestCases := make([]exportedTestCase, 0, len(testCases))
+		for _, testCase := range testCases {
+			exported, err := exportTestCase(inter, testCase)
+			if err != nil {
+				return nil, err
+			}
+			exportedTestCases = append(exportedTestCases, exported)
+		}
 
-		// Run `afterEach()` after running the test function.
-		err = r.runAfterEach(inter)
+		err = r.runTestTearDown(inter)
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, Result{
-			TestName: funcName,
-			Error:    testErr,
-		})
+		return r.runTestsConcurrently(script, exportedTestCases, markers)
+	}
+
+	var snapshot *testSnapshot
+	if r.isolation {
+		snapshot, err = r.createSnapshot(program, inter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hasRun := false
+
+	for _, testCase := range testCases {
+		if reason, skipped := markers.skipped(testCase.funcName); skipped {
+			results = append(results, newSkippedResult(testCase.name, reason))
+			continue
+		}
+
+		// The first test function runs right after the snapshot,
+		// so there is nothing to restore.
+		if snapshot != nil && hasRun {
+			err = r.restoreSnapshot(snapshot, inter)
+			if err != nil {
+				return nil, err
+			}
+		}
+		hasRun = true
+
+		testResult, err := r.runTestCase(inter, testCase)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, *testResult)
 	}
 
 	// Run test `tearDown()` once running all test functions are completed.
@@ -295,25 +504,442 @@ func (r *TestRunner) RunTests(script string) (results Results, err error) {
 	return results, err
 }
 
+// testSnapshot is the state of the blockchain and
+// of the global variables of the test script.
+type testSnapshot struct {
+	backend *backendSnapshot
+	globals map[string]interpreter.Value
+}
+
+func (r *TestRunner) createSnapshot(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+) (*testSnapshot, error) {
+	backend, err := r.backend.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	globals := map[string]interpreter.Value{}
+	for _, variableDecl := range program.Program.VariableDeclarations() {
+		name := variableDecl.Identifier.Identifier
+		variable := inter.Globals.Get(name)
+		if variable == nil {
+			continue
+		}
+		globals[name] = variable.GetValue().Clone(inter)
+	}
+
+	return &testSnapshot{
+		backend: backend,
+		globals: globals,
+	}, nil
+}
+
+func (r *TestRunner) restoreSnapshot(snapshot *testSnapshot, inter *interpreter.Interpreter) error {
+	err := r.backend.restore(snapshot.backend)
+	if err != nil {
+		return err
+	}
+
+	// Restore a copy of the global variables,
+	// so the snapshot can be restored again.
+	for name, value := range snapshot.globals {
+		inter.Globals.Get(name).SetValue(value.Clone(inter))
+	}
+
+	return nil
+}
+
+// runTestCase runs a single test function, together with the
+// `beforeEach()` and `afterEach()` functions.
+// Only failures of `beforeEach()` and `afterEach()` are returned as an error,
+// failures of the test function are part of the result.
+func (r *TestRunner) runTestCase(inter *interpreter.Interpreter, testCase testCase) (*Result, error) {
+	logsStart := len(r.Logs())
+	start := time.Now()
+
+	var testErr, err error
+	if testCase.fuzz {
+		testErr, err = r.runFuzzTest(inter, testCase.funcName)
+	} else {
+		testErr, err = r.invokeTestCase(inter, testCase.funcName, testCase.arguments)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Since(start)
+
+	logs := make([]string, len(r.Logs())-logsStart)
+	copy(logs, r.Logs()[logsStart:])
+
+	result := newResult(testCase.name, testErr, duration, logs)
+	return &result, nil
+}
+
+// invokeTestCase invokes the test function with the given arguments,
+// together with the `beforeEach()` and `afterEach()` functions.
+func (r *TestRunner) invokeTestCase(
+	inter *interpreter.Interpreter,
+	funcName string,
+	arguments []interpreter.Value,
+) (testErr error, err error) {
+	// Run `beforeEach()` before running the test function.
+	err = r.runBeforeEach(inter)
+	if err != nil {
+		return nil, err
+	}
+
+	testErr = r.invokeTestFunction(inter, funcName, arguments...)
+
+	// Run `afterEach()` after running the test function.
+	err = r.runAfterEach(inter)
+	if err != nil {
+		return nil, err
+	}
+
+	return testErr, nil
+}
+
+// TestScript is a test script run by RunScripts.
+type TestScript struct {
+	// Path is the path of the test script, used as the name of the suite.
+	Path string
+	Code string
+
+	// ImportResolver and FileResolver optionally override
+	// the resolvers of the test runner for this test script,
+	// e.g. to resolve paths relative to the test script.
+	ImportResolver ImportResolver
+	FileResolver   FileResolver
+}
+
+// RunScripts runs all the tests of the given test scripts, concurrently,
+// up to the configured parallelism. Each test script is run with its own blockchain.
+// The results are in the same order as the given test scripts.
+func (r *TestRunner) RunScripts(scripts []TestScript) []SuiteResult {
+	suites := make([]SuiteResult, len(scripts))
+
+	r.runConcurrently(len(scripts), func(i int) {
+		script := scripts[i]
+
+		runner := r.clone()
+		if script.ImportResolver != nil {
+			runner.importResolver = script.ImportResolver
+		}
+		if script.FileResolver != nil {
+			runner.fileResolver = script.FileResolver
+		}
+
+		results, err := runner.RunTests(script.Code)
+		suites[i] = NewSuiteResult(script.Path, results, err)
+	})
+
+	return suites
+}
+
+// runTestsConcurrently runs each of the given test cases of the script
+// with its own blockchain and interpreter, including `setup()` and `tearDown()`.
+// The test cases are run with the given arguments, which are not evaluated again.
+// The results are in the same order as the given test cases.
+func (r *TestRunner) runTestsConcurrently(
+	script string,
+	testCases []exportedTestCase,
+	markers *testMarkers,
+) (Results, error) {
+	testResults := make([]*Result, len(testCases))
+	testErrs := make([]error, len(testCases))
+
+	var runIndices []int
+	for i, testCase := range testCases {
+		if reason, skipped := markers.skipped(testCase.funcName); skipped {
+			skippedResult := newSkippedResult(testCase.name, reason)
+			testResults[i] = &skippedResult
+			continue
+		}
+		runIndices = append(runIndices, i)
+	}
+
+	r.runConcurrently(len(runIndices), func(j int) {
+		i := runIndices[j]
+		testResults[i], testErrs[i] = r.clone().runExportedTestCase(script, testCases[i])
+	})
+
+	results := make(Results, 0, len(testCases))
+	var firstErr error
+
+	for i, testResult := range testResults {
+		err := testErrs[i]
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+
+		// `setup()` or `beforeEach()`/`afterEach()` failed
+		if testResult == nil {
+			return nil, err
+		}
+
+		results = append(results, *testResult)
+	}
+
+	// `tearDown()` failed
+	return results, firstErr
+}
+
+// runConcurrently calls the given function for each index up to the given count,
+// with at most the effective parallelism of concurrent calls,
+// and waits until all calls completed.
+func (r *TestRunner) runConcurrently(count int, f func(i int)) {
+	semaphore := make(chan struct{}, r.effectiveParallelism())
+	var wg sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			f(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func (r *TestRunner) effectiveParallelism() int {
+	if r.parallelism < 1 || r.coverageReport != nil {
+		return 1
+	}
+	return r.parallelism
+}
+
+// WithStorageLimit enables or disables the storage limit of the blockchain.
+// If enabled, transactions fail if an account stores more data than
+// its storage capacity, which depends on its Flow token balance.
+func (r *TestRunner) WithStorageLimit(enabled bool) *TestRunner {
+	r.storageLimitEnabled = enabled
+	return r
+}
+
+// WithTransactionFees enables or disables the transaction fees of the blockchain.
+// If enabled, the payer of each transaction is charged with the transaction fees.
+func (r *TestRunner) WithTransactionFees(enabled bool) *TestRunner {
+	r.transactionFeesEnabled = enabled
+	return r
+}
+
+// WithContractRemoval enables or disables the removal of deployed contracts.
+// If disabled, which is the default as on a real network, removing a contract fails.
+func (r *TestRunner) WithContractRemoval(enabled bool) *TestRunner {
+	r.contractRemovalEnabled = enabled
+	return r
+}
+
+// WithState seeds the blockchain with the given state, e.g. exported
+// from a network, so tests can run against a fork of it offline.
+func (r *TestRunner) WithState(state *StateExport) *TestRunner {
+	r.state = state
+	return r
+}
+
+// WithDeterministicClock makes the blockchain's clock independent of the wall clock,
+// so that time-dependent tests are reproducible: The first block of each test script
+// has the given timestamp, and each following block is produced the given
+// block interval later, unless the time is moved. A block interval of zero freezes the time.
+// The start must not be before the timestamp of the genesis block, and the block interval
+// must not be negative, otherwise running the tests fails.
+func (r *TestRunner) WithDeterministicClock(start time.Time, blockInterval time.Duration) *TestRunner {
+	r.deterministicClock = true
+	r.clockStart = start
+	r.blockInterval = blockInterval
+	return r
+}
+
+// WithRandomnessSeed makes the blockchain's randomness deterministic,
+// e.g. of `revertibleRandom` and of the `RandomBeaconHistory` contract:
+// Random values are generated from the given seed, so that tests of randomized
+// logic are reproducible. Unlike WithRandomSeed, it does not affect the order of the tests.
+func (r *TestRunner) WithRandomnessSeed(seed int64) *TestRunner {
+	r.randomnessSeeded = true
+	r.randomnessSeed = seed
+	return r
+}
+
+// WithContractMock substitutes the contract with the given name,
+// which must be mapped to an address using WithContracts,
+// with a mock implementation with the given code.
+// The mock must conform to the public interface of the contract,
+// and it is deployed instead of the contract, e.g. by `Test.deployContract`.
+func (r *TestRunner) WithContractMock(name string, code string) *TestRunner {
+	if r.contractMocks == nil {
+		r.contractMocks = map[string]string{}
+	}
+	r.contractMocks[name] = code
+	return r
+}
+
+// WithContractMockAt substitutes the contract with the given location
+// with a mock implementation with the given code, like WithContractMock.
+func (r *TestRunner) WithContractMockAt(location common.AddressLocation, code string) *TestRunner {
+	if r.contractLocationMocks == nil {
+		r.contractLocationMocks = map[common.AddressLocation]string{}
+	}
+	r.contractLocationMocks[location] = code
+	return r
+}
+
+// clone returns a new test runner with the same configuration.
+func (r *TestRunner) clone() *TestRunner {
+	contracts := make(map[string]common.Address, len(r.contracts))
+	for contract, address := range r.contracts {
+		contracts[contract] = address
+	}
+
+	var contractMocks map[string]string
+	if r.contractMocks != nil {
+		contractMocks = make(map[string]string, len(r.contractMocks))
+		for name, code := range r.contractMocks {
+			contractMocks[name] = code
+		}
+	}
+
+	var contractLocationMocks map[common.AddressLocation]string
+	if r.contractLocationMocks != nil {
+		contractLocationMocks = make(map[common.AddressLocation]string, len(r.contractLocationMocks))
+		for location, code := range r.contractLocationMocks {
+			contractLocationMocks[location] = code
+		}
+	}
+
+	return &TestRunner{
+		logger:         r.logger,
+		importResolver: r.importResolver,
+		fileResolver:   r.fileResolver,
+		coverageReport: r.coverageReport,
+		randomSeed:     r.randomSeed,
+		isolation:      r.isolation,
+		parallelism:    r.parallelism,
+		fuzzIterations: r.fuzzIterations,
+		fuzzCorpusDir:  r.fuzzCorpusDir,
+		testFilter:     r.testFilter,
+		contracts:      contracts,
+
+		storageLimitEnabled:    r.storageLimitEnabled,
+		transactionFeesEnabled: r.transactionFeesEnabled,
+		contractRemovalEnabled: r.contractRemovalEnabled,
+		state:                  r.state,
+		deterministicClock:     r.deterministicClock,
+		clockStart:             r.clockStart,
+		blockInterval:          r.blockInterval,
+		randomnessSeeded:       r.randomnessSeeded,
+		randomnessSeed:         r.randomnessSeed,
+		contractMocks:          contractMocks,
+		contractLocationMocks:  contractLocationMocks,
+	}
+}
+
 func (r *TestRunner) GetTests(script string) ([]string, error) {
-	program, _, err := r.parseCheckAndInterpret(script)
+	program, inter, err := r.parseCheckAndInterpret(script)
+	if err != nil {
+		return nil, err
+	}
+
+	// The arguments of parameterized test functions are evaluated after `setup()`,
+	// like when running the tests, so `tearDown()` is run afterwards.
+	err = r.runTestSetup(inter)
 	if err != nil {
 		return nil, err
 	}
 
 	tests := make([]string, 0)
 
-	for _, funcDecl := range program.Program.FunctionDeclarations() {
-		funcName := funcDecl.Identifier.Identifier
+	for _, funcDecl := range r.testFunctions(program.Program) {
+		testCases, err := r.testCases(program, inter, funcDecl)
+		if err != nil {
+			return nil, err
+		}
 
-		if strings.HasPrefix(funcName, testFunctionPrefix) {
-			tests = append(tests, funcName)
+		for _, testCase := range testCases {
+			tests = append(tests, testCase.name)
 		}
 	}
 
+	err = r.runTestTearDown(inter)
+	if err != nil {
+		return nil, err
+	}
+
 	return tests, nil
 }
 
+// testFunctions returns the test functions of the given program,
+// which are selected by the test filter.
+func (r *TestRunner) testFunctions(program *ast.Program) []*ast.FunctionDeclaration {
+	testFunctions := make([]*ast.FunctionDeclaration, 0)
+
+	for _, funcDecl := range program.FunctionDeclarations() {
+		funcName := funcDecl.Identifier.Identifier
+
+		if !isTestFunction(funcName) ||
+			isArgumentsProvider(program, funcDecl) {
+			continue
+		}
+
+		if r.testFilter != nil && !r.testFilter(funcName) {
+			continue
+		}
+
+		testFunctions = append(testFunctions, funcDecl)
+	}
+
+	return testFunctions
+}
+
+// findTestCase returns the test case with the given name,
+// which is either the name of a test function without parameters,
+// or the name of a test case of a parameterized test function, e.g. `testTransfer[amount=10.0]`.
+func (r *TestRunner) findTestCase(
+	program *interpreter.Program,
+	inter *interpreter.Interpreter,
+	name string,
+) (testCase, error) {
+	funcName := testCaseFunctionName(name)
+
+	for _, funcDecl := range program.Program.FunctionDeclarations() {
+		if funcDecl.Identifier.Identifier != funcName || !isParameterized(funcDecl) {
+			continue
+		}
+
+		testCases, err := r.testCases(program, inter, funcDecl)
+		if err != nil {
+			return testCase{}, err
+		}
+
+		for _, testCase := range testCases {
+			if testCase.name == name {
+				return testCase, nil
+			}
+		}
+
+		return testCase{}, fmt.Errorf(
+			"unknown test case `%s` of parameterized test function `%s`",
+			name,
+			funcName,
+		)
+	}
+
+	return testCase{
+		name:     name,
+		funcName: name,
+		fuzz:     isFuzzFunction(name),
+	}, nil
+}
+
 func (r *TestRunner) replaceImports(code string) string {
 	return r.backend.replaceImports(code)
 }
@@ -323,7 +949,7 @@ func (r *TestRunner) runTestSetup(inter *interpreter.Interpreter) error {
 		return nil
 	}
 
-	return r.invokeTestFunction(inter, setupFunctionName)
+	return r.invokeSetupFunction(inter, setupFunctionName)
 }
 
 func hasSetup(inter *interpreter.Interpreter) bool {
@@ -335,7 +961,7 @@ func (r *TestRunner) runTestTearDown(inter *interpreter.Interpreter) error {
 		return nil
 	}
 
-	return r.invokeTestFunction(inter, tearDownFunctionName)
+	return r.invokeSetupFunction(inter, tearDownFunctionName)
 }
 
 func hasTearDown(inter *interpreter.Interpreter) bool {
@@ -347,7 +973,7 @@ func (r *TestRunner) runBeforeEach(inter *interpreter.Interpreter) error {
 		return nil
 	}
 
-	return r.invokeTestFunction(inter, beforeEachFunctionName)
+	return r.invokeSetupFunction(inter, beforeEachFunctionName)
 }
 
 func hasBeforeEach(inter *interpreter.Interpreter) bool {
@@ -359,14 +985,31 @@ func (r *TestRunner) runAfterEach(inter *interpreter.Interpreter) error {
 		return nil
 	}
 
-	return r.invokeTestFunction(inter, afterEachFunctionName)
+	return r.invokeSetupFunction(inter, afterEachFunctionName)
 }
 
 func hasAfterEach(inter *interpreter.Interpreter) bool {
 	return inter.Globals.Contains(afterEachFunctionName)
 }
 
-func (r *TestRunner) invokeTestFunction(inter *interpreter.Interpreter, funcName string) (err error) {
+// invokeSetupFunction invokes one of the setup functions,
+// i.e. `setup()`, `tearDown()`, `beforeEach()` or `afterEach()`.
+func (r *TestRunner) invokeSetupFunction(inter *interpreter.Interpreter, funcName string) error {
+	err := r.invokeTestFunction(inter, funcName)
+	if err != nil {
+		return &SetupError{
+			FunctionName: funcName,
+			Err:          err,
+		}
+	}
+	return nil
+}
+
+func (r *TestRunner) invokeTestFunction(
+	inter *interpreter.Interpreter,
+	funcName string,
+	arguments ...interpreter.Value,
+) (err error) {
 	// Individually fail each test-case for any internal error.
 	defer func() {
 		recoverPanics(func(internalErr error) {
@@ -374,7 +1017,7 @@ func (r *TestRunner) invokeTestFunction(inter *interpreter.Interpreter, funcName
 		})
 	}()
 
-	_, err = inter.Invoke(funcName)
+	_, err = inter.Invoke(funcName, arguments...)
 	return err
 }
 
@@ -403,9 +1046,42 @@ func (r *TestRunner) parseCheckAndInterpret(script string) (
 	*interpreter.Interpreter,
 	error,
 ) {
+	if r.deterministicClock {
+		// The blocks of the blockchain, other than the genesis block,
+		// are produced by the clock, starting at the given start
+		if r.clockStart.Before(flow.GenesisTime) {
+			return nil, nil, fmt.Errorf(
+				"timestamp %s is before the timestamp of the genesis block %s",
+				r.clockStart.UTC().Format(time.RFC3339Nano),
+				flow.GenesisTime.UTC().Format(time.RFC3339Nano),
+			)
+		}
+
+		if r.blockInterval < 0 {
+			return nil, nil, fmt.Errorf("block interval must not be negative, got %s", r.blockInterval)
+		}
+	}
+
 	// TODO: move this eventually to the `NewTestRunner`
 	env, ctx := r.initializeEnvironment()
 
+	contractMocks, err := r.contractMockLocations()
+	if err != nil {
+		return nil, nil, err
+	}
+	r.backend.contractMocks = contractMocks
+
+	if r.randomnessSeeded {
+		r.backend.UseRandomnessSeed(r.randomnessSeed)
+	}
+
+	if r.state != nil {
+		err := r.backend.ImportState(r.state)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	astProgram, err := parser.ParseProgram(nil, []byte(script), parser.Config{})
 	if err != nil {
 		return nil, nil, err
@@ -414,12 +1090,21 @@ func (r *TestRunner) parseCheckAndInterpret(script string) (
 	for _, funcDecl := range astProgram.FunctionDeclarations() {
 		funcName := funcDecl.Identifier.Identifier
 
-		if !strings.HasPrefix(funcName, testFunctionPrefix) {
+		if !isTestFunction(funcName) ||
+			isArgumentsProvider(astProgram, funcDecl) {
 			continue
 		}
 
-		if !funcDecl.ParameterList.IsEmpty() {
-			return nil, nil, fmt.Errorf("test functions should have no arguments")
+		if !isFuzzFunction(funcName) &&
+			isParameterized(funcDecl) &&
+			!hasArguments(astProgram, funcDecl) {
+			return nil, nil, fmt.Errorf(
+				"parameterized test function `%s` should have arguments: "+
+					"declare them using `pragma arguments` or a `%s%s` function",
+				funcName,
+				funcName,
+				argumentsProviderSuffix,
+			)
 		}
 
 		if funcDecl.ReturnTypeAnnotation != nil {
@@ -460,25 +1145,51 @@ func (r *TestRunner) initializeEnvironment() (
 
 	r.testRuntime = runtime.NewInterpreterRuntime(config)
 
-	r.testFramework = NewTestFrameworkProvider(
+	// The clock is installed before the blockchain produces any blocks,
+	// so that the timestamps of all blocks are deterministic.
+	clock := newSystemClock()
+	if r.deterministicClock {
+		clock = newDeterministicClock(r.clockStart, r.blockInterval)
+	}
+
+	testFramework := newTestFrameworkProvider(
 		r.logger,
 		r.fileResolver,
 		env,
 		r.coverageReport,
+		clock,
+		emulator.WithStorageLimitEnabled(r.storageLimitEnabled),
+		emulator.WithTransactionFeesEnabled(r.transactionFeesEnabled),
+		emulator.WithContractRemovalEnabled(r.contractRemovalEnabled),
 	)
-	backend, ok := r.testFramework.EmulatorBackend().(*EmulatorBackend)
-	if !ok {
-		panic(fmt.Errorf("failed to retrieve EmulatorBackend"))
-	}
+	r.testFramework = testFramework
+	backend := testFramework.emulatorBackend
 	backend.fileResolver = r.fileResolver
 	backend.contracts = r.contracts
+	backend.contractUpdateHandler = r.reloadContractValue
 	r.backend = backend
+	r.contractInterpreters = map[common.AddressLocation]*interpreter.Interpreter{}
+
+	for _, function := range backend.helperFunctions() {
+		env.DeclareValue(function, helpers.BlockchainHelpersLocation)
+	}
 
 	ctx := runtime.Context{
 		Interface:   r.backend.blockchain.NewScriptEnvironment(),
 		Location:    testScriptLocation,
 		Environment: env,
 	}
+	// Mocks are also checked when they are deployed, as they may not be imported.
+	// The programs are not cached, as they are not imported.
+	backend.contractMockChecker = func(location common.AddressLocation, code string, mockCode string) error {
+		checkCtx := ctx
+		checkCtx.Interface = uncachedProgramsInterface{
+			Interface: ctx.Interface,
+		}
+		_, _, err := r.parseAndCheckContractMock(location, code, mockCode, checkCtx)
+		return err
+	}
+
 	if r.coverageReport != nil {
 		r.coverageReport.ExcludeLocation(stdlib.CryptoCheckerLocation)
 		r.coverageReport.ExcludeLocation(stdlib.TestContractLocation)
@@ -610,49 +1321,7 @@ func (r *TestRunner) interpreterContractValueHandler(
 
 			switch location := compositeType.Location.(type) {
 			case common.AddressLocation:
-				// All contracts are deployed on EmulatorBackend's
-				// blockchain, so we construct a storage based on
-				// its ledger.
-				blockchainStorage := runtime.NewStorage(
-					r.backend.blockchain.NewScriptEnvironment(),
-					inter,
-				)
-				storageMap := blockchainStorage.GetStorageMap(
-					location.Address,
-					runtime.StorageDomainContract,
-					false,
-				)
-				if storageMap != nil {
-					storedValue = storageMap.ReadValue(
-						inter,
-						interpreter.StringStorageMapKey(location.Name),
-					)
-				}
-
-				// We need to store every slab of `blockchainStorage`
-				// to the current environment's storage, so that
-				// we can access fields & types.
-				iterator, err := blockchainStorage.SlabIterator()
-				if err != nil {
-					panic(err)
-				}
-				storage := inter.Storage().(*runtime.Storage)
-
-				for {
-					id, slab := iterator()
-					if id == StorageIDUndefined {
-						break
-					}
-					err := storage.Store(id, slab)
-					if err != nil {
-						panic(err)
-					}
-				}
-
-				err = storage.Commit(inter, true)
-				if err != nil {
-					panic(err)
-				}
+				storedValue = r.storedContractValue(inter, location)
 			}
 
 			if storedValue == nil {
@@ -669,6 +1338,91 @@ func (r *TestRunner) interpreterContractValueHandler(
 	}
 }
 
+// storedContractValue returns the value of the deployed contract
+// with the given location, or nil if it is not deployed.
+func (r *TestRunner) storedContractValue(
+	inter *interpreter.Interpreter,
+	location common.AddressLocation,
+) interpreter.Value {
+	var storedValue interpreter.Value
+
+	// All contracts are deployed on EmulatorBackend's
+	// blockchain, so we construct a storage based on
+	// its ledger.
+	blockchainStorage := runtime.NewStorage(
+		r.backend.blockchain.NewScriptEnvironment(),
+		inter,
+	)
+	storageMap := blockchainStorage.GetStorageMap(
+		location.Address,
+		runtime.StorageDomainContract,
+		false,
+	)
+	if storageMap != nil {
+		storedValue = storageMap.ReadValue(
+			inter,
+			interpreter.StringStorageMapKey(location.Name),
+		)
+	}
+
+	// We need to store every slab of `blockchainStorage`
+	// to the current environment's storage, so that
+	// we can access fields & types.
+	iterator, err := blockchainStorage.SlabIterator()
+	if err != nil {
+		panic(err)
+	}
+	storage := inter.Storage().(*runtime.Storage)
+
+	for {
+		id, slab := iterator()
+		if id == StorageIDUndefined {
+			break
+		}
+		err := storage.Store(id, slab)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	err = storage.Commit(inter, true)
+	if err != nil {
+		panic(err)
+	}
+
+	return storedValue
+}
+
+// reloadContractValue reloads the value of the deployed contract
+// with the given location, if it is imported by the test script,
+// e.g. after the contract was updated.
+func (r *TestRunner) reloadContractValue(location common.AddressLocation) {
+	contractInterpreter, ok := r.contractInterpreters[location]
+	if !ok {
+		return
+	}
+
+	variable := contractInterpreter.Globals.Get(location.Name)
+	if variable == nil {
+		return
+	}
+
+	// The contract value is kept if the contract was removed.
+	storedValue := r.storedContractValue(contractInterpreter, location)
+	if storedValue == nil {
+		return
+	}
+
+	contract, ok := variable.GetValue().(*interpreter.CompositeValue)
+	if !ok {
+		return
+	}
+
+	updatedContract := storedValue.(*interpreter.CompositeValue)
+	updatedContract.SetNestedVariables(contract.NestedVariables)
+	variable.SetValue(updatedContract)
+}
+
 func (r *TestRunner) interpreterImportHandler(ctx runtime.Context) interpreter.ImportLocationHandlerFunc {
 	return func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
 		var program *interpreter.Program
@@ -701,6 +1455,10 @@ func (r *TestRunner) interpreterImportHandler(ctx runtime.Context) interpreter.I
 		if err != nil {
 			panic(err)
 		}
+
+		if addressLocation, ok := location.(common.AddressLocation); ok {
+			r.contractInterpreters[addressLocation] = subInterpreter
+		}
 		return interpreter.InterpreterImport{
 			Interpreter: subInterpreter,
 		}
@@ -737,6 +1495,66 @@ func (r *TestRunner) parseAndCheckImport(
 		}
 	}
 
+	addressLocation, ok := location.(common.AddressLocation)
+	if !ok {
+		return r.parseAndCheckCode(location, code, startCtx)
+	}
+
+	mockCode, ok := r.backend.contractMocks[addressLocation]
+	if !ok {
+		return r.parseAndCheckCode(location, code, startCtx)
+	}
+
+	return r.parseAndCheckContractMock(addressLocation, code, mockCode, startCtx)
+}
+
+// parseAndCheckContractMock parses and checks the given code of the mock
+// of the contract with the given location and code.
+// The mock substitutes the contract, so it must conform to the contract.
+func (r *TestRunner) parseAndCheckContractMock(
+	location common.AddressLocation,
+	code string,
+	mockCode string,
+	startCtx runtime.Context,
+) (
+	*ast.Program,
+	*sema.Elaboration,
+	error,
+) {
+	// The program of the contract must not be cached for the location,
+	// as it is the location of the mock.
+	contractCtx := startCtx
+	contractCtx.Interface = uncachedProgramsInterface{
+		Interface: startCtx.Interface,
+	}
+	_, elaboration, err := r.parseAndCheckCode(location, code, contractCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mockProgram, mockElaboration, err := r.parseAndCheckCode(location, mockCode, startCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = checkContractMock(location, elaboration, mockElaboration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mockProgram, mockElaboration, nil
+}
+
+// parseAndCheckCode parses and checks the given code of an imported program.
+func (r *TestRunner) parseAndCheckCode(
+	location common.Location,
+	code string,
+	startCtx runtime.Context,
+) (
+	*ast.Program,
+	*sema.Elaboration,
+	error,
+) {
 	// Create a new (child) context, with new environment.
 
 	env := runtime.NewBaseInterpreterEnvironment(runtime.Config{})
@@ -821,6 +1639,14 @@ func PrettyPrintResults(results Results, scriptPath string) string {
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "Test results: %q\n", scriptPath)
 	for _, result := range results {
+		if result.Skipped {
+			fmt.Fprintf(&sb, "- SKIP: %s", result.TestName)
+			if result.SkipReason != "" {
+				fmt.Fprintf(&sb, " (%s)", result.SkipReason)
+			}
+			sb.WriteRune('\n')
+			continue
+		}
 		sb.WriteString(PrettyPrintResult(scriptPath, result.TestName, result.Error))
 		sb.WriteRune('\n')
 	}
@@ -832,14 +1658,17 @@ func PrettyPrintResult(scriptPath, funcName string, err error) string {
 		return fmt.Sprintf("- PASS: %s", funcName)
 	}
 
-	interErr := err.(interpreter.Error)
+	errString := err.Error()
 
 	// Replace script ID with actual file path
-	errString := strings.ReplaceAll(
-		err.Error(),
-		interErr.Location.String(),
-		scriptPath,
-	)
+	var interErr interpreter.Error
+	if goErrors.As(err, &interErr) && interErr.Location != nil {
+		errString = strings.ReplaceAll(
+			errString,
+			interErr.Location.String(),
+			scriptPath,
+		)
+	}
 
 	// Indent the error messages
 	errString = strings.ReplaceAll(errString, "\n", "\n\t\t\t")
@@ -0,0 +1,42 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2022 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	sdk "github.com/onflow/flow-go-sdk"
+)
+
+// TransactionDetails is the result of an executed transaction,
+// as reported by the emulator.
+type TransactionDetails struct {
+	ID sdk.Identifier
+	// BlockHeight is the height of the block the transaction was executed in.
+	BlockHeight     uint64
+	Error           error
+	ComputationUsed uint64
+	MemoryEstimate  uint64
+	// Events are the events emitted by the transaction, in emission order.
+	Events []sdk.Event
+	Logs   []string
+}
+
+// Succeeded returns true if the transaction executed without errors.
+func (d *TransactionDetails) Succeeded() bool {
+	return d.Error == nil
+}
@@ -27,3 +27,6 @@ var MintFlowTransaction []byte
 
 //go:embed transactions/burn_flow.cdc
 var BurnFlow []byte
+
+//go:embed transactions/revoke_account_key.cdc
+var RevokeAccountKeyTransaction []byte